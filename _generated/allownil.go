@@ -0,0 +1,26 @@
+package _generated
+
+//go:generate msgp
+
+//msgp:allownil NilLists Tags
+
+// NilFields keeps nil slices, maps and
+// []byte nil through the allownil tag.
+type NilFields struct {
+	Bytes  []byte            `msg:"bytes,allownil"`
+	Slice  []int             `msg:"slice,allownil"`
+	Map    map[string]string `msg:"map,allownil"`
+	Nested [][]string        `msg:"nested,allownil"`
+	Plain  []int             `msg:"plain"`
+}
+
+// NilLists keeps all of its nil
+// values through the directive.
+type NilLists struct {
+	Strings []string
+	Tags    Tags
+	Maps    map[string][]byte
+}
+
+// Tags is a named slice type.
+type Tags []string
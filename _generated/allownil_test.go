@@ -0,0 +1,107 @@
+package _generated
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/bytedance/msgp/msgp"
+)
+
+func TestAllowNil(t *testing.T) {
+	for _, in := range []NilFields{
+		{},
+		{Bytes: []byte{}, Slice: []int{}, Map: map[string]string{}, Nested: [][]string{nil, {}}, Plain: []int{}},
+		{Bytes: []byte("x"), Slice: []int{1}, Map: map[string]string{"a": "b"}, Nested: [][]string{{"c"}}, Plain: []int{2}},
+	} {
+		bts, err := in.MarshalMsg(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(bts) > in.Msgsize() {
+			t.Errorf("Msgsize %d is less than encoded size %d", in.Msgsize(), len(bts))
+		}
+		var buf bytes.Buffer
+		if err = msgp.Encode(&buf, &in); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(buf.Bytes(), bts) {
+			t.Errorf("EncodeMsg and MarshalMsg differ for %+v", in)
+		}
+
+		// Plain has no allownil option,
+		// so nil is read as empty
+		want := in
+		if want.Plain == nil {
+			want.Plain = []int{}
+		}
+		var out NilFields
+		if _, err = out.UnmarshalMsg(bts); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(out, want) {
+			t.Errorf("UnmarshalMsg: got %#v, want %#v", out, want)
+		}
+		out = NilFields{}
+		if err = msgp.Decode(bytes.NewReader(bts), &out); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(out, want) {
+			t.Errorf("DecodeMsg: got %#v, want %#v", out, want)
+		}
+	}
+}
+
+func TestAllowNilWritesNil(t *testing.T) {
+	bts, err := (&NilFields{}).MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, _, err := msgp.ReadMapStrIntfBytes(bts, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range []string{"bytes", "slice", "map", "nested"} {
+		if v, ok := m[k]; !ok || v != nil {
+			t.Errorf("%s was written as %#v; expected nil", k, v)
+		}
+	}
+	if v, ok := m["plain"].([]interface{}); !ok || len(v) != 0 {
+		t.Errorf("plain was written as %#v; expected an empty array", m["plain"])
+	}
+}
+
+func TestAllowNilDirective(t *testing.T) {
+	for _, in := range []NilLists{
+		{},
+		{Strings: []string{}, Tags: Tags{}, Maps: map[string][]byte{"nil": nil, "empty": {}}},
+	} {
+		bts, err := in.MarshalMsg(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out NilLists
+		if _, err = out.UnmarshalMsg(bts); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(out, in) {
+			t.Errorf("got %#v, want %#v", out, in)
+		}
+	}
+
+	var tags Tags
+	bts, err := tags.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !msgp.IsNil(bts) {
+		t.Errorf("nil Tags was written as %x", bts)
+	}
+	tags = Tags{"a"}
+	if _, err = tags.UnmarshalMsg(bts); err != nil {
+		t.Fatal(err)
+	}
+	if tags != nil {
+		t.Errorf("got %#v, want nil", tags)
+	}
+}
@@ -0,0 +1,34 @@
+package _generated
+
+import "time"
+
+//go:generate msgp
+
+//msgp:columnar Rows Points
+
+// Row is a row of a result set.
+type Row struct {
+	ID      int64             `msg:"id"`
+	Name    string            `msg:"name"`
+	Score   float64           `msg:"score"`
+	Tags    []string          `msg:"tags"`
+	Attrs   map[string]string `msg:"attrs"`
+	Created time.Time         `msg:"created"`
+	Next    *Row              `msg:"next"`
+}
+
+// Rows is written as one array per field
+// rather than one map per row.
+type Rows []Row
+
+// Points is a columnar slice
+// of an anonymous struct.
+type Points []struct {
+	X, Y float32
+}
+
+// ResultSet holds a columnar slice.
+type ResultSet struct {
+	Name string `msg:"name"`
+	Rows Rows   `msg:"rows"`
+}
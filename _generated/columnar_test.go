@@ -0,0 +1,141 @@
+package _generated
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bytedance/msgp/msgp"
+)
+
+func testRows() Rows {
+	created := time.Unix(1600000000, 5)
+	return Rows{
+		{ID: 1, Name: "a", Score: 0.5, Tags: []string{"x"}, Attrs: map[string]string{"k": "v"}, Created: created},
+		{ID: 2, Name: "b", Tags: []string{}, Attrs: map[string]string{}, Created: created, Next: &Row{ID: 3, Tags: []string{}, Attrs: map[string]string{}, Created: created}},
+	}
+}
+
+func TestColumnar(t *testing.T) {
+	in := testRows()
+	bts, err := in.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bts) > in.Msgsize() {
+		t.Errorf("Msgsize %d is less than encoded size %d", in.Msgsize(), len(bts))
+	}
+
+	// one array per field
+	m, _, err := msgp.ReadMapStrIntfBytes(bts, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m) != 7 {
+		t.Errorf("got %d columns; want 7", len(m))
+	}
+	if ids, ok := m["id"].([]interface{}); !ok || len(ids) != 2 || ids[0] != int64(1) || ids[1] != int64(2) {
+		t.Errorf("id column was written as %#v", m["id"])
+	}
+
+	var out Rows
+	if _, err = out.UnmarshalMsg(bts); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("UnmarshalMsg: got %+v", out)
+	}
+
+	var buf bytes.Buffer
+	if err = msgp.Encode(&buf, in); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != len(bts) {
+		t.Errorf("EncodeMsg wrote %d bytes; MarshalMsg wrote %d", buf.Len(), len(bts))
+	}
+	out = Rows{{ID: 9}, {ID: 9}, {ID: 9}}
+	if err = msgp.Decode(&buf, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("DecodeMsg: got %+v", out)
+	}
+}
+
+func TestColumnarEmpty(t *testing.T) {
+	for _, in := range []Rows{nil, {}} {
+		bts, err := in.MarshalMsg(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := Rows{{ID: 1}}
+		if _, err = out.UnmarshalMsg(bts); err != nil {
+			t.Fatal(err)
+		}
+		if len(out) != 0 {
+			t.Errorf("got %d rows; want 0", len(out))
+		}
+	}
+}
+
+func TestColumnarColumns(t *testing.T) {
+	// columns may be missing or unknown
+	bts := msgp.AppendMapHeader(nil, 3)
+	bts = msgp.AppendString(bts, "extra")
+	bts = msgp.AppendArrayHeader(bts, 2)
+	bts = msgp.AppendBool(bts, true)
+	bts = msgp.AppendBool(bts, false)
+	bts = msgp.AppendString(bts, "Y")
+	bts = msgp.AppendArrayHeader(bts, 2)
+	bts = msgp.AppendFloat32(bts, 1)
+	bts = msgp.AppendFloat32(bts, 2)
+	bts = msgp.AppendString(bts, "X")
+	bts = msgp.AppendArrayHeader(bts, 2)
+	bts = msgp.AppendFloat32(bts, 3)
+	bts = msgp.AppendFloat32(bts, 4)
+
+	var out Points
+	if _, err := out.UnmarshalMsg(bts); err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 2 || out[0].X != 3 || out[0].Y != 1 || out[1].X != 4 || out[1].Y != 2 {
+		t.Errorf("got %+v", out)
+	}
+	out = nil
+	if err := msgp.Decode(bytes.NewReader(bts), &out); err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 2 || out[1].X != 4 || out[1].Y != 2 {
+		t.Errorf("got %+v", out)
+	}
+
+	// columns must have the same length
+	bts = msgp.AppendMapHeader(nil, 2)
+	bts = msgp.AppendString(bts, "X")
+	bts = msgp.AppendArrayHeader(bts, 1)
+	bts = msgp.AppendFloat32(bts, 1)
+	bts = msgp.AppendString(bts, "Y")
+	bts = msgp.AppendArrayHeader(bts, 0)
+	if _, err := out.UnmarshalMsg(bts); err == nil {
+		t.Error("expected an error for columns of different lengths")
+	}
+	if err := msgp.Decode(bytes.NewReader(bts), &out); err == nil {
+		t.Error("expected an error for columns of different lengths")
+	}
+}
+
+func TestColumnarField(t *testing.T) {
+	in := ResultSet{Name: "t", Rows: testRows()}
+	bts, err := in.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out ResultSet
+	if _, err = out.UnmarshalMsg(bts); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("got %+v", out)
+	}
+}
@@ -0,0 +1,56 @@
+package _generated
+
+//go:generate msgp
+
+//msgp:enum Color
+//msgp:enum Priority as:int
+//msgp:enum Mask as:int
+
+// Color is written by name.
+type Color uint8
+
+const (
+	Red Color = iota
+	Green
+	Blue
+)
+
+// Priority is written as an integer,
+// and has its own String method.
+type Priority int
+
+const (
+	Low    Priority = -1
+	Normal Priority = 0
+	High   Priority = 10
+)
+
+func (p Priority) String() string {
+	switch p {
+	case Low:
+		return "low"
+	case High:
+		return "high"
+	default:
+		return "normal"
+	}
+}
+
+// Mask has constants that don't fit in an int64.
+type Mask uint64
+
+const (
+	NoBits  Mask = 0
+	LowBit  Mask = 1
+	HighBit Mask = 1 << 63
+	AllBits Mask = 1<<64 - 1
+)
+
+type Paint struct {
+	Color    Color            `msg:"color"`
+	Colors   []Color          `msg:"colors"`
+	Ptr      *Color           `msg:"ptr"`
+	ByName   map[string]Color `msg:"by_name"`
+	Priority Priority         `msg:"priority"`
+	Mask     Mask             `msg:"mask"`
+}
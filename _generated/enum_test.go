@@ -0,0 +1,135 @@
+package _generated
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/bytedance/msgp/msgp"
+)
+
+func TestEnum(t *testing.T) {
+	blue := Blue
+	in := Paint{
+		Color:    Green,
+		Colors:   []Color{Red, Blue},
+		Ptr:      &blue,
+		ByName:   map[string]Color{"sky": Blue},
+		Priority: High,
+		Mask:     HighBit,
+	}
+	bts, err := in.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bts) > in.Msgsize() {
+		t.Errorf("Msgsize %d is less than encoded size %d", in.Msgsize(), len(bts))
+	}
+
+	m, _, err := msgp.ReadMapStrIntfBytes(bts, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m["color"] != "Green" || m["ptr"] != "Blue" {
+		t.Errorf("colors were written as %#v and %#v", m["color"], m["ptr"])
+	}
+	if m["priority"] != int64(10) {
+		t.Errorf("priority was written as %#v", m["priority"])
+	}
+
+	var out Paint
+	if _, err = out.UnmarshalMsg(bts); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("UnmarshalMsg: got %+v; want %+v", out, in)
+	}
+
+	var buf bytes.Buffer
+	if err = msgp.Encode(&buf, &in); err != nil {
+		t.Fatal(err)
+	}
+	out = Paint{}
+	if err = msgp.Decode(&buf, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("DecodeMsg: got %+v; want %+v", out, in)
+	}
+}
+
+func TestEnumForms(t *testing.T) {
+	// both forms are accepted
+	for _, bts := range [][]byte{
+		msgp.AppendString(nil, "Blue"),
+		msgp.AppendInt(nil, int(Blue)),
+		msgp.AppendUint8(nil, uint8(Blue)),
+	} {
+		var c Color
+		if _, err := c.UnmarshalMsg(bts); err != nil {
+			t.Fatal(err)
+		}
+		if c != Blue {
+			t.Errorf("got %v; want Blue", c)
+		}
+		c = Red
+		if err := msgp.Decode(bytes.NewReader(bts), &c); err != nil {
+			t.Fatal(err)
+		}
+		if c != Blue {
+			t.Errorf("got %v; want Blue", c)
+		}
+	}
+	var p Priority
+	if _, err := p.UnmarshalMsg(msgp.AppendString(nil, "Low")); err != nil || p != Low {
+		t.Errorf("got %v, %v; want low", p, err)
+	}
+
+	// unknown names and values are rejected
+	for _, bts := range [][]byte{
+		msgp.AppendString(nil, "Purple"),
+		msgp.AppendInt(nil, 7),
+	} {
+		var c Color
+		_, err := c.UnmarshalMsg(bts)
+		if _, ok := err.(msgp.EnumError); !ok {
+			t.Errorf("UnmarshalMsg: got error %v; want an EnumError", err)
+		}
+		err = msgp.Decode(bytes.NewReader(bts), &c)
+		if _, ok := err.(msgp.EnumError); !ok {
+			t.Errorf("DecodeMsg: got error %v; want an EnumError", err)
+		}
+	}
+	if _, err := Color(7).MarshalMsg(nil); err == nil {
+		t.Error("expected an error writing an unknown Color")
+	}
+}
+
+func TestEnumHelpers(t *testing.T) {
+	if s := Blue.String(); s != "Blue" {
+		t.Errorf("Blue.String() = %q", s)
+	}
+	if s := Color(9).String(); s != "Color(9)" {
+		t.Errorf("Color(9).String() = %q", s)
+	}
+	if c, err := ParseColor("Green"); err != nil || c != Green {
+		t.Errorf("ParseColor(\"Green\") = %v, %v", c, err)
+	}
+	if _, err := ParseColor("green"); err == nil {
+		t.Error("expected an error for an unknown name")
+	}
+	// hand-written String methods are kept
+	if s := High.String(); s != "high" {
+		t.Errorf("High.String() = %q", s)
+	}
+	if p, err := ParsePriority("High"); err != nil || p != High {
+		t.Errorf("ParsePriority(\"High\") = %v, %v", p, err)
+	}
+	// constants above math.MaxInt64
+	if s := AllBits.String(); s != "AllBits" {
+		t.Errorf("AllBits.String() = %q", s)
+	}
+	if m, err := ParseMask("HighBit"); err != nil || m != HighBit {
+		t.Errorf("ParseMask(\"HighBit\") = %v, %v", m, err)
+	}
+}
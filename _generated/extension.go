@@ -0,0 +1,41 @@
+package _generated
+
+import (
+	"fmt"
+)
+
+//go:generate msgp
+
+//msgp:extension GeoPoint 42
+//msgp:extension Version 43
+//msgp:shim Version as:string using:versionString/parseVersion mode:convert
+
+// GeoPoint implements msgp.Extension
+// through its generated methods.
+type GeoPoint struct {
+	Lat float64 `msg:"lat"`
+	Lon float64 `msg:"lon"`
+}
+
+// Version is encoded as a "major.minor" string.
+type Version uint32
+
+func versionString(v Version) (string, error) {
+	return fmt.Sprintf("%d.%d", v>>16, v&0xffff), nil
+}
+
+func parseVersion(s string) (Version, error) {
+	var major, minor uint16
+	if _, err := fmt.Sscanf(s, "%d.%d", &major, &minor); err != nil {
+		return 0, err
+	}
+	return Version(major)<<16 | Version(minor), nil
+}
+
+type Places struct {
+	Home    GeoPoint  `msg:"home,extension"`
+	Work    *GeoPoint `msg:"work,extension"`
+	Plain   GeoPoint  `msg:"plain"`
+	Version Version   `msg:"version,extension"`
+	Shimmed Version   `msg:"shimmed"`
+}
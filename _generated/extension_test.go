@@ -0,0 +1,99 @@
+package _generated
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/bytedance/msgp/msgp"
+)
+
+func TestGeneratedExtension(t *testing.T) {
+	in := Places{
+		Home:    GeoPoint{Lat: 48.85, Lon: 2.35},
+		Work:    &GeoPoint{Lat: 51.5, Lon: -0.12},
+		Plain:   GeoPoint{Lat: 1, Lon: 2},
+		Version: 1<<16 | 12,
+		Shimmed: 2<<16 | 3,
+	}
+
+	bts, err := in.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bts) > in.Msgsize() {
+		t.Errorf("Msgsize %d is less than encoded size %d", in.Msgsize(), len(bts))
+	}
+
+	// tagged fields are written as extensions,
+	// and are decoded as registered types
+	m, _, err := msgp.ReadMapStrIntfBytes(bts, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"home", "work"} {
+		if _, ok := m[key].(*GeoPoint); !ok {
+			t.Errorf("%s: got %T; want *GeoPoint", key, m[key])
+		}
+	}
+	if _, ok := m["version"].(*Version); !ok {
+		t.Errorf("version: got %T; want *Version", m["version"])
+	}
+	if _, ok := m["plain"].(map[string]interface{}); !ok {
+		t.Errorf("plain: got %T; want a map", m["plain"])
+	}
+	if m["shimmed"] != "2.3" {
+		t.Errorf("shimmed: got %#v", m["shimmed"])
+	}
+
+	var out Places
+	if _, err = out.UnmarshalMsg(bts); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("UnmarshalMsg: got %+v; want %+v", out, in)
+	}
+
+	var buf bytes.Buffer
+	if err = msgp.Encode(&buf, &in); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), bts) {
+		t.Fatal("EncodeMsg and MarshalMsg differ")
+	}
+	out = Places{}
+	if err = msgp.Decode(&buf, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("DecodeMsg: got %+v; want %+v", out, in)
+	}
+}
+
+func TestGeneratedExtensionBody(t *testing.T) {
+	v := Version(3<<16 | 14)
+	if v.ExtensionType() != 43 {
+		t.Errorf("ExtensionType: got %d", v.ExtensionType())
+	}
+	body, err := v.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Len() != len(body) {
+		t.Errorf("Len: got %d; want %d", v.Len(), len(body))
+	}
+	b := make([]byte, v.Len())
+	if err = v.MarshalBinaryTo(b); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, body) {
+		t.Errorf("MarshalBinaryTo wrote % x; want % x", b, body)
+	}
+	var out Version
+	if err = out.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+	if out != v {
+		t.Errorf("UnmarshalBinary: got %d; want %d", out, v)
+	}
+}
@@ -0,0 +1,21 @@
+package _generated
+
+import (
+	"image"
+	"net"
+)
+
+//go:generate msgp
+
+//msgp:external image.Point image.Rectangle net.IPNet net.SRV
+
+// Externals holds types from other packages
+// that are encoded by generated functions.
+type Externals struct {
+	Origin   image.Point             `msg:"origin"`
+	Bounds   image.Rectangle         `msg:"bounds"`
+	ByName   map[string]*image.Point `msg:"by_name"`
+	Network  net.IPNet               `msg:"network"`
+	Service  net.SRV                 `msg:"service"`
+	Services []*net.SRV              `msg:"services"`
+}
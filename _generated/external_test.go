@@ -0,0 +1,101 @@
+package _generated
+
+import (
+	"bytes"
+	"image"
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/bytedance/msgp/msgp"
+)
+
+func TestExternal(t *testing.T) {
+	_, network, _ := net.ParseCIDR("10.1.0.0/16")
+	in := Externals{
+		Origin:   image.Pt(1, -2),
+		Bounds:   image.Rect(0, 0, 640, 480),
+		ByName:   map[string]*image.Point{"a": {X: 3, Y: 4}, "nil": nil},
+		Network:  *network,
+		Service:  net.SRV{Target: "a.example.com.", Port: 443, Priority: 1, Weight: 10},
+		Services: []*net.SRV{{Target: "b.example.com.", Port: 80}, nil},
+	}
+
+	bts, err := in.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bts) > in.Msgsize() {
+		t.Errorf("Msgsize %d is less than encoded size %d", in.Msgsize(), len(bts))
+	}
+	var out Externals
+	left, err := out.UnmarshalMsg(bts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(left) > 0 {
+		t.Errorf("%d bytes left over", len(left))
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("UnmarshalMsg: got %+v; want %+v", out, in)
+	}
+
+	var buf bytes.Buffer
+	if err = msgp.Encode(&buf, &in); err != nil {
+		t.Fatal(err)
+	}
+	// map order is random, so only the lengths match
+	if len(bts) != buf.Len() {
+		t.Fatalf("MarshalMsg wrote %d bytes; EncodeMsg wrote %d", len(bts), buf.Len())
+	}
+	out = Externals{}
+	if err = msgp.Decode(&buf, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("DecodeMsg: got %+v; want %+v", out, in)
+	}
+}
+
+func TestExternalFuncs(t *testing.T) {
+	in := image.Rect(-1, -2, 3, 4)
+	bts, err := AppendImageRectangle(nil, &in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bts) > SizeImageRectangle(&in) {
+		t.Errorf("SizeImageRectangle %d is less than encoded size %d", SizeImageRectangle(&in), len(bts))
+	}
+
+	// the wire format matches a local type
+	// with the same fields
+	m, _, err := msgp.ReadMapStrIntfBytes(bts, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if min, ok := m["Min"].(map[string]interface{}); !ok || min["X"] != int64(-1) || min["Y"] != int64(-2) {
+		t.Errorf("Min was written as %#v", m["Min"])
+	}
+
+	var out image.Rectangle
+	if _, err = UnmarshalImageRectangle(bts, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out != in {
+		t.Errorf("UnmarshalImageRectangle: got %v; want %v", out, in)
+	}
+
+	var buf bytes.Buffer
+	w := msgp.NewWriter(&buf)
+	if err = EncodeImageRectangle(w, &in); err != nil {
+		t.Fatal(err)
+	}
+	w.Flush()
+	out = image.Rectangle{}
+	if err = DecodeImageRectangle(msgp.NewReader(&buf), &out); err != nil {
+		t.Fatal(err)
+	}
+	if out != in {
+		t.Errorf("DecodeImageRectangle: got %v; want %v", out, in)
+	}
+}
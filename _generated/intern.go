@@ -0,0 +1,17 @@
+package _generated
+
+//go:generate msgp
+
+//msgp:intern Interned
+
+// Interned reads its strings
+// and map keys through an Interner.
+type Interned struct {
+	Kind   string            `msg:"kind"`
+	Tags   []string          `msg:"tags"`
+	Counts map[string]int    `msg:"counts"`
+	Nested map[string]string `msg:"nested"`
+	Child  InternedChild     `msg:"child"`
+	Ptr    *InternedChild    `msg:"ptr"`
+}
+
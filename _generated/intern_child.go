@@ -0,0 +1,11 @@
+package _generated
+
+//go:generate msgp
+
+//msgp:intern InternedChild
+
+// InternedChild is declared apart from Interned,
+// which passes its Interner on to it.
+type InternedChild struct {
+	Name string `msg:"name"`
+}
@@ -0,0 +1,67 @@
+package _generated
+
+import (
+	"reflect"
+	"testing"
+	"unsafe"
+
+	"github.com/bytedance/msgp/msgp"
+)
+
+func stringData(s string) uintptr {
+	return (*reflect.StringHeader)(unsafe.Pointer(&s)).Data
+}
+
+func TestInternedUnmarshal(t *testing.T) {
+	in := Interned{
+		Kind:   "kind",
+		Tags:   []string{"tag", "tag"},
+		Counts: map[string]int{"key": 1},
+		Nested: map[string]string{"kind": "tag"},
+		Child:  InternedChild{Name: "tag"},
+		Ptr:    &InternedChild{Name: "kind"},
+	}
+	bts, err := in.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	interner := msgp.NewInterner(0)
+	var a, b Interned
+	if _, err = a.UnmarshalMsgWithInterner(bts, interner); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = b.UnmarshalMsgWithInterner(bts, interner); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(a, in) {
+		t.Fatalf("got %+v; want %+v", a, in)
+	}
+	if interner.Len() != 3 {
+		t.Errorf("interned %d strings; want 3", interner.Len())
+	}
+	if stringData(a.Kind) != stringData(b.Kind) || stringData(a.Tags[0]) != stringData(b.Tags[1]) ||
+		stringData(a.Nested["kind"]) != stringData(a.Tags[0]) {
+		t.Error("strings are not shared")
+	}
+	for k := range b.Counts {
+		if stringData(k) != stringData(interner.Intern([]byte("key"))) {
+			t.Error("map keys are not shared")
+		}
+	}
+	if stringData(a.Child.Name) != stringData(a.Tags[0]) || stringData(b.Ptr.Name) != stringData(a.Kind) {
+		t.Error("the strings of child types are not shared")
+	}
+
+	// UnmarshalMsg doesn't intern
+	var c Interned
+	if _, err = c.UnmarshalMsg(bts); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(c, in) {
+		t.Fatalf("got %+v; want %+v", c, in)
+	}
+	if stringData(c.Kind) == stringData(a.Kind) {
+		t.Error("UnmarshalMsg interned a string")
+	}
+}
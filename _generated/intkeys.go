@@ -0,0 +1,26 @@
+package _generated
+
+//go:generate msgp
+
+//msgp:intkeys IntKeysV1 IntKeysV2 IntKeysNested
+
+type IntKeysV1 struct {
+	ID    int64   `msg:"1"`
+	Name  string  `msg:"2"`
+	Score float64 `msg:"3"`
+}
+
+// IntKeysV2 drops field 3 and adds
+// fields 4 and 300.
+type IntKeysV2 struct {
+	ID     int64             `msg:"1"`
+	Name   string            `msg:"2"`
+	Labels []string          `msg:"4"`
+	Extra  map[string]string `msg:"300"`
+}
+
+type IntKeysNested struct {
+	Child *IntKeysV1  `msg:"1"`
+	List  []IntKeysV2 `msg:"2"`
+	Fixed [2]Fixed    `msg:"-1"`
+}
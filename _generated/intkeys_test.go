@@ -0,0 +1,72 @@
+package _generated
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/bytedance/msgp/msgp"
+)
+
+func TestIntKeysWireFormat(t *testing.T) {
+	v := IntKeysV1{ID: 7, Name: "seven", Score: 7.5}
+	bts, err := v.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sz, o, err := msgp.ReadMapHeaderBytes(bts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sz != 3 {
+		t.Fatalf("expected 3 fields; found %d", sz)
+	}
+	for want := int64(1); want <= 3; want++ {
+		var key int64
+		key, o, err = msgp.ReadInt64Bytes(o)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if key != want {
+			t.Errorf("expected key %d; found %d", want, key)
+		}
+		o, err = msgp.Skip(o)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestIntKeysAddedAndRemovedFields(t *testing.T) {
+	v1 := IntKeysV1{ID: 7, Name: "seven", Score: 7.5}
+	bts, err := v1.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v2 IntKeysV2
+	_, err = v2.UnmarshalMsg(bts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v2.ID != v1.ID || v2.Name != v1.Name {
+		t.Fatalf("got %+v from %+v", v2, v1)
+	}
+
+	v2.Labels = []string{"a"}
+	v2.Extra = map[string]string{"k": "v"}
+	var buf bytes.Buffer
+	err = msgp.Encode(&buf, &v2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() > v2.Msgsize() {
+		t.Errorf("Msgsize() = %d; encoded %d bytes", v2.Msgsize(), buf.Len())
+	}
+	var back IntKeysV1
+	err = msgp.Decode(&buf, &back)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if back.ID != v1.ID || back.Name != v1.Name || back.Score != 0 {
+		t.Fatalf("got %+v from %+v", back, v2)
+	}
+}
@@ -0,0 +1,46 @@
+package _generated
+
+import (
+	"fmt"
+	"math/big"
+	"net/url"
+	"time"
+)
+
+//go:generate msgp
+
+// Marshalers encodes fields through
+// their encoding.BinaryMarshaler and
+// encoding.TextMarshaler methods.
+type Marshalers struct {
+	When     time.Time           `msg:"when,text"`
+	Temp     Celsius             `msg:"temp,text"`
+	Temps    []Celsius           `msg:"temps,text"`
+	ByCity   map[string]*Celsius `msg:"by_city,text"`
+	Endpoint url.URL             `msg:"endpoint,binary"`
+	Ratio    *big.Rat            `msg:"ratio,text"`
+	Fixed    [2]Celsius          `msg:"fixed,text"`
+	Plain    Celsius             `msg:"plain"`
+}
+
+// Celsius is written as text, like "21.5C".
+type Celsius float64
+
+func (c Celsius) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprintf("%gC", float64(c))), nil
+}
+
+// MarshaledSize bounds the length of MarshalText's output,
+// so Msgsize doesn't have to call it.
+func (c Celsius) MarshaledSize() int {
+	return len("-2.2250738585072014e-308C")
+}
+
+func (c *Celsius) UnmarshalText(b []byte) error {
+	var f float64
+	if _, err := fmt.Sscanf(string(b), "%gC", &f); err != nil {
+		return err
+	}
+	*c = Celsius(f)
+	return nil
+}
@@ -0,0 +1,118 @@
+package _generated
+
+import (
+	"bytes"
+	"math/big"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bytedance/msgp/msgp"
+)
+
+func TestMarshalers(t *testing.T) {
+	hot := Celsius(40)
+	endpoint, _ := url.Parse("https://example.com/a?b=c")
+	in := Marshalers{
+		When:     time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC),
+		Temp:     21.5,
+		Temps:    []Celsius{-3, 0.25},
+		ByCity:   map[string]*Celsius{"hot": &hot},
+		Endpoint: *endpoint,
+		Ratio:    big.NewRat(1, 3),
+		Fixed:    [2]Celsius{1, 2},
+		Plain:    7,
+	}
+
+	bts, err := in.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// check the wire forms
+	m, _, err := msgp.ReadMapStrIntfBytes(bts, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m["when"] != "2020-01-02T03:04:05.000000006Z" || m["temp"] != "21.5C" || m["ratio"] != "1/3" {
+		t.Errorf("text fields: %v, %v, %v", m["when"], m["temp"], m["ratio"])
+	}
+	if _, ok := m["endpoint"].([]byte); !ok {
+		t.Errorf("endpoint is %T; want []byte", m["endpoint"])
+	}
+	if _, ok := m["plain"].(float64); !ok {
+		t.Errorf("plain is %T; want float64", m["plain"])
+	}
+
+	var out Marshalers
+	if _, err = out.UnmarshalMsg(bts); err != nil {
+		t.Fatal(err)
+	}
+	checkMarshalers(t, &in, &out)
+
+	var buf bytes.Buffer
+	if err = msgp.Encode(&buf, &in); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), bts) {
+		t.Fatalf("MarshalMsg and EncodeMsg differ:\n% x\n% x", bts, buf.Bytes())
+	}
+	var dec Marshalers
+	if err = msgp.Decode(&buf, &dec); err != nil {
+		t.Fatal(err)
+	}
+	checkMarshalers(t, &in, &dec)
+
+	// errors from the unmarshalers are returned
+	bad := msgp.AppendMapHeader(nil, 1)
+	bad = msgp.AppendString(bad, "temp")
+	bad = msgp.AppendString(bad, "hot")
+	if _, err = out.UnmarshalMsg(bad); err == nil {
+		t.Error("expected an error from UnmarshalText")
+	}
+}
+
+func checkMarshalers(t *testing.T, in, out *Marshalers) {
+	t.Helper()
+	if !out.When.Equal(in.When) || out.Temp != in.Temp || !reflect.DeepEqual(out.Temps, in.Temps) ||
+		out.Fixed != in.Fixed || out.Plain != in.Plain {
+		t.Errorf("got %+v", out)
+	}
+	if len(out.ByCity) != 1 || *out.ByCity["hot"] != *in.ByCity["hot"] {
+		t.Errorf("by_city: got %v", out.ByCity)
+	}
+	if out.Endpoint.String() != in.Endpoint.String() {
+		t.Errorf("endpoint: got %v", out.Endpoint.String())
+	}
+	if out.Ratio.Cmp(in.Ratio) != 0 {
+		t.Errorf("ratio: got %v", out.Ratio)
+	}
+}
+
+func TestTypeCheck(t *testing.T) {
+	endpoint, _ := url.Parse("mailto:someone@example.com")
+	in := TypeChecked{
+		Endpoint: *endpoint,
+		Ratio:    big.NewRat(-5, 7),
+		Ratios:   []big.Rat{*big.NewRat(1, 2)},
+	}
+	bts, err := in.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, _, err := msgp.ReadMapStrIntfBytes(bts, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m["ratio"] != "-5/7" {
+		t.Errorf("ratio was written as %#v", m["ratio"])
+	}
+	var out TypeChecked
+	if _, err = out.UnmarshalMsg(bts); err != nil {
+		t.Fatal(err)
+	}
+	if out.Endpoint.String() != in.Endpoint.String() || out.Ratio.Cmp(in.Ratio) != 0 ||
+		len(out.Ratios) != 1 || out.Ratios[0].Cmp(&in.Ratios[0]) != 0 {
+		t.Errorf("got %+v", out)
+	}
+}
@@ -0,0 +1,18 @@
+package _generated
+
+//go:generate msgp
+
+// Features writes its numeric slices as
+// little-endian bin objects.
+type Features struct {
+	ID      int64                `msg:"id"`
+	Floats  []float64            `msg:"floats,packed"`
+	Singles []float32            `msg:"singles,packed"`
+	Counts  []uint16             `msg:"counts,packed"`
+	Deltas  []int32              `msg:"deltas,packed"`
+	Nested  [][]int64            `msg:"nested,packed"`
+	Named   map[string][]float32 `msg:"named,packed"`
+	Ptr     *[]uint64            `msg:"ptr,packed"`
+	Maybe   []int16              `msg:"maybe,packed,allownil"`
+	Plain   []float64            `msg:"plain"`
+}
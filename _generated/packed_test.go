@@ -0,0 +1,79 @@
+package _generated
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/bytedance/msgp/msgp"
+)
+
+func TestPacked(t *testing.T) {
+	u := []uint64{1 << 63, 2}
+	in := Features{
+		ID:      9,
+		Floats:  make([]float64, 1000),
+		Singles: []float32{1.5, -2},
+		Counts:  []uint16{0, 65535},
+		Deltas:  []int32{-7},
+		Nested:  [][]int64{{1, 2}, {}},
+		Named:   map[string][]float32{"a": {3}},
+		Ptr:     &u,
+		Maybe:   []int16{},
+		Plain:   []float64{1, 2},
+	}
+	for i := range in.Floats {
+		in.Floats[i] = float64(i) / 3
+	}
+
+	bts, err := in.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bts) > in.Msgsize() {
+		t.Errorf("Msgsize %d is less than encoded size %d", in.Msgsize(), len(bts))
+	}
+	m, _, err := msgp.ReadMapStrIntfBytes(bts, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f, ok := m["floats"].([]byte); !ok || len(f) != 8000 {
+		t.Errorf("floats was written as %T", m["floats"])
+	}
+	if _, ok := m["plain"].([]interface{}); !ok {
+		t.Errorf("plain was written as %T", m["plain"])
+	}
+
+	var out Features
+	if _, err = out.UnmarshalMsg(bts); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("UnmarshalMsg: got %+v", out)
+	}
+
+	var buf bytes.Buffer
+	if err = msgp.Encode(&buf, &in); err != nil {
+		t.Fatal(err)
+	}
+	out = Features{}
+	if err = msgp.Decode(&buf, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("DecodeMsg: got %+v", out)
+	}
+
+	// nil is kept by allownil
+	in.Maybe = nil
+	bts, err = in.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = out.UnmarshalMsg(bts); err != nil {
+		t.Fatal(err)
+	}
+	if out.Maybe != nil {
+		t.Errorf("got %#v; want nil", out.Maybe)
+	}
+}
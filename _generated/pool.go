@@ -0,0 +1,33 @@
+package _generated
+
+//go:generate msgp
+
+//msgp:pool PooledParent PooledChild
+
+// PooledParent and PooledChild are
+// allocated from pools when decoded.
+type PooledParent struct {
+	Name     string                  `msg:"name"`
+	Data     []byte                  `msg:"data"`
+	Ints     []int                   `msg:"ints"`
+	Child    *PooledChild            `msg:"child"`
+	Children []*PooledChild          `msg:"children"`
+	Values   []PooledChild           `msg:"values"`
+	ByName   map[string]*PooledChild `msg:"by_name"`
+	Counts   map[string]int          `msg:"counts"`
+	Fixed    [2]string               `msg:"fixed"`
+	Inner    struct {
+		A int    `msg:"a"`
+		B []byte `msg:"b"`
+	} `msg:"inner"`
+	Plain *NotPooled `msg:"plain"`
+}
+
+type PooledChild struct {
+	ID   int64    `msg:"id"`
+	Tags []string `msg:"tags"`
+}
+
+type NotPooled struct {
+	X int `msg:"x"`
+}
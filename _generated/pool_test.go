@@ -0,0 +1,135 @@
+package _generated
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/bytedance/msgp/msgp"
+)
+
+// freeList is an Allocator that
+// counts the values it hands out
+type freeList struct {
+	free  []interface{}
+	gets  int
+	reuse int
+}
+
+func (f *freeList) Get() interface{} {
+	f.gets++
+	if len(f.free) == 0 {
+		return nil
+	}
+	f.reuse++
+	x := f.free[len(f.free)-1]
+	f.free = f.free[:len(f.free)-1]
+	return x
+}
+
+func (f *freeList) Put(x interface{}) { f.free = append(f.free, x) }
+
+func testPooledParent() *PooledParent {
+	p := &PooledParent{
+		Name:     "parent",
+		Data:     []byte("data"),
+		Ints:     []int{1, 2, 3},
+		Child:    &PooledChild{ID: 1, Tags: []string{"a"}},
+		Children: []*PooledChild{{ID: 2, Tags: []string{"x"}}, {ID: 3, Tags: []string{"b", "c"}}},
+		Values:   []PooledChild{{ID: 4, Tags: []string{"d"}}},
+		ByName:   map[string]*PooledChild{"five": {ID: 5, Tags: []string{"y"}}},
+		Counts:   map[string]int{"x": 1},
+		Fixed:    [2]string{"f", "g"},
+		Plain:    &NotPooled{X: 6},
+	}
+	p.Inner.A = 7
+	p.Inner.B = []byte("inner")
+	return p
+}
+
+func TestPoolReset(t *testing.T) {
+	p := testPooledParent()
+	data := p.Data
+	p.Reset()
+
+	want := PooledParent{
+		Data:     data[:0],
+		Ints:     []int{},
+		Children: []*PooledChild{},
+		Values:   []PooledChild{},
+		ByName:   map[string]*PooledChild{},
+		Counts:   map[string]int{},
+	}
+	want.Inner.B = []byte{}
+	if !reflect.DeepEqual(*p, want) {
+		t.Errorf("got %#v\nwant %#v", *p, want)
+	}
+	if cap(p.Data) != cap(data) {
+		t.Error("Reset did not keep the capacity of Data")
+	}
+}
+
+func TestPoolAllocator(t *testing.T) {
+	parents := &freeList{}
+	children := &freeList{}
+	ints := &freeList{}
+	byName := &freeList{}
+	SetPooledParentAllocator(parents)
+	SetPooledChildAllocator(children)
+	SetPooledParentIntsAllocator(ints)
+	SetPooledParentByNameAllocator(byName)
+	defer func() {
+		SetPooledParentAllocator(&freeList{})
+		SetPooledChildAllocator(&freeList{})
+		SetPooledParentIntsAllocator(&freeList{})
+		SetPooledParentByNameAllocator(&freeList{})
+	}()
+
+	in := testPooledParent()
+	bts, err := in.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		out := AcquirePooledParent()
+		if _, err := out.UnmarshalMsg(bts); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(in, out) {
+			t.Fatalf("round %d: got %#v\nwant %#v", i, out, in)
+		}
+		out.Release()
+	}
+	if parents.gets != 3 || parents.reuse != 2 {
+		t.Errorf("parents: %d gets, %d reused", parents.gets, parents.reuse)
+	}
+	// Child, two Children and one ByName value per round
+	if children.gets != 12 || children.reuse != 8 {
+		t.Errorf("children: %d gets, %d reused", children.gets, children.reuse)
+	}
+	// the slices and maps of released parents are reused too
+	if ints.gets != 3 || ints.reuse != 2 {
+		t.Errorf("Ints: %d gets, %d reused", ints.gets, ints.reuse)
+	}
+	if byName.gets != 3 || byName.reuse != 2 {
+		t.Errorf("ByName: %d gets, %d reused", byName.gets, byName.reuse)
+	}
+
+	// decoding a smaller message into a released
+	// value must not leave any of the old data behind
+	small := &PooledParent{Name: "small", Children: []*PooledChild{{ID: 9}}}
+	bts, err = small.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := AcquirePooledParent()
+	if err := msgp.Decode(bytes.NewReader(bts), out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Name != "small" || len(out.Children) != 1 || out.Children[0].ID != 9 || len(out.Children[0].Tags) != 0 ||
+		out.Child != nil || len(out.Ints) != 0 || len(out.Values) != 0 || len(out.ByName) != 0 || out.Plain != nil {
+		t.Errorf("stale data after decoding: %#v", out)
+	}
+	out.Release()
+}
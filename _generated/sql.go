@@ -0,0 +1,34 @@
+package _generated
+
+//go:generate msgp -sql
+
+// Account is stored in a BLOB column
+// through its Scan and Value methods.
+type Account struct {
+	ID    int64             `msg:"id"`
+	Email string            `msg:"email"`
+	Roles []string          `msg:"roles"`
+	Prefs map[string]string `msg:"prefs"`
+}
+
+// Labels shows that types other
+// than structs get the methods too.
+type Labels []string
+
+// Session has a Value field, so it can't
+// have a Value method, and is skipped.
+type Session struct {
+	Token string `msg:"token"`
+	Value []byte `msg:"value"`
+}
+
+// Cursor has a Scan method of its own, so
+// it is skipped too.
+type Cursor struct {
+	Pos int `msg:"pos"`
+}
+
+func (c *Cursor) Scan() bool {
+	c.Pos++
+	return c.Pos < 10
+}
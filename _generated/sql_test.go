@@ -0,0 +1,98 @@
+package _generated
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"reflect"
+	"testing"
+
+	"github.com/bytedance/msgp/msgp"
+)
+
+var (
+	_ sql.Scanner   = (*Account)(nil)
+	_ driver.Valuer = Account{}
+	_ sql.Scanner   = (*Labels)(nil)
+	_ driver.Valuer = Labels{}
+)
+
+func TestGeneratedSQL(t *testing.T) {
+	in := Account{
+		ID:    7,
+		Email: "a@example.com",
+		Roles: []string{"admin"},
+		Prefs: map[string]string{"theme": "dark"},
+	}
+	v, err := in.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, ok := v.([]byte)
+	if !ok {
+		t.Fatalf("Value returned %T", v)
+	}
+	if !driver.IsValue(v) {
+		t.Errorf("%T is not a driver.Value", v)
+	}
+
+	var out Account
+	if err = out.Scan(b); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("got %+v; want %+v", out, in)
+	}
+	// drivers may reuse the bytes
+	for i := range b {
+		b[i] = 0
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("Scan kept the driver's bytes: %+v", out)
+	}
+
+	// some drivers return strings
+	out = Account{}
+	v, _ = in.Value()
+	if err = out.Scan(string(v.([]byte))); err != nil || !reflect.DeepEqual(in, out) {
+		t.Errorf("got %+v, %v", out, err)
+	}
+
+	// NULL is the zero value
+	if err = out.Scan(nil); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out, Account{}) {
+		t.Errorf("got %+v after NULL", out)
+	}
+	if err = out.Scan(int64(1)); err == nil {
+		t.Error("scanned an int64")
+	}
+
+	labels := Labels{"a", "b"}
+	v, err = labels.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Labels
+	if err = got.Scan(v); err != nil || !reflect.DeepEqual(got, labels) {
+		t.Errorf("got %v, %v", got, err)
+	}
+
+	// Session and Cursor are skipped, because they
+	// have a field or method named Value or Scan
+	if _, ok := interface{}(&Session{}).(sql.Scanner); ok {
+		t.Error("Session has a Scan method")
+	}
+	if _, ok := interface{}(Cursor{}).(driver.Valuer); ok {
+		t.Error("Cursor has a Value method")
+	}
+	s := Session{Token: "t", Value: []byte("v")}
+	v, err = msgp.SQL{Msg: &s}.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s2 Session
+	if err = (msgp.SQL{Msg: &s2}).Scan(v); err != nil || !reflect.DeepEqual(s, s2) {
+		t.Errorf("got %+v, %v", s2, err)
+	}
+}
@@ -0,0 +1,27 @@
+package _generated
+
+import (
+	"encoding/json"
+	"math/big"
+	"net"
+	"net/url"
+	"time"
+)
+
+//go:generate msgp
+
+// StdlibTypes holds the standard library
+// types that msgp supports natively.
+type StdlibTypes struct {
+	Timeout  time.Duration          `msg:"timeout"`
+	Timeouts []time.Duration        `msg:"timeouts"`
+	Balance  *big.Int               `msg:"balance"`
+	Rate     *big.Float             `msg:"rate"`
+	Addr     net.IP                 `msg:"addr"`
+	Endpoint *url.URL               `msg:"endpoint"`
+	Amount   json.Number            `msg:"amount"`
+	Amounts  map[string]json.Number `msg:"amounts"`
+	Zone     *time.Location         `msg:"zone"`
+	NilInt   *big.Int               `msg:"nil_int"`
+	NilURL   *url.URL               `msg:"nil_url"`
+}
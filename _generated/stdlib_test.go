@@ -0,0 +1,87 @@
+package _generated
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"net"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bytedance/msgp/msgp"
+)
+
+func TestStdlibTypes(t *testing.T) {
+	balance, _ := new(big.Int).SetString("-123456789012345678901234567890", 10)
+	rate, _ := new(big.Float).SetPrec(200).SetString("3.14159265358979323846264338327950288")
+	endpoint, _ := url.Parse("https://user@example.com:8080/path?q=1#frag")
+	in := StdlibTypes{
+		Timeout:  3 * time.Second,
+		Timeouts: []time.Duration{time.Millisecond, -time.Hour},
+		Balance:  balance,
+		Rate:     rate,
+		Addr:     net.ParseIP("192.168.0.1"),
+		Endpoint: endpoint,
+		Amount:   json.Number("1.5"),
+		Amounts:  map[string]json.Number{"int": "-42", "uint": "18446744073709551615", "big": "1e400"},
+		Zone:     time.UTC,
+	}
+
+	bts, err := in.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bts) > in.Msgsize() {
+		t.Errorf("Msgsize %d is less than encoded size %d", in.Msgsize(), len(bts))
+	}
+	var buf bytes.Buffer
+	if err = msgp.Encode(&buf, &in); err != nil {
+		t.Fatal(err)
+	}
+	// map order is random, so only the lengths match
+	if len(bts) != buf.Len() {
+		t.Fatalf("MarshalMsg wrote %d bytes; EncodeMsg wrote %d", len(bts), buf.Len())
+	}
+
+	var out StdlibTypes
+	if _, err = out.UnmarshalMsg(bts); err != nil {
+		t.Fatal(err)
+	}
+	checkStdlibTypes(t, &in, &out)
+
+	var dec StdlibTypes
+	if err = msgp.Decode(&buf, &dec); err != nil {
+		t.Fatal(err)
+	}
+	checkStdlibTypes(t, &in, &dec)
+}
+
+func checkStdlibTypes(t *testing.T, in, out *StdlibTypes) {
+	t.Helper()
+	if out.Timeout != in.Timeout || !reflect.DeepEqual(out.Timeouts, in.Timeouts) {
+		t.Errorf("durations: got %v and %v", out.Timeout, out.Timeouts)
+	}
+	if out.Balance.Cmp(in.Balance) != 0 {
+		t.Errorf("balance: got %v; want %v", out.Balance, in.Balance)
+	}
+	if out.Rate.Cmp(in.Rate) != 0 || out.Rate.Prec() != in.Rate.Prec() {
+		t.Errorf("rate: got %v (prec %d); want %v", out.Rate, out.Rate.Prec(), in.Rate)
+	}
+	if !out.Addr.Equal(in.Addr) || len(out.Addr) != net.IPv4len {
+		t.Errorf("addr: got %v (%d bytes)", out.Addr, len(out.Addr))
+	}
+	if out.Endpoint.String() != in.Endpoint.String() {
+		t.Errorf("endpoint: got %v; want %v", out.Endpoint, in.Endpoint)
+	}
+	if out.Amount != in.Amount || !reflect.DeepEqual(out.Amounts, in.Amounts) {
+		t.Errorf("amounts: got %v and %v", out.Amount, out.Amounts)
+	}
+	if out.Zone != time.UTC {
+		t.Errorf("zone: got %v", out.Zone)
+	}
+	if out.NilInt != nil || out.NilURL != nil {
+		t.Errorf("nil pointers: got %v and %v", out.NilInt, out.NilURL)
+	}
+}
@@ -0,0 +1,18 @@
+package _generated
+
+import "time"
+
+//go:generate msgp
+
+// TimeFormats writes its times in
+// the formats set by their tags.
+type TimeFormats struct {
+	Ext     time.Time            `msg:"ext"`
+	Unix    time.Time            `msg:"unix,unix"`
+	Millis  time.Time            `msg:"millis,unixms"`
+	Nanos   time.Time            `msg:"nanos,unixns"`
+	RFC3339 time.Time            `msg:"rfc3339,rfc3339"`
+	Ptr     *time.Time           `msg:"ptr,unixms"`
+	Slice   []time.Time          `msg:"slice,unixms"`
+	Map     map[string]time.Time `msg:"map,rfc3339"`
+}
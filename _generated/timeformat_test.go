@@ -0,0 +1,96 @@
+package _generated
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/bytedance/msgp/msgp"
+)
+
+func TestTimeFormats(t *testing.T) {
+	now := time.Date(2021, 6, 7, 8, 9, 10, 123456789, time.UTC)
+	ms := now.Truncate(time.Millisecond)
+	in := TimeFormats{
+		Ext:     now,
+		Unix:    now,
+		Millis:  now,
+		Nanos:   now,
+		RFC3339: now,
+		Ptr:     &now,
+		Slice:   []time.Time{now, now.Add(-time.Hour)},
+		Map:     map[string]time.Time{"now": now},
+	}
+	bts, err := in.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bts) > in.Msgsize() {
+		t.Errorf("Msgsize %d is less than encoded size %d", in.Msgsize(), len(bts))
+	}
+
+	m, _, err := msgp.ReadMapStrIntfBytes(bts, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m["unix"] != now.Unix() || m["millis"] != ms.UnixNano()/1e6 || m["nanos"] != now.UnixNano() {
+		t.Errorf("integer times were written as %#v, %#v and %#v", m["unix"], m["millis"], m["nanos"])
+	}
+	if m["rfc3339"] != "2021-06-07T08:09:10.123456789Z" {
+		t.Errorf("rfc3339 was written as %#v", m["rfc3339"])
+	}
+
+	check := func(out *TimeFormats) {
+		t.Helper()
+		if !out.Ext.Equal(now) || !out.Unix.Equal(now.Truncate(time.Second)) || !out.Millis.Equal(ms) ||
+			!out.Nanos.Equal(now) || !out.RFC3339.Equal(now) || !out.Ptr.Equal(ms) ||
+			len(out.Slice) != 2 || !out.Slice[1].Equal(ms.Add(-time.Hour)) || !out.Map["now"].Equal(now) {
+			t.Errorf("got %+v", out)
+		}
+	}
+	var out TimeFormats
+	if _, err = out.UnmarshalMsg(bts); err != nil {
+		t.Fatal(err)
+	}
+	check(&out)
+
+	var buf bytes.Buffer
+	if err = msgp.Encode(&buf, &in); err != nil {
+		t.Fatal(err)
+	}
+	out = TimeFormats{}
+	if err = msgp.Decode(&buf, &out); err != nil {
+		t.Fatal(err)
+	}
+	check(&out)
+}
+
+func TestTimeFormatsLenient(t *testing.T) {
+	now := time.Date(2021, 6, 7, 8, 9, 10, 0, time.UTC)
+	// every form is accepted by every field,
+	// and integers are read in the field's unit
+	forms := map[string][]byte{
+		"ext":       msgp.AppendTime(nil, now),
+		"timestamp": msgp.AppendTimestamp(nil, now),
+		"rfc3339":   msgp.AppendString(nil, now.Format(time.RFC3339)),
+		"millis":    msgp.AppendInt64(nil, now.Unix()*1000),
+		"float":     msgp.AppendFloat64(nil, float64(now.Unix())*1000),
+	}
+	for name, form := range forms {
+		b := msgp.AppendMapHeader(nil, 1)
+		b = msgp.AppendString(b, "millis")
+		b = append(b, form...)
+		var out TimeFormats
+		if _, err := out.UnmarshalMsg(b); err != nil {
+			t.Errorf("%s: %s", name, err)
+		} else if !out.Millis.Equal(now) {
+			t.Errorf("%s: got %v; want %v", name, out.Millis, now)
+		}
+		out = TimeFormats{}
+		if err := msgp.Decode(bytes.NewReader(b), &out); err != nil {
+			t.Errorf("%s: %s", name, err)
+		} else if !out.Millis.Equal(now) {
+			t.Errorf("%s: got %v; want %v", name, out.Millis, now)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package _generated
+
+import "time"
+
+//go:generate msgp
+
+//msgp:timestamp Timestamps
+
+// Timestamps writes its times with the
+// MessagePack timestamp extension.
+type Timestamps struct {
+	At    time.Time   `msg:"at"`
+	Ptr   *time.Time  `msg:"ptr"`
+	Times []time.Time `msg:"times"`
+}
+
+// LegacyTimes writes its times in
+// the legacy format.
+type LegacyTimes struct {
+	At time.Time `msg:"at"`
+}
@@ -0,0 +1,73 @@
+package _generated
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/bytedance/msgp/msgp"
+)
+
+func TestTimestampDirective(t *testing.T) {
+	at := time.Unix(1500000000, 0)
+	in := Timestamps{At: at, Ptr: &at, Times: []time.Time{at, at.Add(time.Nanosecond)}}
+
+	bts, err := in.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err = msgp.Encode(&buf, &in); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(bts, buf.Bytes()) {
+		t.Fatalf("MarshalMsg and EncodeMsg differ:\n% x\n% x", bts, buf.Bytes())
+	}
+	if len(bts) > in.Msgsize() {
+		t.Errorf("Msgsize %d is less than encoded size %d", in.Msgsize(), len(bts))
+	}
+
+	// the 'at' value is the 32-bit timestamp form
+	_, o, err := msgp.ReadMapHeaderBytes(bts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, o, err = msgp.ReadStringBytes(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := msgp.AppendTimestamp(nil, at); !bytes.HasPrefix(o, want) {
+		t.Errorf("got % x; want prefix % x", o[:len(want)], want)
+	}
+
+	var out Timestamps
+	if _, err = out.UnmarshalMsg(bts); err != nil {
+		t.Fatal(err)
+	}
+	if !out.At.Equal(at) || !out.Ptr.Equal(at) || len(out.Times) != 2 || !out.Times[1].Equal(in.Times[1]) {
+		t.Errorf("UnmarshalMsg: got %v", out)
+	}
+
+	// types without the directive keep the legacy
+	// format, but read timestamps too
+	var legacy LegacyTimes
+	if _, err = legacy.UnmarshalMsg(bts); err != nil {
+		t.Fatal(err)
+	}
+	if !legacy.At.Equal(at) {
+		t.Errorf("got %v; want %v", legacy.At, at)
+	}
+	bts, err = legacy.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := msgp.AppendTime(nil, at); !bytes.HasSuffix(bts, want) {
+		t.Errorf("got % x; want suffix % x", bts, want)
+	}
+	if err = msgp.Decode(bytes.NewReader(bts), &out); err != nil {
+		t.Fatal(err)
+	}
+	if !out.At.Equal(at) {
+		t.Errorf("DecodeMsg: got %v; want %v", out.At, at)
+	}
+}
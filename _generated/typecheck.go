@@ -0,0 +1,19 @@
+package _generated
+
+import (
+	"math/big"
+	"net/url"
+)
+
+//go:generate msgp
+
+//msgp:typecheck
+
+// TypeChecked has fields of library types that
+// are encoded through their marshalers, which
+// the generator finds by type-checking.
+type TypeChecked struct {
+	Endpoint url.URL   `msg:"endpoint"`
+	Ratio    *big.Rat  `msg:"ratio"`
+	Ratios   []big.Rat `msg:"ratios"`
+}
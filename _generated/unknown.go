@@ -0,0 +1,36 @@
+package _generated
+
+import "github.com/bytedance/msgp/msgp"
+
+//go:generate msgp
+
+// UnknownV1 is an older version of UnknownV2
+// that keeps the fields it doesn't know about.
+type UnknownV1 struct {
+	Name    string   `msg:"name"`
+	Unknown msgp.Raw `msg:",unknown"`
+	Age     int      `msg:"age"`
+}
+
+type UnknownV2 struct {
+	Name  string            `msg:"name"`
+	Age   int               `msg:"age"`
+	Email string            `msg:"email"`
+	Tags  []string          `msg:"tags"`
+	Attrs map[string]string `msg:"attrs"`
+}
+
+type UnknownNested struct {
+	Inner struct {
+		A    int      `msg:"a"`
+		Rest msgp.Raw `msg:",unknown"`
+	} `msg:"inner"`
+	Ptr *UnknownV1 `msg:"ptr"`
+}
+
+// UnknownEmbedded passes the keys it doesn't recognize
+// to UnknownV1, so Rest is ignored.
+type UnknownEmbedded struct {
+	UnknownV1
+	Rest msgp.Raw `msg:",unknown"`
+}
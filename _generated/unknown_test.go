@@ -0,0 +1,120 @@
+package _generated
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/bytedance/msgp/msgp"
+)
+
+func TestUnknownFieldsMarshal(t *testing.T) {
+	in := UnknownV2{
+		Name:  "jim",
+		Age:   40,
+		Email: "jim@example.com",
+		Tags:  []string{"a", "b"},
+		Attrs: map[string]string{"k": "v"},
+	}
+	bts, err := in.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mid UnknownV1
+	left, err := mid.UnmarshalMsg(bts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(left) > 0 {
+		t.Errorf("%d bytes left over", len(left))
+	}
+	if mid.Name != "jim" || mid.Age != 40 {
+		t.Fatalf("bad known fields: %+v", mid)
+	}
+	mid.Age = 41
+
+	bts, err = mid.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bts) > mid.Msgsize() {
+		t.Errorf("Msgsize() = %d; encoded %d bytes", mid.Msgsize(), len(bts))
+	}
+	var out UnknownV2
+	_, err = out.UnmarshalMsg(bts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	in.Age = 41
+	if !reflect.DeepEqual(in, out) {
+		t.Fatalf("round trip mismatch:\n in: %+v\nout: %+v", in, out)
+	}
+
+	// decoding again must not accumulate fields
+	_, err = mid.UnmarshalMsg(bts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := msgp.UnknownFieldCount(mid.Unknown)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Errorf("expected 3 unknown fields; found %d", n)
+	}
+}
+
+func TestUnknownFieldsEncode(t *testing.T) {
+	in := UnknownV2{
+		Name:  "jim",
+		Age:   40,
+		Email: "jim@example.com",
+		Tags:  []string{"a", "b"},
+		Attrs: map[string]string{"k": "v"},
+	}
+	var buf bytes.Buffer
+	err := msgp.Encode(&buf, &in)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mid UnknownV1
+	err = msgp.Decode(&buf, &mid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	err = msgp.Encode(&buf, &mid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out UnknownV2
+	err = msgp.Decode(&buf, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Fatalf("round trip mismatch:\n in: %+v\nout: %+v", in, out)
+	}
+}
+
+func TestUnknownFieldsEmbedded(t *testing.T) {
+	in := UnknownV2{Name: "jim", Age: 40, Email: "jim@example.com"}
+	bts, err := in.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := UnknownEmbedded{Rest: msgp.Raw{0xc0}}
+	if _, err = out.UnmarshalMsg(bts); err != nil {
+		t.Fatal(err)
+	}
+	if out.Name != "jim" || out.Age != 40 {
+		t.Errorf("embedded fields not decoded: %+v", out.UnknownV1)
+	}
+	if len(out.Rest) != 1 {
+		t.Errorf("Rest = %x; want it left alone", []byte(out.Rest))
+	}
+}
@@ -0,0 +1,14 @@
+package _generated
+
+//go:generate msgp
+
+// ZeroCopy fields alias the buffer
+// passed to UnmarshalMsg.
+type ZeroCopy struct {
+	Name   string            `msg:"name,zerocopy"`
+	Data   []byte            `msg:"data,zerocopy"`
+	Tags   []string          `msg:"tags,zerocopy"`
+	Attrs  map[string]string `msg:"attrs,zerocopy"`
+	Opt    *string           `msg:"opt,zerocopy"`
+	Copied string            `msg:"copied"`
+}
@@ -0,0 +1,52 @@
+package _generated
+
+import (
+	"reflect"
+	"testing"
+	"unsafe"
+)
+
+func TestZeroCopyAliases(t *testing.T) {
+	opt := "optional"
+	in := ZeroCopy{
+		Name:   "name",
+		Data:   []byte("data"),
+		Tags:   []string{"a", "b"},
+		Attrs:  map[string]string{"k": "v"},
+		Opt:    &opt,
+		Copied: "copied",
+	}
+	bts, err := in.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out ZeroCopy
+	left, err := out.UnmarshalMsg(bts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(left) != 0 {
+		t.Errorf("%d bytes left over", len(left))
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Fatalf("%#v != %#v", in, out)
+	}
+
+	start := uintptr(unsafe.Pointer(&bts[0]))
+	end := start + uintptr(len(bts))
+	inbuf := func(s string) bool {
+		p := (*reflect.StringHeader)(unsafe.Pointer(&s)).Data
+		return p >= start && p < end
+	}
+	for _, s := range []string{out.Name, out.Tags[0], out.Tags[1], out.Attrs["k"], *out.Opt} {
+		if !inbuf(s) {
+			t.Errorf("%q does not alias the input buffer", s)
+		}
+	}
+	if p := uintptr(unsafe.Pointer(&out.Data[0])); p < start || p >= end {
+		t.Error("Data does not alias the input buffer")
+	}
+	if inbuf(out.Copied) {
+		t.Error("Copied aliases the input buffer")
+	}
+}
@@ -16,8 +16,9 @@ func decode(w io.Writer) *decodeGen {
 
 type decodeGen struct {
 	passes
-	p        printer
-	hasfield bool
+	p           printer
+	hasfield    bool
+	hasfieldnum bool
 }
 
 func (d *decodeGen) Method() Method { return Decode }
@@ -30,12 +31,21 @@ func (d *decodeGen) needsField() {
 	d.hasfield = true
 }
 
+func (d *decodeGen) needsFieldNum() {
+	if d.hasfieldnum {
+		return
+	}
+	d.p.print("\nvar fieldnum int64; _ = fieldnum")
+	d.hasfieldnum = true
+}
+
 func (d *decodeGen) Execute(p Elem) error {
 	p = d.applyall(p)
 	if p == nil {
 		return nil
 	}
 	d.hasfield = false
+	d.hasfieldnum = false
 	if !d.p.ok() {
 		return d.p.err
 	}
@@ -44,9 +54,13 @@ func (d *decodeGen) Execute(p Elem) error {
 		return nil
 	}
 
-	d.p.comment("DecodeMsg implements msgp.Decodable")
-
-	d.p.printf("\nfunc (%s %s) DecodeMsg(dc *msgp.Reader) (err error) {", p.Varname(), methodReceiver(p))
+	if name := ExternalName(p.TypeName()); name != "" {
+		d.p.comment(fmt.Sprintf("Decode%s reads a %s from 'dc'", name, p.TypeName()))
+		d.p.printf("\nfunc Decode%s(dc *msgp.Reader, %s %s) (err error) {", name, p.Varname(), methodReceiver(p))
+	} else {
+		d.p.comment("DecodeMsg implements msgp.Decodable")
+		d.p.printf("\nfunc (%s %s) DecodeMsg(dc *msgp.Reader) (err error) {", p.Varname(), methodReceiver(p))
+	}
 	next(d, p)
 	d.p.nakedReturn()
 	unsetReceiver(p)
@@ -59,6 +73,8 @@ func (d *decodeGen) gStruct(s *Struct) {
 	}
 	if s.AsTuple {
 		d.structAsTuple(s)
+	} else if s.AsIntKeys {
+		d.structAsIntKeys(s)
 	} else {
 		d.structAsMap(s)
 	}
@@ -93,6 +109,9 @@ func (d *decodeGen) structAsMap(s *Struct) {
 	sz := randIdent()
 	d.p.declare(sz, u32)
 	d.assignAndCheck(sz, mapHeader)
+	if s.Unknown != nil {
+		d.p.printf("\n%[1]s = %[1]s[:0]", s.UnknownVarname())
+	}
 
 	d.p.printf("\nfor %s > 0 {\n%s--", sz, sz)
 	d.assignAndCheck("field", mapKey)
@@ -123,6 +142,9 @@ func (d *decodeGen) structAsMap(s *Struct) {
 			"\nvar _r = bytes.NewReader(nil)" +
 			embeddedCode
 		d.p.print(embeddedCode)
+	} else if s.Unknown != nil {
+		d.p.printf("\ndefault:\n%[1]s, err = dc.ReadUnknownField(%[1]s, field)", s.UnknownVarname())
+		d.p.print(errcheck)
 	} else {
 		d.p.print("\ndefault:\nerr = dc.Skip()")
 		d.p.print(errcheck)
@@ -131,11 +153,48 @@ func (d *decodeGen) structAsMap(s *Struct) {
 	d.p.closeblock() // close for loop
 }
 
+func (d *decodeGen) structAsIntKeys(s *Struct) {
+	d.needsFieldNum()
+	sz := randIdent()
+	d.p.declare(sz, u32)
+	d.assignAndCheck(sz, mapHeader)
+
+	d.p.printf("\nfor %s > 0 {\n%s--", sz, sz)
+	d.assignAndCheck("fieldnum", "Int64")
+	d.p.print("\nswitch fieldnum {")
+	for i := range s.Fields {
+		d.p.printf("\ncase %d:", fieldNum(s.Fields[i]))
+		next(d, s.Fields[i].FieldElem)
+		if !d.p.ok() {
+			return
+		}
+	}
+	d.p.print("\ndefault:\nerr = dc.Skip()")
+	d.p.print(errcheck)
+	d.p.closeblock() // close switch
+	d.p.closeblock() // close for loop
+}
+
 func (d *decodeGen) gBase(b *BaseElem) {
 	if !d.p.ok() {
 		return
 	}
 
+	if b.AllowNil && b.Value == Bytes {
+		d.readNil(b.Varname())
+		defer d.p.closeblock()
+	}
+
+	// enums are read by name or value
+	if b.Enum != nil {
+		tmp := randIdent()
+		d.p.printf("\n{ var %s int64", tmp)
+		d.p.printf("\n%s, err = dc.ReadEnum(%s)", tmp, enumVar(b))
+		d.p.print(errcheck)
+		d.p.printf("\n%s = %s(%s)\n}", b.Varname(), b.TypeName(), tmp)
+		return
+	}
+
 	// open block for 'tmp'
 	var tmp string
 	if b.Convert {
@@ -158,11 +217,24 @@ func (d *decodeGen) gBase(b *BaseElem) {
 	case IDENT:
 		if b.TypeName() == "msgp.Any" {
 			d.p.printf("\n%s, err = msgp.DecodeAny(dc)", vname)
+		} else if b.Marshaler != MsgMarshaler {
+			d.p.printf("\nerr = dc.Read%sUnmarshaler(%s)", b.Marshaler, addressOf(vname))
+		} else if b.External {
+			d.p.printf("\nerr = Decode%s(dc, %s)", ExternalName(b.TypeName()), addressOf(vname))
 		} else {
 			d.p.printf("\nerr = %s.DecodeMsg(dc)", vname)
 		}
 	case Ext:
 		d.p.printf("\nerr = dc.ReadExtension(%s)", vname)
+	case Time:
+		if b.Convert {
+			vname = tmp
+		}
+		if f := b.timeFormat(); f != "" {
+			d.p.printf("\n%s, err = dc.ReadTimeAs(%s)", vname, f)
+		} else {
+			d.p.printf("\n%s, err = dc.Read%s()", vname, bname)
+		}
 	default:
 		if b.Convert {
 			d.p.printf("\n%s, err = dc.Read%s()", tmp, bname)
@@ -172,6 +244,15 @@ func (d *decodeGen) gBase(b *BaseElem) {
 	}
 	d.p.print(errcheck)
 
+	// an empty []byte must not be read as nil
+	if b.AllowNil && b.Value == Bytes {
+		if b.Convert {
+			d.p.printf("\nif %[1]s == nil { %[1]s = []byte{} }", tmp)
+		} else {
+			d.p.printf("\nif %[1]s == nil { %[1]s = []byte{} }", vname)
+		}
+	}
+
 	// close block for 'tmp'
 	if b.Convert {
 		if b.ShimMode == Cast {
@@ -183,10 +264,49 @@ func (d *decodeGen) gBase(b *BaseElem) {
 	}
 }
 
+// columnar reads a slice of structs written as
+// a map of each field to an array of its values.
+// The first array sets the number of rows, and
+// the others must have the same length.
+func (d *decodeGen) columnar(s *Slice) {
+	st := s.Els.(*Struct)
+	d.needsField()
+	sz, rows, n := randIdent(), randIdent(), randIdent()
+	d.p.declare(sz, u32)
+	d.assignAndCheck(sz, mapHeader)
+	d.p.printf("\n%s := -1", rows)
+	d.p.printf("\nfor %s > 0 {\n%s--", sz, sz)
+	d.assignAndCheck("field", mapKey)
+	d.p.declare(n, u32)
+	d.assignAndCheck(n, arrayHeader)
+	d.p.printf("\nif %s < 0 {", rows)
+	d.p.resizeSlice(n, s)
+	d.p.printf("\n%s = int(%s)\n} else if int(%s) != %s {", rows, n, n, rows)
+	d.p.printf("\nerr = msgp.ArrayError{Wanted: uint32(%s), Got: %s}\nreturn\n}", rows, n)
+	d.p.print("\nswitch msgp.UnsafeString(field) {")
+	for i := range st.Fields {
+		if !d.p.ok() {
+			return
+		}
+		d.p.printf("\ncase \"%s\":", st.Fields[i].FieldTag)
+		d.p.rangeBlock(s.Index, s.Varname(), d, st.Fields[i].FieldElem)
+	}
+	d.p.printf("\ndefault:\nfor ; %[1]s > 0; %[1]s-- {\nerr = dc.Skip()", n)
+	d.p.print(errcheck)
+	d.p.closeblock() // close skip loop
+	d.p.closeblock() // close switch
+	d.p.closeblock() // close for loop
+	d.p.printf("\nif %s < 0 {\n%s = (%s)[:0]\n}", rows, s.Varname(), s.Varname())
+}
+
 func (d *decodeGen) gMap(m *Map) {
 	if !d.p.ok() {
 		return
 	}
+	if m.AllowNil {
+		d.readNil(m.Varname())
+		defer d.p.closeblock()
+	}
 	sz := randIdent()
 
 	// resize or allocate map
@@ -209,6 +329,19 @@ func (d *decodeGen) gSlice(s *Slice) {
 	if !d.p.ok() {
 		return
 	}
+	if s.AllowNil {
+		d.readNil(s.Varname())
+		defer d.p.closeblock()
+	}
+	if s.Packed {
+		d.p.printf("\n%[1]s, err = dc.Read%[2]s(%[1]s)", s.Varname(), s.packedName())
+		d.p.print(errcheck)
+		return
+	}
+	if s.Columnar {
+		d.columnar(s)
+		return
+	}
 	sz := randIdent()
 	d.p.declare(sz, u32)
 	d.assignAndCheck(sz, arrayHeader)
@@ -235,14 +368,21 @@ func (d *decodeGen) gArray(a *Array) {
 	d.p.rangeBlock(a.Index, a.Varname(), d, a.Els)
 }
 
+// readNil opens a block that sets 'vname'
+// to nil if the next object is nil, or
+// otherwise continues in the else branch
+func (d *decodeGen) readNil(vname string) {
+	d.p.print("\nif dc.IsNil() {")
+	d.p.print("\nerr = dc.ReadNil()")
+	d.p.print(errcheck)
+	d.p.printf("\n%s = nil\n} else {", vname)
+}
+
 func (d *decodeGen) gPtr(p *Ptr) {
 	if !d.p.ok() {
 		return
 	}
-	d.p.print("\nif dc.IsNil() {")
-	d.p.print("\nerr = dc.ReadNil()")
-	d.p.print(errcheck)
-	d.p.printf("\n%s = nil\n} else {", p.Varname())
+	d.readNil(p.Varname())
 	d.p.initPtr(p)
 	next(d, p.Value)
 	d.p.closeblock()
@@ -92,6 +92,17 @@ const (
 	Time // time.Time
 	Ext  // extension
 
+	// standard library types
+	Duration    // time.Duration
+	BigInt      // *big.Int
+	BigFloat    // *big.Float
+	NetipAddr   // netip.Addr
+	NetipPrefix // netip.Prefix
+	NetIP       // net.IP
+	URL         // *url.URL
+	JSONNumber  // json.Number
+	Location    // *time.Location
+
 	IDENT // IDENT means an unrecognized identifier
 )
 
@@ -120,6 +131,34 @@ var primitives = map[string]Primitive{
 	"interface{}":    Intf,
 	"time.Time":      Time,
 	"msgp.Extension": Ext,
+	"time.Duration":  Duration,
+	"*big.Int":       BigInt,
+	"*big.Float":     BigFloat,
+	"netip.Addr":     NetipAddr,
+	"netip.Prefix":   NetipPrefix,
+	"net.IP":         NetIP,
+	"*url.URL":       URL,
+	"json.Number":    JSONNumber,
+	"*time.Location": Location,
+}
+
+// typeNames are the Go types of the
+// primitives that are not named after
+// their Read/Write methods
+var typeNames = map[Primitive]string{
+	Intf:        "interface{}",
+	Bytes:       "[]byte",
+	Time:        "time.Time",
+	Ext:         "msgp.Extension",
+	Duration:    "time.Duration",
+	BigInt:      "*big.Int",
+	BigFloat:    "*big.Float",
+	NetipAddr:   "netip.Addr",
+	NetipPrefix: "netip.Prefix",
+	NetIP:       "net.IP",
+	URL:         "*url.URL",
+	JSONNumber:  "json.Number",
+	Location:    "*time.Location",
 }
 
 // types built into the library
@@ -233,9 +272,11 @@ func (a *Array) Complexity() int { return 1 + a.Els.Complexity() }
 // Map is a map[string]Elem
 type Map struct {
 	common
-	Keyidx string // key variable name
-	Validx string // value variable name
-	Value  Elem   // value element
+	Keyidx   string // key variable name
+	Validx   string // value variable name
+	Value    Elem   // value element
+	AllowNil bool   // encode a nil map as nil
+	Alloc    string // allocator of a pooled struct's field, or empty
 }
 
 func (m *Map) SetVarname(s string) {
@@ -270,8 +311,12 @@ func (m *Map) Complexity() int { return 2 + m.Value.Complexity() }
 
 type Slice struct {
 	common
-	Index string
-	Els   Elem // The type of each element
+	Index    string
+	Els      Elem   // The type of each element
+	AllowNil bool   // encode a nil slice as nil
+	Packed   bool   // encode the elements as one bin object
+	Columnar bool   // encode a slice of structs as one array per field
+	Alloc    string // allocator of a pooled struct's field, or empty
 }
 
 func (s *Slice) SetVarname(a string) {
@@ -303,6 +348,35 @@ func (s *Slice) Complexity() int {
 	return 1 + s.Els.Complexity()
 }
 
+// PackedWidth returns the size of an element of
+// a slice of 'p' written in the packed format,
+// or zero if it can't be packed.
+func PackedWidth(p Primitive) int {
+	switch p {
+	case Int16, Uint16:
+		return 2
+	case Int32, Uint32, Float32:
+		return 4
+	case Int64, Uint64, Float64:
+		return 8
+	default:
+		return 0
+	}
+}
+
+// packedName returns the name of the msgp
+// functions for a packed slice, e.g.
+// "PackedFloat64s" for a []float64
+func (s *Slice) packedName() string {
+	return "Packed" + s.Els.(*BaseElem).BaseName() + "s"
+}
+
+// packedWidth returns the size
+// of an element of a packed slice
+func (s *Slice) packedWidth() int {
+	return PackedWidth(s.Els.(*BaseElem).Value)
+}
+
 type Ptr struct {
 	common
 	Value Elem
@@ -320,8 +394,9 @@ func (s *Ptr) SetVarname(a string) {
 		return
 
 	case *BaseElem:
-		// identities have pointer receivers
-		if x.Value == IDENT {
+		// identities have pointer receivers,
+		// but marshalers take their address
+		if x.Value == IDENT && x.Marshaler == MsgMarshaler && !x.External {
 			x.SetVarname(a)
 		} else {
 			x.SetVarname("*" + a)
@@ -359,8 +434,13 @@ func (s *Ptr) Needsinit() bool {
 
 type Struct struct {
 	common
-	Fields  []StructField // field list
-	AsTuple bool          // write as an array instead of a map
+	Fields    []StructField // field list
+	AsTuple   bool          // write as an array instead of a map
+	AsIntKeys bool          // write as a map keyed by integer field tags
+	Unknown   *UnknownField // preserves unrecognized fields, or nil
+	Pooled    bool          // has generated Acquire/Reset/Release functions
+	Interned  bool          // UnmarshalMsg interns strings and map keys
+	ExtType   string        // generated msgp.Extension type number, or empty
 }
 
 func (s *Struct) TypeName() string {
@@ -369,10 +449,16 @@ func (s *Struct) TypeName() string {
 	}
 	str := "struct{\n"
 	for i := range s.Fields {
+		if s.Unknown != nil && s.Unknown.Index == i {
+			str += s.Unknown.FieldName + " msgp.Raw " + s.Unknown.RawTag + ";\n"
+		}
 		str += s.Fields[i].FieldName +
 			" " + s.Fields[i].FieldElem.TypeName() +
 			" " + s.Fields[i].RawTag + ";\n"
 	}
+	if s.Unknown != nil && s.Unknown.Index >= len(s.Fields) {
+		str += s.Unknown.FieldName + " msgp.Raw " + s.Unknown.RawTag + ";\n"
+	}
 	str += "}"
 	s.common.Alias(str)
 	return s.common.alias
@@ -393,6 +479,12 @@ func (s *Struct) Copy() Elem {
 	return &g
 }
 
+// UnknownVarname returns the variable name
+// of the struct's unknown-field holder.
+func (s *Struct) UnknownVarname() string {
+	return s.Varname() + "." + s.Unknown.FieldName
+}
+
 func (s *Struct) Complexity() int {
 	c := 1
 	for i := range s.Fields {
@@ -409,6 +501,16 @@ type StructField struct {
 	Expandable bool   // expandable anonymous field
 }
 
+// UnknownField is a msgp.Raw field tagged
+// `msg:",unknown"`. Decoders append the key/value
+// pairs they don't recognize to it, and encoders
+// write them back out after the known fields.
+type UnknownField struct {
+	FieldName string // the name of the struct field
+	RawTag    string // the full struct tag
+	Index     int    // position among the other fields
+}
+
 type ShimMode int
 
 const (
@@ -416,6 +518,48 @@ const (
 	Convert
 )
 
+// Marshaler selects the methods
+// through which an IDENT is encoded.
+type Marshaler uint8
+
+const (
+	MsgMarshaler    Marshaler = iota // the msgp interfaces
+	BinaryMarshaler                  // encoding.BinaryMarshaler and BinaryUnmarshaler
+	TextMarshaler                    // encoding.TextMarshaler and TextUnmarshaler
+)
+
+// String returns the prefix of the runtime
+// functions for the marshaler, e.g. "Binary"
+// for msgp.WriteBinaryMarshaler.
+func (m Marshaler) String() string {
+	switch m {
+	case BinaryMarshaler:
+		return "Binary"
+	case TextMarshaler:
+		return "Text"
+	default:
+		return "Msg"
+	}
+}
+
+// enumVar returns the name of the
+// generated *msgp.Enum for an enum type
+func enumVar(b *BaseElem) string {
+	return "msgpEnum" + b.TypeName()
+}
+
+// ExternalName returns the suffix of the free
+// functions generated for a type defined in
+// another package, e.g. "YType" for y.Type,
+// or the empty string for local types.
+func ExternalName(typ string) string {
+	dot := strings.IndexByte(typ, '.')
+	if dot <= 0 {
+		return ""
+	}
+	return strings.ToUpper(typ[:1]) + typ[1:dot] + typ[dot+1:]
+}
+
 // BaseElem is an element that
 // can be represented by a primitive
 // MessagePack type.
@@ -426,6 +570,17 @@ type BaseElem struct {
 	ShimFromBase string    // shim from base type, or empty
 	Value        Primitive // Type of element
 	Convert      bool      // should we do an explicit conversion?
+	ZeroCopy     bool      // alias the input buffer in UnmarshalMsg
+	Pooled       bool      // identity has generated Acquire/Reset/Release functions
+	Timestamp    bool      // write time.Time as a timestamp extension (-1)
+	TimeAs       string    // time.Time tag option: unix, unixms, unixns or rfc3339
+	AllowNil     bool      // encode nil []byte as nil
+	Marshaler    Marshaler // methods used to encode an IDENT
+	External     bool      // IDENT is encoded by generated free functions
+	ExtType      string    // generated msgp.Extension type number, or empty
+	Enum         []string  // constants of an enum type, or nil
+	EnumAsInt    bool      // write an enum as its integer value
+	EnumString   bool      // generate a String method for an enum
 	mustinline   bool      // must inline; not printable
 	needsref     bool      // needs reference for shim
 }
@@ -495,27 +650,51 @@ func (s *BaseElem) BaseName() string {
 	return s.Value.String()
 }
 
+// writeName is the suffix of the Write and
+// Append methods for the element, which differs
+// from BaseName for timestamps
+func (s *BaseElem) writeName() string {
+	if s.Value == Time && s.Timestamp {
+		return "Timestamp"
+	}
+	return s.BaseName()
+}
+
+// timeFormat returns the msgp.TimeFormat for
+// a time.Time with an encoding option, or ""
+func (s *BaseElem) timeFormat() string {
+	if s.Value != Time {
+		return ""
+	}
+	switch s.TimeAs {
+	case "unix":
+		return "msgp.TimeUnix"
+	case "unixms":
+		return "msgp.TimeUnixMilli"
+	case "unixns":
+		return "msgp.TimeUnixNano"
+	case "rfc3339":
+		return "msgp.TimeRFC3339"
+	default:
+		return ""
+	}
+}
+
 func (s *BaseElem) BaseType() string {
 	switch s.Value {
 	case IDENT:
 		return s.TypeName()
+	}
 
 	// exceptions to the naming/capitalization
-	// rule:
-	case Intf:
-		return "interface{}"
-	case Bytes:
-		return "[]byte"
-	case Time:
-		return "time.Time"
-	case Ext:
-		return "msgp.Extension"
+	// rule
+	if name, ok := typeNames[s.Value]; ok {
+		return name
+	}
 
 	// everything else is base.String() with
 	// the first letter as lowercase
-	default:
-		return strings.ToLower(s.BaseName())
-	}
+	return strings.ToLower(s.BaseName())
 }
 
 func (s *BaseElem) Needsref(b bool) {
@@ -593,6 +772,24 @@ func (k Primitive) String() string {
 		return "time.Time"
 	case Ext:
 		return "Extension"
+	case Duration:
+		return "Duration"
+	case BigInt:
+		return "BigInt"
+	case BigFloat:
+		return "BigFloat"
+	case NetipAddr:
+		return "NetipAddr"
+	case NetipPrefix:
+		return "NetipPrefix"
+	case NetIP:
+		return "NetIP"
+	case URL:
+		return "URL"
+	case JSONNumber:
+		return "JSONNumber"
+	case Location:
+		return "Location"
 	case IDENT:
 		return "Ident"
 	default:
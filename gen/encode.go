@@ -57,9 +57,14 @@ func (e *encodeGen) Execute(p Elem) error {
 		return nil
 	}
 
-	e.p.comment("EncodeMsg implements msgp.Encodable")
-
-	e.p.printf("\nfunc (%s %s) EncodeMsg(en *msgp.Writer) (err error) {", p.Varname(), imutMethodReceiver(p))
+	if name := ExternalName(p.TypeName()); name != "" {
+		e.p.comment(fmt.Sprintf("Encode%s writes a %s to 'en'", name, p.TypeName()))
+		e.p.printf("\nfunc Encode%s(en *msgp.Writer, %s %s) (err error) {", name, p.Varname(), methodReceiver(p))
+		defer unsetReceiver(p)
+	} else {
+		e.p.comment("EncodeMsg implements msgp.Encodable")
+		e.p.printf("\nfunc (%s %s) EncodeMsg(en *msgp.Writer) (err error) {", p.Varname(), imutMethodReceiver(p))
+	}
 	next(e, p)
 	e.p.nakedReturn()
 	return e.p.err
@@ -106,21 +111,36 @@ func (e *encodeGen) appendraw(bts []byte) {
 
 func (e *encodeGen) structmap(s *Struct) {
 	nfields := len(s.Fields)
-	data := msgp.AppendMapHeader(nil, uint32(nfields))
-	e.p.printf("\n// map header, size %d", nfields)
-	e.Fuse(data)
-	if len(s.Fields) == 0 {
+	var data []byte
+	if s.Unknown != nil {
 		e.fuseHook()
+		unknown := randIdent()
+		e.p.declare(unknown, u32)
+		e.p.printf("\n%s, err = msgp.UnknownFieldCount(%s)", unknown, s.UnknownVarname())
+		e.p.print(errcheck)
+		e.p.printf("\n// map header, size %d + unknown fields", nfields)
+		e.writeAndCheck(mapHeader, literalFmt, fmt.Sprintf("%d + %s", nfields, unknown))
+	} else {
+		data = msgp.AppendMapHeader(nil, uint32(nfields))
+		e.p.printf("\n// map header, size %d", nfields)
+		e.Fuse(data)
+		if len(s.Fields) == 0 {
+			e.fuseHook()
+		}
 	}
 	for i := range s.Fields {
 		if !e.p.ok() {
 			return
 		}
-		data = msgp.AppendString(nil, s.Fields[i].FieldTag)
+		data = appendFieldKey(nil, s, i)
 		e.p.printf("\n// write %q", s.Fields[i].FieldTag)
 		e.Fuse(data)
 		next(e, s.Fields[i].FieldElem)
 	}
+	if s.Unknown != nil {
+		e.p.printf("\n// write unknown fields\n_, err = en.Write(%s)", s.UnknownVarname())
+		e.p.print(errcheck)
+	}
 }
 
 func (e *encodeGen) gMap(m *Map) {
@@ -129,6 +149,10 @@ func (e *encodeGen) gMap(m *Map) {
 	}
 	e.fuseHook()
 	vname := m.Varname()
+	if m.AllowNil {
+		e.writeNil(vname)
+		defer e.p.closeblock()
+	}
 	e.writeAndCheck(mapHeader, lenAsUint32, vname)
 
 	e.p.printf("\nfor %s, %s := range %s {", m.Keyidx, m.Validx, vname)
@@ -137,12 +161,19 @@ func (e *encodeGen) gMap(m *Map) {
 	e.p.closeblock()
 }
 
+// writeNil opens a block that writes nil if
+// 'vname' is nil, or otherwise continues in
+// the else branch
+func (e *encodeGen) writeNil(vname string) {
+	e.p.printf("\nif %s == nil { err = en.WriteNil(); if err != nil { return; } } else {", vname)
+}
+
 func (e *encodeGen) gPtr(s *Ptr) {
 	if !e.p.ok() {
 		return
 	}
 	e.fuseHook()
-	e.p.printf("\nif %s == nil { err = en.WriteNil(); if err != nil { return; } } else {", s.Varname())
+	e.writeNil(s.Varname())
 	next(e, s.Value)
 	e.p.closeblock()
 }
@@ -152,10 +183,44 @@ func (e *encodeGen) gSlice(s *Slice) {
 		return
 	}
 	e.fuseHook()
+	if s.AllowNil {
+		e.writeNil(s.Varname())
+		defer e.p.closeblock()
+	}
+	if s.Packed {
+		e.p.printf("\nerr = en.Write%s(%s)", s.packedName(), s.Varname())
+		e.p.print(errcheck)
+		return
+	}
+	if s.Columnar {
+		e.columnar(s)
+		return
+	}
 	e.writeAndCheck(arrayHeader, lenAsUint32, s.Varname())
 	e.p.rangeBlock(s.Index, s.Varname(), e, s.Els)
 }
 
+// columnar writes a slice of structs as a map
+// of each field to an array of its values
+func (e *encodeGen) columnar(s *Slice) {
+	st := s.Els.(*Struct)
+	e.p.printf("\n// map header, size %d", len(st.Fields))
+	e.Fuse(msgp.AppendMapHeader(nil, uint32(len(st.Fields))))
+	if len(st.Fields) == 0 {
+		e.fuseHook()
+	}
+	for i := range st.Fields {
+		if !e.p.ok() {
+			return
+		}
+		e.p.printf("\n// write %q", st.Fields[i].FieldTag)
+		e.Fuse(msgp.AppendString(nil, st.Fields[i].FieldTag))
+		e.fuseHook()
+		e.writeAndCheck(arrayHeader, lenAsUint32, s.Varname())
+		e.p.rangeBlock(s.Index, s.Varname(), e, st.Fields[i].FieldElem)
+	}
+}
+
 func (e *encodeGen) gArray(a *Array) {
 	if !e.p.ok() {
 		return
@@ -177,6 +242,15 @@ func (e *encodeGen) gBase(b *BaseElem) {
 		return
 	}
 	e.fuseHook()
+	if b.AllowNil && b.Value == Bytes {
+		e.writeNil(b.Varname())
+		defer e.p.closeblock()
+	}
+	if b.Enum != nil && !b.EnumAsInt {
+		e.p.printf("\nerr = en.WriteEnum(%s, int64(%s))", enumVar(b), b.Varname())
+		e.p.print(errcheck)
+		return
+	}
 	vname := b.Varname()
 	if b.Convert {
 		if b.ShimMode == Cast {
@@ -192,11 +266,18 @@ func (e *encodeGen) gBase(b *BaseElem) {
 	if b.Value == IDENT { // unknown identity
 		if b.TypeName() == "msgp.Any" {
 			e.p.printf("\nerr = msgp.EncodeAny(%s,en)", vname)
+		} else if b.Marshaler != MsgMarshaler {
+			e.p.printf("\nerr = en.Write%sMarshaler(%s)", b.Marshaler, addressOf(vname))
+		} else if b.External {
+			e.p.printf("\nerr = Encode%s(en, %s)", ExternalName(b.TypeName()), addressOf(vname))
 		} else {
 			e.p.printf("\nerr = %s.EncodeMsg(en)", vname)
 		}
 		e.p.print(errcheck)
+	} else if f := b.timeFormat(); f != "" {
+		e.p.printf("\nerr = en.WriteTimeAs(%s, %s)", vname, f)
+		e.p.print(errcheck)
 	} else { // typical case
-		e.writeAndCheck(b.BaseName(), literalFmt, vname)
+		e.writeAndCheck(b.writeName(), literalFmt, vname)
 	}
 }
@@ -0,0 +1,67 @@
+package gen
+
+import (
+	"io"
+	"strings"
+)
+
+func enum(w io.Writer) *enumGen {
+	return &enumGen{p: printer{w: w}}
+}
+
+// enumGen prints the msgp.Enum describing
+// an enum type, along with its String method
+// and Parse function.
+type enumGen struct {
+	passes
+	p printer
+}
+
+func (e *enumGen) Method() Method { return Decode | Encode | Marshal | Unmarshal | Size }
+
+func (e *enumGen) Apply(dirs []string) error {
+	return nil
+}
+
+func (e *enumGen) Execute(el Elem) error {
+	if !e.p.ok() {
+		return e.p.err
+	}
+	el = e.applyall(el)
+	if el == nil {
+		return nil
+	}
+	b, ok := el.(*BaseElem)
+	if !ok || b.Enum == nil || !IsPrintable(b) {
+		return nil
+	}
+	name := b.TypeName()
+	vname := enumVar(b)
+
+	// constants of 64-bit unsigned types may
+	// not fit in an int64 at compile time
+	ctor, conv := "NewEnum", "int64"
+	if b.Value == Uint || b.Value == Uint64 {
+		ctor, conv = "NewEnumUint64", "uint64"
+	}
+	values := make([]string, len(b.Enum))
+	for i, c := range b.Enum {
+		values[i] = conv + "(" + c + ")"
+	}
+	e.p.comment(vname + " holds the names and values of the " + name + " constants")
+	e.p.printf("\nvar %s = msgp.%s(%q, []string{", vname, ctor, name)
+	for _, c := range b.Enum {
+		e.p.printf("%q, ", c)
+	}
+	e.p.printf("}, []%s{%s})\n", conv, strings.Join(values, ", "))
+
+	if b.EnumString {
+		e.p.comment("String returns the name of the " + name + " constant")
+		e.p.printf("\nfunc (z %s) String() string { return %s.String(int64(z)) }\n", name, vname)
+	}
+
+	e.p.comment("Parse" + name + " returns the " + name + " constant named 's'")
+	e.p.printf("\nfunc Parse%s(s string) (%s, error) {", name, name)
+	e.p.printf("\nv, err := %s.Parse(s)\nreturn %s(v), err\n}\n", vname, name)
+	return e.p.err
+}
@@ -0,0 +1,62 @@
+package gen
+
+import (
+	"io"
+)
+
+func extension(w io.Writer) *extensionGen {
+	return &extensionGen{p: printer{w: w}}
+}
+
+// extensionGen prints the msgp.Extension methods
+// for types with an extension type number, and
+// registers them with msgp.RegisterExtension.
+type extensionGen struct {
+	passes
+	p printer
+}
+
+func (x *extensionGen) Method() Method { return Marshal | Unmarshal }
+
+func (x *extensionGen) Apply(dirs []string) error {
+	return nil
+}
+
+func (x *extensionGen) Execute(e Elem) error {
+	if !x.p.ok() {
+		return x.p.err
+	}
+	e = x.applyall(e)
+	if e == nil || !IsPrintable(e) || ExternalName(e.TypeName()) != "" {
+		return nil
+	}
+	var typ string
+	switch e := e.(type) {
+	case *Struct:
+		typ = e.ExtType
+	case *BaseElem:
+		typ = e.ExtType
+	}
+	if typ == "" {
+		return nil
+	}
+	name := e.TypeName()
+
+	x.p.comment("ExtensionType implements msgp.Extension")
+	x.p.printf("\nfunc (z *%s) ExtensionType() int8 { return %s }\n", name, typ)
+
+	x.p.comment("Len implements msgp.Extension")
+	x.p.printf("\nfunc (z *%s) Len() int { return msgp.MarshalLen(z) }\n", name)
+
+	x.p.comment("MarshalBinaryTo implements msgp.Extension")
+	x.p.printf("\nfunc (z *%s) MarshalBinaryTo(b []byte) error { return msgp.MarshalTo(z, b) }\n", name)
+
+	x.p.comment("UnmarshalBinary implements msgp.Extension")
+	x.p.printf("\nfunc (z *%s) UnmarshalBinary(b []byte) error {", name)
+	x.p.print("\n_, err := z.UnmarshalMsg(b)\nreturn err\n}\n")
+
+	x.p.printf("\nfunc init() {")
+	x.p.printf("\nmsgp.RegisterExtension(%s, func() msgp.Extension { return new(%s) })", typ, name)
+	x.p.print("\n}\n")
+	return x.p.err
+}
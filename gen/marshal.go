@@ -37,18 +37,24 @@ func (m *marshalGen) Execute(p Elem) error {
 		return nil
 	}
 
-	m.p.comment("MarshalMsg implements msgp.Marshaler")
-
 	// save the vname before
 	// calling methodReceiver so
 	// that z.Msgsize() is printed correctly
 	c := p.Varname()
 
-	m.p.printf("\nfunc (%s %s) MarshalMsg(b []byte) (o []byte, err error) {", p.Varname(), imutMethodReceiver(p))
-	if p.TypeName() == "msgp.Any" {
-		m.p.printf("\no = msgp.Require(b, %s.Msgsize()+1)", c)
+	if name := ExternalName(p.TypeName()); name != "" {
+		m.p.comment(fmt.Sprintf("Append%s appends a %s to 'b'", name, p.TypeName()))
+		m.p.printf("\nfunc Append%s(b []byte, %s %s) (o []byte, err error) {", name, p.Varname(), methodReceiver(p))
+		m.p.printf("\no = msgp.Require(b, Size%s(%s))", name, c)
+		defer unsetReceiver(p)
 	} else {
-		m.p.printf("\no = msgp.Require(b, %s.Msgsize())", c)
+		m.p.comment("MarshalMsg implements msgp.Marshaler")
+		m.p.printf("\nfunc (%s %s) MarshalMsg(b []byte) (o []byte, err error) {", p.Varname(), imutMethodReceiver(p))
+		if p.TypeName() == "msgp.Any" {
+			m.p.printf("\no = msgp.Require(b, %s.Msgsize()+1)", c)
+		} else {
+			m.p.printf("\no = msgp.Require(b, %s.Msgsize())", c)
+		}
 	}
 	next(m, p)
 	m.p.nakedReturn()
@@ -105,23 +111,36 @@ func (m *marshalGen) tuple(s *Struct) {
 
 func (m *marshalGen) mapstruct(s *Struct) {
 	data := make([]byte, 0, 64)
-	data = msgp.AppendMapHeader(data, uint32(len(s.Fields)))
-	m.p.printf("\n// map header, size %d", len(s.Fields))
-	m.Fuse(data)
-	if len(s.Fields) == 0 {
+	if s.Unknown != nil {
 		m.fuseHook()
+		unknown := randIdent()
+		m.p.declare(unknown, u32)
+		m.p.printf("\n%s, err = msgp.UnknownFieldCount(%s)", unknown, s.UnknownVarname())
+		m.p.print(errcheck)
+		m.p.printf("\n// map header, size %d + unknown fields", len(s.Fields))
+		m.rawAppend(mapHeader, literalFmt, fmt.Sprintf("%d + %s", len(s.Fields), unknown))
+	} else {
+		data = msgp.AppendMapHeader(data, uint32(len(s.Fields)))
+		m.p.printf("\n// map header, size %d", len(s.Fields))
+		m.Fuse(data)
+		if len(s.Fields) == 0 {
+			m.fuseHook()
+		}
 	}
 	for i := range s.Fields {
 		if !m.p.ok() {
 			return
 		}
-		data = msgp.AppendString(nil, s.Fields[i].FieldTag)
+		data = appendFieldKey(nil, s, i)
 
 		m.p.printf("\n// string %q", s.Fields[i].FieldTag)
 		m.Fuse(data)
 
 		next(m, s.Fields[i].FieldElem)
 	}
+	if s.Unknown != nil {
+		m.p.printf("\n// unknown fields\no = append(o, %s...)", s.UnknownVarname())
+	}
 }
 
 // append raw data
@@ -139,6 +158,10 @@ func (m *marshalGen) gMap(s *Map) {
 	}
 	m.fuseHook()
 	vname := s.Varname()
+	if s.AllowNil {
+		m.appendNil(vname)
+		defer m.p.closeblock()
+	}
 	m.rawAppend(mapHeader, lenAsUint32, vname)
 	m.p.printf("\nfor %s, %s := range %s {", s.Keyidx, s.Validx, vname)
 	m.rawAppend(stringTyp, literalFmt, s.Keyidx)
@@ -152,10 +175,44 @@ func (m *marshalGen) gSlice(s *Slice) {
 	}
 	m.fuseHook()
 	vname := s.Varname()
+	if s.AllowNil {
+		m.appendNil(vname)
+		defer m.p.closeblock()
+	}
+	if s.Packed {
+		m.p.printf("\no, err = msgp.Append%s(o, %s)", s.packedName(), vname)
+		m.p.print(errcheck)
+		return
+	}
+	if s.Columnar {
+		m.columnar(s)
+		return
+	}
 	m.rawAppend(arrayHeader, lenAsUint32, vname)
 	m.p.rangeBlock(s.Index, vname, m, s.Els)
 }
 
+// columnar appends a slice of structs as a map
+// of each field to an array of its values
+func (m *marshalGen) columnar(s *Slice) {
+	st := s.Els.(*Struct)
+	m.p.printf("\n// map header, size %d", len(st.Fields))
+	m.Fuse(msgp.AppendMapHeader(nil, uint32(len(st.Fields))))
+	if len(st.Fields) == 0 {
+		m.fuseHook()
+	}
+	for i := range st.Fields {
+		if !m.p.ok() {
+			return
+		}
+		m.p.printf("\n// string %q", st.Fields[i].FieldTag)
+		m.Fuse(msgp.AppendString(nil, st.Fields[i].FieldTag))
+		m.fuseHook()
+		m.rawAppend(arrayHeader, lenAsUint32, s.Varname())
+		m.p.rangeBlock(s.Index, s.Varname(), m, st.Fields[i].FieldElem)
+	}
+}
+
 func (m *marshalGen) gArray(a *Array) {
 	if !m.p.ok() {
 		return
@@ -170,12 +227,19 @@ func (m *marshalGen) gArray(a *Array) {
 	m.p.rangeBlock(a.Index, a.Varname(), m, a.Els)
 }
 
+// appendNil opens a block that appends nil if
+// 'vname' is nil, or otherwise continues in
+// the else branch
+func (m *marshalGen) appendNil(vname string) {
+	m.p.printf("\nif %s == nil {\no = msgp.AppendNil(o)\n} else {", vname)
+}
+
 func (m *marshalGen) gPtr(p *Ptr) {
 	if !m.p.ok() {
 		return
 	}
 	m.fuseHook()
-	m.p.printf("\nif %s == nil {\no = msgp.AppendNil(o)\n} else {", p.Varname())
+	m.appendNil(p.Varname())
 	next(m, p.Value)
 	m.p.closeblock()
 }
@@ -185,6 +249,15 @@ func (m *marshalGen) gBase(b *BaseElem) {
 		return
 	}
 	m.fuseHook()
+	if b.AllowNil && b.Value == Bytes {
+		m.appendNil(b.Varname())
+		defer m.p.closeblock()
+	}
+	if b.Enum != nil && !b.EnumAsInt {
+		m.p.printf("\no, err = msgp.AppendEnum(o, %s, int64(%s))", enumVar(b), b.Varname())
+		m.p.print(errcheck)
+		return
+	}
 	vname := b.Varname()
 
 	if b.Convert {
@@ -204,6 +277,10 @@ func (m *marshalGen) gBase(b *BaseElem) {
 		echeck = true
 		if b.TypeName() == "msgp.Any" {
 			m.p.printf("\no, err = msgp.MarshalAny(%s,o)", vname)
+		} else if b.Marshaler != MsgMarshaler {
+			m.p.printf("\no, err = msgp.Append%sMarshaler(o, %s)", b.Marshaler, addressOf(vname))
+		} else if b.External {
+			m.p.printf("\no, err = Append%s(o, %s)", ExternalName(b.TypeName()), addressOf(vname))
 		} else {
 			m.p.printf("\no, err = %s.MarshalMsg(o)", vname)
 		}
@@ -211,7 +288,11 @@ func (m *marshalGen) gBase(b *BaseElem) {
 		echeck = true
 		m.p.printf("\no, err = msgp.Append%s(o, %s)", b.BaseName(), vname)
 	default:
-		m.rawAppend(b.BaseName(), literalFmt, vname)
+		if f := b.timeFormat(); f != "" {
+			m.p.printf("\no = msgp.AppendTimeAs(o, %s, %s)", vname, f)
+		} else {
+			m.rawAppend(b.writeName(), literalFmt, vname)
+		}
 	}
 
 	if echeck {
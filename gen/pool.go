@@ -0,0 +1,216 @@
+package gen
+
+import (
+	"io"
+)
+
+func pool(w io.Writer) *poolGen {
+	return &poolGen{p: printer{w: w}}
+}
+
+// poolGen prints the Acquire, Release and
+// Reset functions for pooled structs.
+type poolGen struct {
+	passes
+	p printer
+}
+
+func (p *poolGen) Method() Method { return Decode | Unmarshal }
+
+func (p *poolGen) Apply(dirs []string) error {
+	return nil
+}
+
+func (p *poolGen) Execute(e Elem) error {
+	if !p.p.ok() {
+		return p.p.err
+	}
+	e = p.applyall(e)
+	if e == nil {
+		return nil
+	}
+	st, ok := e.(*Struct)
+	if !ok || !st.Pooled || !IsPrintable(st) || ExternalName(st.TypeName()) != "" {
+		return nil
+	}
+
+	name := st.TypeName()
+	pool := "msgpPool" + name
+
+	p.p.comment(pool + " is the allocator used by Acquire" + name + " and (*" + name + ").Release")
+	p.p.printf("\nvar %s = msgp.NewAllocatorVar(&sync.Pool{New: func() interface{} { return new(%s) }})\n", pool, name)
+
+	p.p.comment("Set" + name + "Allocator replaces the allocator used by Acquire" + name + " and (*" + name + ").Release")
+	p.p.printf("\nfunc Set%sAllocator(a msgp.Allocator) { %s.Store(a) }\n", name, pool)
+
+	for i := range st.Fields {
+		fe := st.Fields[i].FieldElem
+		alloc, typ := fieldAlloc(fe)
+		if alloc == "" {
+			continue
+		}
+		field := st.Fields[i].FieldName
+		p.p.comment(alloc + " is the allocator of " + name + "." + field)
+		p.p.printf("\nvar %s = msgp.NewAllocatorVar(new(sync.Pool))\n", alloc)
+		p.p.comment("Set" + name + field + "Allocator replaces the allocator of " + name + "." + field + ", which holds values of type " + typ)
+		p.p.printf("\nfunc Set%s%sAllocator(a msgp.Allocator) { %s.Store(a) }\n", name, field, alloc)
+	}
+
+	p.p.comment("Acquire" + name + " returns a " + name + " from its allocator")
+	p.p.printf("\nfunc Acquire%[1]s() *%[1]s {", name)
+	p.p.printf("\nif z, ok := %s.Get().(*%s); ok {\nreturn z\n}", pool, name)
+	p.p.printf("\nreturn new(%s)\n}\n", name)
+
+	p.p.comment("Release resets z and returns it, and its slices and maps, to their allocators")
+	p.p.printf("\nfunc (z *%s) Release() {", name)
+	p.p.print("\nz.Reset()")
+	for i := range st.Fields {
+		fe := st.Fields[i].FieldElem
+		alloc, _ := fieldAlloc(fe)
+		if alloc == "" {
+			continue
+		}
+		vname := "z." + st.Fields[i].FieldName
+		if _, ok := fe.(*Slice); ok {
+			p.p.printf("\nif %[1]s != nil {\nv := %[1]s\n%[2]s.Put(&v)\n%[1]s = nil\n}", vname, alloc)
+		} else {
+			p.p.printf("\nif %[1]s != nil {\n%[2]s.Put(%[1]s)\n%[1]s = nil\n}", vname, alloc)
+		}
+	}
+	p.p.printf("\n%s.Put(z)\n}\n", pool)
+
+	p.p.comment("Reset sets z to its zero value, but keeps the capacity of its slices and maps and releases its pooled children")
+	p.p.printf("\nfunc (%s *%s) Reset() {", st.Varname(), name)
+	p.resetFields(st)
+	p.p.print("\n}\n")
+	return p.p.err
+}
+
+func (p *poolGen) resetFields(st *Struct) {
+	for i := range st.Fields {
+		next(p, st.Fields[i].FieldElem)
+	}
+	if st.Unknown != nil {
+		p.p.printf("\n%[1]s = %[1]s[:0]", st.UnknownVarname())
+	}
+}
+
+func (p *poolGen) gStruct(st *Struct) {
+	if !p.p.ok() {
+		return
+	}
+	if st.Pooled {
+		p.p.printf("\n%s.Reset()", st.Varname())
+		return
+	}
+	p.resetFields(st)
+}
+
+func (p *poolGen) gPtr(pt *Ptr) {
+	if !p.p.ok() {
+		return
+	}
+	if isPooled(pt.Value) {
+		p.p.printf("\nif %[1]s != nil {\n%[1]s.Release()\n%[1]s = nil\n}", pt.Varname())
+		return
+	}
+	p.p.printf("\n%s = nil", pt.Varname())
+}
+
+func (p *poolGen) gSlice(sl *Slice) {
+	if !p.p.ok() {
+		return
+	}
+	if needsReset(sl.Els) {
+		p.p.rangeBlock(sl.Index, sl.Varname(), p, sl.Els)
+	}
+	p.p.printf("\n%[1]s = %[1]s[:0]", sl.Varname())
+}
+
+func (p *poolGen) gArray(a *Array) {
+	if !p.p.ok() {
+		return
+	}
+	p.p.rangeBlock(a.Index, a.Varname(), p, a.Els)
+}
+
+func (p *poolGen) gMap(m *Map) {
+	if !p.p.ok() {
+		return
+	}
+	if pt, ok := m.Value.(*Ptr); ok && isPooled(pt.Value) {
+		p.p.printf("\nfor key, val := range %[1]s {\nif val != nil {\nval.Release()\n}\ndelete(%[1]s, key)\n}", m.Varname())
+		return
+	}
+	p.p.clearMap(m.Varname())
+}
+
+func (p *poolGen) gBase(b *BaseElem) {
+	if !p.p.ok() {
+		return
+	}
+	vname := b.Varname()
+	if b.Pooled {
+		p.p.printf("\n%s.Reset()", vname)
+		return
+	}
+	if b.ShimToBase == "" {
+		switch b.Value {
+		case Bytes:
+			p.p.printf("\n%[1]s = %[1]s[:0]", vname)
+			return
+		case String:
+			p.p.printf("\n%s = \"\"", vname)
+			return
+		case Bool:
+			p.p.printf("\n%s = false", vname)
+			return
+		case Intf:
+			p.p.printf("\n%s = nil", vname)
+			return
+		case Float32, Float64, Complex64, Complex128, Uint, Uint8, Uint16, Uint32, Uint64,
+			Byte, Int, Int8, Int16, Int32, Int64:
+			p.p.printf("\n%s = 0", vname)
+			return
+		}
+	}
+	p.p.printf("\n%s = *new(%s)", vname, b.TypeName())
+}
+
+// fieldAlloc returns the allocator of the slice
+// or map field 'e' of a pooled struct, and the
+// type of the values it holds
+func fieldAlloc(e Elem) (alloc string, typ string) {
+	switch e := e.(type) {
+	case *Slice:
+		return e.Alloc, "*" + e.TypeName()
+	case *Map:
+		return e.Alloc, e.TypeName()
+	default:
+		return "", ""
+	}
+}
+
+// isPooled reports whether 'e' is a struct
+// with generated Acquire/Reset/Release functions
+func isPooled(e Elem) bool {
+	switch e := e.(type) {
+	case *Struct:
+		return e.Pooled
+	case *BaseElem:
+		return e.Pooled
+	default:
+		return false
+	}
+}
+
+// needsReset reports whether the elements of a
+// slice must be reset before it is truncated,
+// because decoding into the spare capacity
+// would not overwrite them completely
+func needsReset(e Elem) bool {
+	if b, ok := e.(*BaseElem); ok {
+		return b.Pooled || b.Value == IDENT || b.Value == Ext || b.ShimToBase != ""
+	}
+	return true
+}
@@ -80,9 +80,14 @@ func (s *sizeGen) Execute(p Elem) error {
 		return nil
 	}
 
-	s.p.comment("Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message")
-
-	s.p.printf("\nfunc (%s %s) Msgsize() (s int) {", p.Varname(), imutMethodReceiver(p))
+	if name := ExternalName(p.TypeName()); name != "" {
+		s.p.comment(fmt.Sprintf("Size%s returns an upper bound estimate of the number of bytes occupied by the serialized %s", name, p.TypeName()))
+		s.p.printf("\nfunc Size%s(%s %s) (s int) {", name, p.Varname(), methodReceiver(p))
+		defer unsetReceiver(p)
+	} else {
+		s.p.comment("Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message")
+		s.p.printf("\nfunc (%s %s) Msgsize() (s int) {", p.Varname(), imutMethodReceiver(p))
+	}
 	s.state = assign
 	next(s, p)
 	s.p.nakedReturn()
@@ -107,13 +112,20 @@ func (s *sizeGen) gStruct(st *Struct) {
 		}
 	} else {
 		data := msgp.AppendMapHeader(nil, nfields)
-		s.addConstant(strconv.Itoa(len(data)))
+		if st.Unknown != nil {
+			s.addConstant(builtinSize(mapHeader))
+		} else {
+			s.addConstant(strconv.Itoa(len(data)))
+		}
 		for i := range st.Fields {
 			data = data[:0]
-			data = msgp.AppendString(data, st.Fields[i].FieldTag)
+			data = appendFieldKey(data, st, i)
 			s.addConstant(strconv.Itoa(len(data)))
 			next(s, st.Fields[i].FieldElem)
 		}
+		if st.Unknown != nil {
+			s.addConstant("len(" + st.UnknownVarname() + ")")
+		}
 	}
 }
 
@@ -130,6 +142,15 @@ func (s *sizeGen) gSlice(sl *Slice) {
 		return
 	}
 
+	if sl.Packed {
+		s.addConstant(fmt.Sprintf("(msgp.BytesPrefixSize + %s*%d)", lenExpr(sl), sl.packedWidth()))
+		return
+	}
+	if sl.Columnar {
+		s.columnar(sl)
+		return
+	}
+
 	s.addConstant(builtinSize(arrayHeader))
 
 	// if the slice's element is a fixed size
@@ -166,6 +187,30 @@ func (s *sizeGen) gArray(a *Array) {
 	s.state = add
 }
 
+// columnar adds the size of a slice of structs
+// written as one array per field
+func (s *sizeGen) columnar(sl *Slice) {
+	st := sl.Els.(*Struct)
+	data := msgp.AppendMapHeader(nil, uint32(len(st.Fields)))
+	s.addConstant(strconv.Itoa(len(data)))
+	for i := range st.Fields {
+		if !s.p.ok() {
+			return
+		}
+		data = msgp.AppendString(data[:0], st.Fields[i].FieldTag)
+		s.addConstant(strconv.Itoa(len(data)))
+		s.addConstant(builtinSize(arrayHeader))
+		el := st.Fields[i].FieldElem
+		if str, ok := fixedsizeExpr(el); ok {
+			s.addConstant(fmt.Sprintf("(%s * (%s))", lenExpr(sl), str))
+			continue
+		}
+		s.state = add
+		s.p.rangeBlock(sl.Index, sl.Varname(), s, el)
+		s.state = add
+	}
+}
+
 func (s *sizeGen) gMap(m *Map) {
 	s.addConstant(builtinSize(mapHeader))
 	vn := m.Varname()
@@ -184,7 +229,9 @@ func (s *sizeGen) gBase(b *BaseElem) {
 	if !s.p.ok() {
 		return
 	}
-	if b.Convert && b.ShimMode == Convert {
+	if b.Enum != nil && !b.EnumAsInt {
+		s.addConstant(enumVar(b) + ".Size()")
+	} else if b.Convert && b.ShimMode == Convert {
 		s.state = add
 		vname := randIdent()
 		s.p.printf("\nvar %s %s", vname, b.BaseType())
@@ -194,6 +241,12 @@ func (s *sizeGen) gBase(b *BaseElem) {
 		s.p.printf("\ns += %s", basesizeExpr(b.Value, vname, b.BaseName(), b.TypeName() == "msgp.Any"))
 		s.state = expr
 
+	} else if b.Value == IDENT && b.Marshaler != MsgMarshaler {
+		s.addConstant(fmt.Sprintf("msgp.%sMarshalerSize(%s)", b.Marshaler, addressOf(b.Varname())))
+	} else if f := b.timeFormat(); f != "" {
+		s.addConstant("msgp.TimeAsSize(" + f + ")")
+	} else if b.Value == IDENT && b.External {
+		s.addConstant(fmt.Sprintf("Size%s(%s)", ExternalName(b.TypeName()), addressOf(b.Varname())))
 	} else {
 		vname := b.Varname()
 		if b.Convert {
@@ -212,7 +265,8 @@ func lenExpr(sl *Slice) string {
 // size on the wire?
 func fixedSize(p Primitive) bool {
 	switch p {
-	case Intf, Ext, IDENT, Bytes, String:
+	case Intf, Ext, IDENT, Bytes, String,
+		BigInt, BigFloat, NetipAddr, NetipPrefix, NetIP, URL, JSONNumber, Location:
 		return false
 	default:
 		return true
@@ -237,10 +291,16 @@ func fixedsizeExpr(e Elem) (string, bool) {
 			return fmt.Sprintf("(%s * (%s))", e.Size, str), true
 		}
 	case *BaseElem:
+		if f := e.timeFormat(); f != "" {
+			return "msgp.TimeAsSize(" + f + ")", true
+		}
 		if fixedSize(e.Value) {
 			return builtinSize(e.BaseName()), true
 		}
 	case *Struct:
+		if e.Unknown != nil {
+			return "", false
+		}
 		var str string
 		for _, f := range e.Fields {
 			if fs, ok := fixedsizeExpr(f.FieldElem); ok {
@@ -257,8 +317,8 @@ func fixedsizeExpr(e Elem) (string, bool) {
 		mhdr := msgp.AppendMapHeader(nil, uint32(len(e.Fields)))
 		hdrlen += len(mhdr)
 		var strbody []byte
-		for _, f := range e.Fields {
-			strbody = msgp.AppendString(strbody[:0], f.FieldTag)
+		for i := range e.Fields {
+			strbody = appendFieldKey(strbody[:0], e, i)
 			hdrlen += len(strbody)
 		}
 		return fmt.Sprintf("%d + %s", hdrlen, str), true
@@ -282,6 +342,8 @@ func basesizeExpr(value Primitive, vname, basename string, isAny bool) string {
 		return "msgp.BytesPrefixSize + len(" + vname + ")"
 	case String:
 		return "msgp.StringPrefixSize + len(" + vname + ")"
+	case BigInt, BigFloat, NetipAddr, NetipPrefix, NetIP, URL, JSONNumber, Location:
+		return builtinSize(basename) + "(" + vname + ")"
 	default:
 		return builtinSize(basename)
 	}
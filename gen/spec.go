@@ -3,6 +3,10 @@ package gen
 import (
 	"fmt"
 	"io"
+	"strconv"
+	"strings"
+
+	"github.com/bytedance/msgp/msgp"
 )
 
 const (
@@ -42,9 +46,11 @@ func (m Method) String() string {
 		return "size"
 	case Test:
 		return "test"
+	case SQL:
+		return "sql"
 	default:
 		// return e.g. "decode+encode+test"
-		modes := [...]Method{Decode, Encode, Marshal, Unmarshal, Size, Test}
+		modes := [...]Method{Decode, Encode, Marshal, Unmarshal, Size, Test, SQL}
 		any := false
 		nm := ""
 		for _, mm := range modes {
@@ -76,6 +82,8 @@ func strtoMeth(s string) Method {
 		return Size
 	case "test":
 		return Test
+	case "sql":
+		return SQL
 	default:
 		return 0
 	}
@@ -88,6 +96,7 @@ const (
 	Unmarshal                                            // msgp.Unmarshaler
 	Size                                                 // msgp.Sizer
 	Test                                                 // generate tests
+	SQL                                                  // sql.Scanner and driver.Valuer
 	invalidmeth                                          // this isn't a method
 	encodetest  = Encode | Decode | Test                 // tests for Encodable and Decodable
 	marshaltest = Marshal | Unmarshal | Test             // tests for Marshaler and Unmarshaler
@@ -101,7 +110,7 @@ func NewPrinter(m Method, out io.Writer, tests io.Writer) *Printer {
 	if m.isset(Test) && tests == nil {
 		panic("cannot print tests with 'nil' tests argument!")
 	}
-	gens := make([]generator, 0, 7)
+	gens := make([]generator, 0, 8)
 	if m.isset(Decode) {
 		gens = append(gens, decode(out))
 	}
@@ -117,6 +126,18 @@ func NewPrinter(m Method, out io.Writer, tests io.Writer) *Printer {
 	if m.isset(Size) {
 		gens = append(gens, sizes(out))
 	}
+	if m.isset(Decode) || m.isset(Unmarshal) {
+		gens = append(gens, pool(out))
+	}
+	if m.isset(Marshal) && m.isset(Unmarshal) {
+		gens = append(gens, extension(out))
+	}
+	if m&(Decode|Encode|Marshal|Unmarshal|Size) != 0 {
+		gens = append(gens, enum(out))
+	}
+	if m.isset(SQL | Marshal | Unmarshal) {
+		gens = append(gens, sqlmethods(out))
+	}
 	if m.isset(marshaltest) {
 		gens = append(gens, mtest(tests))
 	}
@@ -306,7 +327,12 @@ func (p *printer) resizeMap(size string, m *Map) {
 		return
 	}
 	p.printf("\nif %s == nil {", vn)
-	p.printf("\n%s = make(%s, %s)", vn, m.TypeName(), size)
+	if m.Alloc != "" {
+		p.printf("\nif v, ok := %s.Get().(%s); ok {\n%s = v\n} else {", m.Alloc, m.TypeName(), vn)
+		p.printf("\n%s = make(%s, %s)\n}", vn, m.TypeName(), size)
+	} else {
+		p.printf("\n%s = make(%s, %s)", vn, m.TypeName(), size)
+	}
 	p.printf("\n} else if len(%s) > 0 {", vn)
 	p.clearMap(vn)
 	p.closeblock()
@@ -326,6 +352,12 @@ func (p *printer) clearMap(name string) {
 }
 
 func (p *printer) resizeSlice(size string, s *Slice) {
+	if s.Alloc != "" {
+		p.printf("\nif %[1]s == nil || cap(%[1]s) < int(%[2]s) {", s.Varname(), size)
+		p.printf("\nif v, ok := %[4]s.Get().(*%[3]s); ok && cap(*v) >= int(%[2]s) { %[1]s = (*v)[:%[2]s] } else { %[1]s = make(%[3]s, %[2]s) }", s.Varname(), size, s.TypeName(), s.Alloc)
+		p.printf("\n} else { %[1]s = (%[1]s)[:%[2]s] }", s.Varname(), size)
+		return
+	}
 	p.printf("\nif %[2]s == 0 || cap(%[1]s) < int(%[2]s) { %[1]s = make(%[3]s, %[2]s) } else { %[1]s = (%[1]s)[:%[2]s] }", s.Varname(), size, s.TypeName())
 }
 
@@ -372,12 +404,44 @@ func (p *printer) print(format string) {
 func (p *printer) initPtr(pt *Ptr) {
 	if pt.Needsinit() {
 		vname := pt.Varname()
+		if isPooled(pt.Value) {
+			p.printf("\nif %s == nil { %s = Acquire%s(); }", vname, vname, pt.Value.TypeName())
+			return
+		}
 		p.printf("\nif %s == nil { %s = new(%s); }", vname, vname, pt.Value.TypeName())
 	}
 }
 
 func (p *printer) ok() bool { return p.err == nil }
 
+// addressOf returns an expression for
+// the address of the variable 'vname'
+func addressOf(vname string) string {
+	if strings.HasPrefix(vname, "*") {
+		return vname[1:]
+	}
+	return "&" + vname
+}
+
+// appendFieldKey appends the encoded map key of
+// the i'th field of a map-encoded struct to b
+func appendFieldKey(b []byte, s *Struct, i int) []byte {
+	if s.AsIntKeys {
+		return msgp.AppendInt64(b, fieldNum(s.Fields[i]))
+	}
+	return msgp.AppendString(b, s.Fields[i].FieldTag)
+}
+
+// fieldNum returns the integer key of a field
+// in a struct written with integer keys
+func fieldNum(f StructField) int64 {
+	n, err := strconv.ParseInt(f.FieldTag, 10, 64)
+	if err != nil {
+		panic("non-integer key " + strconv.Quote(f.FieldTag) + " in integer-keyed struct")
+	}
+	return n
+}
+
 func tobaseConvert(b *BaseElem) string {
 	return b.ToBase() + "(" + b.Varname() + ")"
 }
@@ -0,0 +1,41 @@
+package gen
+
+import (
+	"io"
+)
+
+func sqlmethods(w io.Writer) *sqlGen {
+	return &sqlGen{p: printer{w: w}}
+}
+
+// sqlGen prints the sql.Scanner and driver.Valuer
+// methods, which store the MessagePack encoding
+// of a type through msgp.SQL.
+type sqlGen struct {
+	passes
+	p printer
+}
+
+func (s *sqlGen) Method() Method { return SQL }
+
+func (s *sqlGen) Apply(dirs []string) error {
+	return nil
+}
+
+func (s *sqlGen) Execute(e Elem) error {
+	if !s.p.ok() {
+		return s.p.err
+	}
+	e = s.applyall(e)
+	if e == nil || !IsPrintable(e) || ExternalName(e.TypeName()) != "" {
+		return nil
+	}
+	name := e.TypeName()
+
+	s.p.comment("Value implements driver.Valuer")
+	s.p.printf("\nfunc (z %s) Value() (driver.Value, error) { return msgp.SQL{Msg: &z}.Value() }\n", name)
+
+	s.p.comment("Scan implements sql.Scanner")
+	s.p.printf("\nfunc (z *%s) Scan(src interface{}) error { return msgp.SQL{Msg: z}.Scan(src) }\n", name)
+	return s.p.err
+}
@@ -28,7 +28,7 @@ type mtestGen struct {
 
 func (m *mtestGen) Execute(p Elem) error {
 	p = m.applyall(p)
-	if p != nil && IsPrintable(p) {
+	if p != nil && IsPrintable(p) && ExternalName(p.TypeName()) == "" {
 		switch p.(type) {
 		case *Struct, *Array, *Slice, *Map:
 			return marshalTestTempl.Execute(m.w, p)
@@ -50,7 +50,7 @@ func etest(w io.Writer) *etestGen {
 
 func (e *etestGen) Execute(p Elem) error {
 	p = e.applyall(p)
-	if p != nil && IsPrintable(p) {
+	if p != nil && IsPrintable(p) && ExternalName(p.TypeName()) == "" {
 		switch p.(type) {
 		case *Struct, *Array, *Slice, *Map:
 			return encodeTestTempl.Execute(e.w, p)
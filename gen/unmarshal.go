@@ -15,8 +15,11 @@ func unmarshal(w io.Writer) *unmarshalGen {
 
 type unmarshalGen struct {
 	passes
-	p        printer
-	hasfield bool
+	p           printer
+	hasfield    bool
+	hasfieldnum bool
+	intern      string // the Interner parameter, if the type is interned
+	ptr         string // the pointer being read into, if any
 }
 
 func (u *unmarshalGen) Method() Method { return Unmarshal }
@@ -29,8 +32,18 @@ func (u *unmarshalGen) needsField() {
 	u.hasfield = true
 }
 
+func (u *unmarshalGen) needsFieldNum() {
+	if u.hasfieldnum {
+		return
+	}
+	u.p.print("\nvar fieldnum int64; _ = fieldnum")
+	u.hasfieldnum = true
+}
+
 func (u *unmarshalGen) Execute(p Elem) error {
 	u.hasfield = false
+	u.hasfieldnum = false
+	u.intern = ""
 	if !u.p.ok() {
 		return u.p.err
 	}
@@ -42,9 +55,20 @@ func (u *unmarshalGen) Execute(p Elem) error {
 		return nil
 	}
 
-	u.p.comment("UnmarshalMsg implements msgp.Unmarshaler")
-
-	u.p.printf("\nfunc (%s %s) UnmarshalMsg(bts []byte) (o []byte, err error) {", p.Varname(), methodReceiver(p))
+	if name := ExternalName(p.TypeName()); name != "" {
+		u.p.comment(fmt.Sprintf("Unmarshal%s reads a %s from 'bts' and returns the remaining bytes", name, p.TypeName()))
+		u.p.printf("\nfunc Unmarshal%s(bts []byte, %s %s) (o []byte, err error) {", name, p.Varname(), methodReceiver(p))
+	} else if st, ok := p.(*Struct); ok && st.Interned {
+		u.intern = "in"
+		u.p.comment("UnmarshalMsg implements msgp.Unmarshaler")
+		u.p.printf("\nfunc (%s %s) UnmarshalMsg(bts []byte) (o []byte, err error) {", p.Varname(), methodReceiver(p))
+		u.p.printf("\nreturn %s.UnmarshalMsgWithInterner(bts, nil)\n}\n", p.Varname())
+		u.p.comment("UnmarshalMsgWithInterner implements msgp.InternUnmarshaler")
+		u.p.printf("\nfunc (%s %s) UnmarshalMsgWithInterner(bts []byte, in *msgp.Interner) (o []byte, err error) {", p.Varname(), methodReceiver(p))
+	} else {
+		u.p.comment("UnmarshalMsg implements msgp.Unmarshaler")
+		u.p.printf("\nfunc (%s %s) UnmarshalMsg(bts []byte) (o []byte, err error) {", p.Varname(), methodReceiver(p))
+	}
 	next(u, p)
 	u.p.print("\no = bts")
 	u.p.nakedReturn()
@@ -67,6 +91,8 @@ func (u *unmarshalGen) gStruct(s *Struct) {
 	}
 	if s.AsTuple {
 		u.tuple(s)
+	} else if s.AsIntKeys {
+		u.intkeys(s)
 	} else {
 		u.mapstruct(s)
 	}
@@ -93,6 +119,9 @@ func (u *unmarshalGen) mapstruct(s *Struct) {
 	sz := randIdent()
 	u.p.declare(sz, u32)
 	u.assignAndCheck(sz, mapHeader)
+	if s.Unknown != nil {
+		u.p.printf("\n%[1]s = %[1]s[:0]", s.UnknownVarname())
+	}
 
 	u.p.printf("\nfor %s > 0 {", sz)
 	u.p.printf("\n%s--; field, bts, err = msgp.ReadMapKeyZC(bts)", sz)
@@ -112,7 +141,14 @@ func (u *unmarshalGen) mapstruct(s *Struct) {
 			if vType != vElemType {
 				embeddedCode += fmt.Sprintf("\nif %s == nil { %s = new(%s); }", vname, vname, vElemType)
 			}
-			embeddedCode += "\n_,err=" + vname + ".UnmarshalMsg(_b)"
+			if u.intern != "" {
+				if vType == vElemType {
+					vname = "&" + vname
+				}
+				embeddedCode += "\n_,err=" + u.intern + ".Unmarshal(_b, " + vname + ")"
+			} else {
+				embeddedCode += "\n_,err=" + vname + ".UnmarshalMsg(_b)"
+			}
 			embeddedCode += errcheck
 		}
 	}
@@ -121,6 +157,9 @@ func (u *unmarshalGen) mapstruct(s *Struct) {
 			errcheck +
 			embeddedCode
 		u.p.print(embeddedCode)
+	} else if s.Unknown != nil {
+		u.p.printf("\ndefault:\n%[1]s, bts, err = msgp.ReadUnknownFieldBytes(%[1]s, field, bts)", s.UnknownVarname())
+		u.p.print(errcheck)
 	} else {
 		u.p.print("\ndefault:\nbts, err = msgp.Skip(bts)")
 		u.p.print(errcheck)
@@ -128,11 +167,82 @@ func (u *unmarshalGen) mapstruct(s *Struct) {
 	u.p.print("\n}\n}") // close switch and for loop
 }
 
+func (u *unmarshalGen) intkeys(s *Struct) {
+	u.needsFieldNum()
+	sz := randIdent()
+	u.p.declare(sz, u32)
+	u.assignAndCheck(sz, mapHeader)
+
+	u.p.printf("\nfor %s > 0 {", sz)
+	u.p.printf("\n%s--; fieldnum, bts, err = msgp.ReadInt64Bytes(bts)", sz)
+	u.p.print(errcheck)
+	u.p.print("\nswitch fieldnum {")
+	for i := range s.Fields {
+		if !u.p.ok() {
+			return
+		}
+		u.p.printf("\ncase %d:", fieldNum(s.Fields[i]))
+		next(u, s.Fields[i].FieldElem)
+	}
+	u.p.print("\ndefault:\nbts, err = msgp.Skip(bts)")
+	u.p.print(errcheck)
+	u.p.print("\n}\n}") // close switch and for loop
+}
+
+// columnar reads a slice of structs written as
+// a map of each field to an array of its values.
+// The first array sets the number of rows, and
+// the others must have the same length.
+func (u *unmarshalGen) columnar(s *Slice) {
+	st := s.Els.(*Struct)
+	u.needsField()
+	sz, rows, n := randIdent(), randIdent(), randIdent()
+	u.p.declare(sz, u32)
+	u.assignAndCheck(sz, mapHeader)
+	u.p.printf("\n%s := -1", rows)
+	u.p.printf("\nfor %s > 0 {", sz)
+	u.p.printf("\n%s--; field, bts, err = msgp.ReadMapKeyZC(bts)", sz)
+	u.p.print(errcheck)
+	u.p.declare(n, u32)
+	u.assignAndCheck(n, arrayHeader)
+	u.p.printf("\nif %s < 0 {", rows)
+	u.p.resizeSlice(n, s)
+	u.p.printf("\n%s = int(%s)\n} else if int(%s) != %s {", rows, n, n, rows)
+	u.p.printf("\nerr = msgp.ArrayError{Wanted: uint32(%s), Got: %s}\nreturn\n}", rows, n)
+	u.p.print("\nswitch msgp.UnsafeString(field) {")
+	for i := range st.Fields {
+		if !u.p.ok() {
+			return
+		}
+		u.p.printf("\ncase \"%s\":", st.Fields[i].FieldTag)
+		u.p.rangeBlock(s.Index, s.Varname(), u, st.Fields[i].FieldElem)
+	}
+	u.p.printf("\ndefault:\nfor ; %[1]s > 0; %[1]s-- {\nbts, err = msgp.Skip(bts)", n)
+	u.p.print(errcheck)
+	u.p.print("\n}\n}\n}") // close skip loop, switch and for loop
+	u.p.printf("\nif %s < 0 {\n%s = (%s)[:0]\n}", rows, s.Varname(), s.Varname())
+}
+
 func (u *unmarshalGen) gBase(b *BaseElem) {
 	if !u.p.ok() {
 		return
 	}
 
+	if b.AllowNil && b.Value == Bytes {
+		u.readNil(b.Varname())
+		defer u.p.closeblock()
+	}
+
+	// enums are read by name or value
+	if b.Enum != nil {
+		tmp := randIdent()
+		u.p.printf("\n{\nvar %s int64", tmp)
+		u.p.printf("\n%s, bts, err = msgp.ReadEnumBytes(bts, %s)", tmp, enumVar(b))
+		u.p.print(errcheck)
+		u.p.printf("\n%s = %s(%s)\n}", b.Varname(), b.TypeName(), tmp)
+		return
+	}
+
 	refname := b.Varname() // assigned to
 	lowered := b.Varname() // passed as argument
 	if b.Convert {
@@ -144,12 +254,43 @@ func (u *unmarshalGen) gBase(b *BaseElem) {
 
 	switch b.Value {
 	case Bytes:
-		u.p.printf("\n%s, bts, err = msgp.ReadBytesBytes(bts, %s)", refname, lowered)
+		if b.ZeroCopy {
+			u.p.printf("\n%s, bts, err = msgp.ReadBytesZC(bts)", refname)
+		} else {
+			u.p.printf("\n%s, bts, err = msgp.ReadBytesBytes(bts, %s)", refname, lowered)
+		}
+	case String:
+		if b.ZeroCopy {
+			zc := randIdent()
+			u.p.printf("\nvar %s []byte", zc)
+			u.p.printf("\n%s, bts, err = msgp.ReadStringZC(bts)", zc)
+			u.p.printf("\n%s = msgp.UnsafeString(%s)", refname, zc)
+		} else if u.intern != "" {
+			u.p.printf("\n%s, bts, err = %s.ReadStringBytes(bts)", refname, u.intern)
+		} else {
+			u.p.printf("\n%s, bts, err = msgp.ReadStringBytes(bts)", refname)
+		}
 	case Ext:
 		u.p.printf("\nbts, err = msgp.ReadExtensionBytes(bts, %s)", lowered)
+	case Time:
+		if f := b.timeFormat(); f != "" {
+			u.p.printf("\n%s, bts, err = msgp.ReadTimeAsBytes(bts, %s)", refname, f)
+		} else {
+			u.p.printf("\n%s, bts, err = msgp.ReadTimeBytes(bts)", refname)
+		}
 	case IDENT:
 		if b.TypeName() == "msgp.Any" {
 			u.p.printf("\n%s, bts, err = msgp.UnmarshalAny(bts)", lowered)
+		} else if b.Marshaler != MsgMarshaler {
+			u.p.printf("\nbts, err = msgp.Read%sUnmarshalerBytes(bts, %s)", b.Marshaler, addressOf(lowered))
+		} else if b.External {
+			u.p.printf("\nbts, err = Unmarshal%s(bts, %s)", ExternalName(b.TypeName()), addressOf(lowered))
+		} else if u.intern != "" {
+			// identities under a pointer are named after it
+			if lowered != u.ptr {
+				lowered = addressOf(lowered)
+			}
+			u.p.printf("\nbts, err = %s.Unmarshal(bts, %s)", u.intern, lowered)
 		} else {
 			u.p.printf("\nbts, err = %s.UnmarshalMsg(bts)", lowered)
 		}
@@ -158,6 +299,11 @@ func (u *unmarshalGen) gBase(b *BaseElem) {
 	}
 	u.p.print(errcheck)
 
+	// an empty []byte must not be read as nil
+	if b.AllowNil && b.Value == Bytes {
+		u.p.printf("\nif %[1]s == nil { %[1]s = []byte{} }", refname)
+	}
+
 	if b.Convert {
 		// close 'tmp' block
 		if b.ShimMode == Cast {
@@ -194,6 +340,19 @@ func (u *unmarshalGen) gSlice(s *Slice) {
 	if !u.p.ok() {
 		return
 	}
+	if s.AllowNil {
+		u.readNil(s.Varname())
+		defer u.p.closeblock()
+	}
+	if s.Packed {
+		u.p.printf("\n%[1]s, bts, err = msgp.Read%[2]sBytes(bts, %[1]s)", s.Varname(), s.packedName())
+		u.p.print(errcheck)
+		return
+	}
+	if s.Columnar {
+		u.columnar(s)
+		return
+	}
 	sz := randIdent()
 	u.p.declare(sz, u32)
 	u.assignAndCheck(sz, arrayHeader)
@@ -205,6 +364,10 @@ func (u *unmarshalGen) gMap(m *Map) {
 	if !u.p.ok() {
 		return
 	}
+	if m.AllowNil {
+		u.readNil(m.Varname())
+		defer u.p.closeblock()
+	}
 	sz := randIdent()
 	u.p.declare(sz, u32)
 	u.assignAndCheck(sz, mapHeader)
@@ -215,15 +378,30 @@ func (u *unmarshalGen) gMap(m *Map) {
 	// loop and get key,value
 	u.p.printf("\nfor %s > 0 {", sz)
 	u.p.printf("\nvar %s string; var %s %s; %s--", m.Keyidx, m.Validx, m.Value.TypeName(), sz)
-	u.assignAndCheck(m.Keyidx, stringTyp)
+	if u.intern != "" {
+		u.p.printf("\n%s, bts, err = %s.ReadStringBytes(bts)", m.Keyidx, u.intern)
+		u.p.print(errcheck)
+	} else {
+		u.assignAndCheck(m.Keyidx, stringTyp)
+	}
 	next(u, m.Value)
 	u.p.mapAssign(m)
 	u.p.closeblock()
 }
 
+// readNil opens a block that sets 'vname'
+// to nil if the next object is nil, or
+// otherwise continues in the else branch
+func (u *unmarshalGen) readNil(vname string) {
+	u.p.printf("\nif msgp.IsNil(bts) { bts, err = msgp.ReadNilBytes(bts); if err != nil { return }; %s = nil; } else { ", vname)
+}
+
 func (u *unmarshalGen) gPtr(p *Ptr) {
-	u.p.printf("\nif msgp.IsNil(bts) { bts, err = msgp.ReadNilBytes(bts); if err != nil { return }; %s = nil; } else { ", p.Varname())
+	u.readNil(p.Varname())
 	u.p.initPtr(p)
+	ptr := u.ptr
+	u.ptr = p.Varname()
 	next(u, p.Value)
+	u.ptr = ptr
 	u.p.closeblock()
 }
@@ -17,6 +17,7 @@
 //  -io = satisfy the `msgp.Decodable` and `msgp.Encodable` interfaces (default is true)
 //  -marshal = satisfy the `msgp.Marshaler` and `msgp.Unmarshaler` interfaces (default is true)
 //  -tests = generate tests and benchmarks (default is true)
+//  -sql = satisfy the `sql.Scanner` and `driver.Valuer` interfaces (default is false; requires -marshal)
 //
 // For more information, please read README.md, and the wiki at github.com/tinylib/msgp
 //
@@ -45,6 +46,7 @@ var (
 	encode     = flag.Bool("io", true, "create Encode and Decode methods")
 	marshal    = flag.Bool("marshal", true, "create Marshal and Unmarshal methods")
 	tests      = flag.Bool("tests", true, "create tests and benchmarks")
+	sql        = flag.Bool("sql", false, "create Scan and Value methods (requires -marshal)")
 	unexported = flag.Bool("unexported", false, "also process unexported types")
 )
 
@@ -74,6 +76,13 @@ func main() {
 	if *tests {
 		mode |= gen.Test
 	}
+	if *sql {
+		if !*marshal {
+			fmt.Println(chalk.Red.Color("-sql requires -marshal"))
+			os.Exit(1)
+		}
+		mode |= gen.SQL
+	}
 
 	if mode&^gen.Test == 0 {
 		fmt.Println(chalk.Red.Color("No methods to generate; -io=false && -marshal=false"))
@@ -0,0 +1,72 @@
+package msgp
+
+import (
+	"sync/atomic"
+)
+
+// Allocator supplies the values returned by the
+// Acquire functions that the generator prints for
+// types listed in a //msgp:pool directive, and takes
+// them back when they are released. *sync.Pool
+// implements Allocator, so does a caller-supplied
+// arena or free list.
+//
+// Each slice and map field of a pooled struct has
+// an Allocator too, from which the generated decoders
+// take a slice (as a pointer to it) or a map when the
+// field is nil or too small, and to which Release
+// returns it after it has been truncated or cleared.
+type Allocator interface {
+	// Get returns a value previously passed to
+	// Put. It may return nil (or a value of
+	// another type), in which case a new value
+	// is allocated.
+	Get() interface{}
+
+	// Put makes a released value available
+	// to subsequent calls to Get.
+	Put(x interface{})
+}
+
+// AllocatorVar holds an Allocator that can be
+// replaced while it is in use. It implements
+// Allocator by calling the one it holds, or by
+// allocating every value if that is nil.
+type AllocatorVar struct {
+	v atomic.Value // holds an allocatorBox
+}
+
+// atomic.Value must always hold the same type
+type allocatorBox struct{ a Allocator }
+
+// NewAllocatorVar returns an AllocatorVar
+// that holds 'a'.
+func NewAllocatorVar(a Allocator) *AllocatorVar {
+	v := new(AllocatorVar)
+	v.Store(a)
+	return v
+}
+
+// Store replaces the Allocator held by v.
+func (v *AllocatorVar) Store(a Allocator) { v.v.Store(allocatorBox{a}) }
+
+// Load returns the Allocator held by v.
+func (v *AllocatorVar) Load() Allocator {
+	b, _ := v.v.Load().(allocatorBox)
+	return b.a
+}
+
+// Get implements Allocator.
+func (v *AllocatorVar) Get() interface{} {
+	if a := v.Load(); a != nil {
+		return a.Get()
+	}
+	return nil
+}
+
+// Put implements Allocator.
+func (v *AllocatorVar) Put(x interface{}) {
+	if a := v.Load(); a != nil {
+		a.Put(x)
+	}
+}
@@ -0,0 +1,48 @@
+package msgp
+
+import (
+	"sync"
+	"testing"
+)
+
+type countAlloc struct{ puts int }
+
+func (c *countAlloc) Get() interface{}  { return c }
+func (c *countAlloc) Put(x interface{}) { c.puts++ }
+
+func TestAllocatorVar(t *testing.T) {
+	var v AllocatorVar
+	if v.Load() != nil || v.Get() != nil {
+		t.Fatal("the zero AllocatorVar holds an Allocator")
+	}
+	v.Put(1) // discarded
+
+	c := &countAlloc{}
+	v.Store(c)
+	if v.Get() != c {
+		t.Error("Get did not call the Allocator")
+	}
+	v.Put(1)
+	if c.puts != 1 {
+		t.Errorf("%d puts; want 1", c.puts)
+	}
+
+	// Allocators of other types can be stored
+	// while the AllocatorVar is in use
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				v.Put(v.Get())
+			}
+		}()
+	}
+	v.Store(new(sync.Pool))
+	v.Store(nil)
+	wg.Wait()
+	if v.Load() != nil {
+		t.Error("Store(nil) did not clear the Allocator")
+	}
+}
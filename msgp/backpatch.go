@@ -0,0 +1,96 @@
+package msgp
+
+import (
+	"errors"
+)
+
+// The functions in this file write array and map
+// headers before their size is known, and patch
+// it in afterwards. The headers always take the
+// 5-byte array32 or map32 form, which is valid
+// MessagePack for any size.
+
+// ErrNotBegun is returned by (*Writer).End when
+// no container is open.
+var ErrNotBegun = errors.New("msgp: End called without BeginArray or BeginMap")
+
+// AppendArrayHeaderPlaceholder appends an array header
+// whose size is set later with PatchArrayHeader, and
+// returns the offset of the header in the slice.
+func AppendArrayHeaderPlaceholder(b []byte) ([]byte, int) {
+	o, n := ensure(b, 5)
+	prefixu32(o[n:], marray32, 0)
+	return o, n
+}
+
+// PatchArrayHeader sets the size of the array whose header
+// was appended by AppendArrayHeaderPlaceholder at 'at'.
+func PatchArrayHeader(b []byte, at int, sz uint32) {
+	prefixu32(b[at:at+5], marray32, sz)
+}
+
+// AppendMapHeaderPlaceholder appends a map header
+// whose size is set later with PatchMapHeader, and
+// returns the offset of the header in the slice.
+func AppendMapHeaderPlaceholder(b []byte) ([]byte, int) {
+	o, n := ensure(b, 5)
+	prefixu32(o[n:], mmap32, 0)
+	return o, n
+}
+
+// PatchMapHeader sets the size of the map whose header
+// was appended by AppendMapHeaderPlaceholder at 'at'.
+func PatchMapHeader(b []byte, at int, sz uint32) {
+	prefixu32(b[at:at+5], mmap32, sz)
+}
+
+// BeginArray writes the header of an array whose size
+// is set when it is ended with End. Arrays and maps can
+// be nested, and are ended in the reverse order. The
+// header is patched in the Writer's buffer, so until the
+// outermost container is ended, what is written is held
+// in memory rather than written to the underlying writer.
+func (mw *Writer) BeginArray() error {
+	return mw.begin(marray32)
+}
+
+// BeginMap is like BeginArray, but writes
+// a map. Its size is the number of pairs.
+func (mw *Writer) BeginMap() error {
+	return mw.begin(mmap32)
+}
+
+func (mw *Writer) begin(pre byte) error {
+	o, err := mw.require(5)
+	if err != nil {
+		return err
+	}
+	prefixu32(mw.buf[o:], pre, 0)
+	mw.open = append(mw.open, len(mw.held)+o)
+	return nil
+}
+
+// End sets the size of the innermost open array or
+// map to 'sz'. When the outermost one is ended, what
+// has been held back is written to the underlying
+// writer. It returns ErrNotBegun if no array or
+// map is open.
+func (mw *Writer) End(sz uint32) error {
+	n := len(mw.open)
+	if n == 0 {
+		return ErrNotBegun
+	}
+	at := mw.open[n-1]
+	mw.open = mw.open[:n-1]
+	hdr := mw.held
+	if at >= len(mw.held) {
+		hdr, at = mw.buf, at-len(mw.held)
+	}
+	big.PutUint32(hdr[at+1:at+5], sz)
+	if n > 1 || len(mw.held) == 0 {
+		return nil
+	}
+	_, err := mw.w.Write(mw.held)
+	mw.held = mw.held[:0]
+	return err
+}
@@ -0,0 +1,82 @@
+package msgp
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBackpatchBytes(t *testing.T) {
+	b, arr := AppendArrayHeaderPlaceholder(AppendString(nil, "prefix"))
+	b = AppendInt(b, 1)
+	b, m := AppendMapHeaderPlaceholder(b)
+	b = AppendString(b, "key")
+	b = AppendBool(b, true)
+	PatchMapHeader(b, m, 1)
+	PatchArrayHeader(b, arr, 2)
+
+	s, b, err := ReadStringBytes(b)
+	if err != nil || s != "prefix" {
+		t.Fatalf("got %q, %v", s, err)
+	}
+	v, rest, err := ReadIntfBytes(b)
+	if err != nil || len(rest) != 0 {
+		t.Fatalf("%d bytes left, %v", len(rest), err)
+	}
+	want := []interface{}{int64(1), map[string]interface{}{"key": true}}
+	if !reflect.DeepEqual(v, want) {
+		t.Errorf("got %v; want %v", v, want)
+	}
+}
+
+func TestWriterBeginEnd(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriterSize(&buf, 18)
+	long := strings.Repeat("x", 100)
+
+	if err := w.BeginArray(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		w.WriteInt(i)
+	}
+	w.BeginMap()
+	w.WriteString("big")
+	w.WriteString(long)
+	w.WriteString("bin")
+	w.Write(AppendBytes(nil, []byte(long)))
+	w.Flush()
+	if buf.Len() != 0 {
+		t.Fatalf("%d bytes were written while open", buf.Len())
+	}
+	if err := w.End(2); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.End(11); err != nil {
+		t.Fatal(err)
+	}
+	w.WriteInt(42)
+	w.Flush()
+
+	r := NewReader(&buf)
+	v, err := r.ReadIntf()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []interface{}{}
+	for i := 0; i < 10; i++ {
+		want = append(want, int64(i))
+	}
+	want = append(want, map[string]interface{}{"big": long, "bin": []byte(long)})
+	if !reflect.DeepEqual(v, want) {
+		t.Errorf("got %v; want %v", v, want)
+	}
+	if i, err := r.ReadInt(); err != nil || i != 42 {
+		t.Errorf("got %d, %v after the array", i, err)
+	}
+
+	if err = w.End(0); err != ErrNotBegun {
+		t.Errorf("End without Begin returned %v", err)
+	}
+}
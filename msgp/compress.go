@@ -0,0 +1,478 @@
+package msgp
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Compressed streams are framed streams (see FrameWriter)
+// where the first byte of each frame identifies the codec
+// that compressed the rest of it, or is CodecNone if it
+// wasn't compressed. The rest is a batch of one or more
+// messages, one after another. Since each frame is
+// compressed on its own, frames can still be skipped
+// without decompressing them.
+
+// Codec is a compression format. The
+// io.WriteCloser returned by NewWriter is
+// closed after the whole frame is written.
+type Codec interface {
+	NewWriter(w io.Writer) (io.WriteCloser, error)
+	NewReader(r io.Reader) (io.ReadCloser, error)
+}
+
+const (
+	CodecNone  uint8 = 0 // not compressed
+	CodecFlate uint8 = 1 // compress/flate
+	CodecGzip  uint8 = 2 // compress/gzip
+
+	// DefaultCompressThreshold is the size of the smallest
+	// frame compressed by a new CompressedWriter.
+	DefaultCompressThreshold = 256
+)
+
+var codecReg = map[uint8]Codec{
+	CodecFlate: flateCodec{},
+	CodecGzip:  gzipCodec{},
+}
+
+// RegisterCodec registers the Codec 'c'
+// under the ID 'id', which is written
+// at the start of each frame it compresses.
+//
+// RegisterCodec will panic if you call it multiple
+// times with the same 'id' argument, or if you use a
+// reserved ID (CodecNone, CodecFlate or CodecGzip).
+func RegisterCodec(id uint8, c Codec) {
+	switch id {
+	case CodecNone, CodecFlate, CodecGzip:
+		panic(fmt.Sprint("msgp: forbidden codec ID: ", id))
+	}
+	if _, ok := codecReg[id]; ok {
+		panic(fmt.Sprint("msgp: RegisterCodec() called with id ", id, " more than once"))
+	}
+	codecReg[id] = c
+}
+
+// CodecError is returned when a frame
+// uses a codec that isn't registered.
+type CodecError struct {
+	Codec uint8
+}
+
+// Error implements the error interface
+func (c CodecError) Error() string {
+	return fmt.Sprintf("msgp: unknown compression codec %d", c.Codec)
+}
+
+// Resumable is always 'true' for CodecErrors
+func (c CodecError) Resumable() bool { return true }
+
+// compress appends the codec ID and then 'msg' to 'b',
+// compressing 'msg' if it is at least 'threshold' bytes
+// long and compressing it makes it smaller.
+func compress(b []byte, msg []byte, codec uint8, threshold int) ([]byte, error) {
+	if codec != CodecNone && len(msg) >= threshold {
+		c, ok := codecReg[codec]
+		if !ok {
+			return b, CodecError{Codec: codec}
+		}
+		buf := bytes.NewBuffer(append(b, codec))
+		w, err := c.NewWriter(buf)
+		if err != nil {
+			return b, err
+		}
+		if _, err = w.Write(msg); err != nil {
+			w.Close()
+			return b, err
+		}
+		if err = w.Close(); err != nil {
+			return b, err
+		}
+		o := buf.Bytes()
+		if len(o)-len(b) <= len(msg) {
+			return o, nil
+		}
+		b = o[:len(b)]
+	}
+	return append(append(b, CodecNone), msg...), nil
+}
+
+// decompress returns the message in the frame 'p',
+// decompressing it into 'scratch' if it is compressed.
+// Messages that decompress to more than 'max' bytes
+// return a FrameSizeError.
+func decompress(p []byte, scratch []byte, max int) ([]byte, error) {
+	if len(p) == 0 {
+		return nil, ErrShortBytes
+	}
+	if p[0] == CodecNone {
+		return p[1:], nil
+	}
+	c, ok := codecReg[p[0]]
+	if !ok {
+		return nil, CodecError{Codec: p[0]}
+	}
+	r, err := c.NewReader(bytes.NewReader(p[1:]))
+	if err != nil {
+		return nil, err
+	}
+	buf := bytes.NewBuffer(scratch[:0])
+	n, err := buf.ReadFrom(io.LimitReader(r, int64(max)+1))
+	r.Close()
+	if err != nil {
+		return nil, noEOF(err)
+	}
+	if n > int64(max) {
+		return nil, FrameSizeError{Size: int(n), Max: max}
+	}
+	return buf.Bytes(), nil
+}
+
+// CompressedWriter writes messages to an io.Writer
+// in frames compressed with a Codec. By default, each
+// message has its own frame; since small messages
+// hardly compress on their own, they can be batched
+// instead by setting BatchMessages or BatchBytes.
+type CompressedWriter struct {
+	// Threshold is the size of the smallest frame
+	// that is compressed. Smaller frames, and
+	// those that don't get smaller when compressed,
+	// are written as they are.
+	Threshold int
+
+	// Checksum and MaxSize are
+	// the FrameWriter options.
+	Checksum bool
+	MaxSize  int
+
+	// BatchMessages and BatchBytes, if either is
+	// set, make the writer hold messages back until
+	// it has that many messages or bytes of them,
+	// and then write them all in one frame. Flush
+	// writes the messages held back so far.
+	BatchMessages int
+	BatchBytes    int
+
+	codec uint8
+	fw    FrameWriter
+	enc   encoder
+	msg   []byte
+	body  []byte
+	batch []byte // the messages held back
+	count int    // the number of messages in batch
+}
+
+// NewCompressedWriter returns a CompressedWriter that
+// writes to 'w' and compresses with the codec 'codec'.
+func NewCompressedWriter(w io.Writer, codec uint8) *CompressedWriter {
+	return &CompressedWriter{
+		Threshold: DefaultCompressThreshold,
+		codec:     codec,
+		fw:        FrameWriter{w: w},
+	}
+}
+
+// Reset changes the io.Writer that frames are
+// written to, and discards any messages that
+// have been held back.
+func (cw *CompressedWriter) Reset(w io.Writer) {
+	cw.fw.Reset(w)
+	cw.batch = cw.batch[:0]
+	cw.count = 0
+}
+
+// Encode writes 'e' like WriteFrame.
+func (cw *CompressedWriter) Encode(e Encodable) error {
+	msg, err := cw.enc.encode(cw.msg[:0], e)
+	if err != nil {
+		return err
+	}
+	cw.msg = msg
+	return cw.WriteFrame(msg)
+}
+
+// WriteFrame writes 'msg', which should be one
+// message, as one frame, or adds it to the batch
+// if BatchMessages or BatchBytes is set.
+func (cw *CompressedWriter) WriteFrame(msg []byte) error {
+	if cw.BatchMessages <= 0 && cw.BatchBytes <= 0 {
+		return cw.writeFrame(msg)
+	}
+	// a message that would make the batch
+	// too large for a frame starts a new one
+	if cw.count > 0 && len(cw.batch)+len(msg) > maxFrameSize(cw.MaxSize)-1 {
+		if err := cw.Flush(); err != nil {
+			return err
+		}
+	}
+	cw.batch = append(cw.batch, msg...)
+	cw.count++
+	if (cw.BatchMessages > 0 && cw.count >= cw.BatchMessages) ||
+		(cw.BatchBytes > 0 && len(cw.batch) >= cw.BatchBytes) {
+		return cw.Flush()
+	}
+	return nil
+}
+
+// Flush writes the messages that have been
+// held back, if any, as one frame.
+func (cw *CompressedWriter) Flush() error {
+	if cw.count == 0 {
+		return nil
+	}
+	err := cw.writeFrame(cw.batch)
+	cw.batch = cw.batch[:0]
+	cw.count = 0
+	return err
+}
+
+// writeFrame compresses 'msgs' into one frame
+func (cw *CompressedWriter) writeFrame(msgs []byte) error {
+	body, err := compress(cw.body[:0], msgs, cw.codec, cw.Threshold)
+	if err != nil {
+		return err
+	}
+	cw.body = body
+	cw.fw.Checksum = cw.Checksum
+	cw.fw.MaxSize = cw.MaxSize
+	return cw.fw.WriteFrame(body)
+}
+
+// CompressedReader reads messages written
+// by a CompressedWriter from an io.Reader.
+type CompressedReader struct {
+	// MaxSize is the largest frame or decompressed
+	// message that can be read. If it is zero,
+	// DefaultMaxFrameSize is used.
+	MaxSize int
+
+	fr   FrameReader
+	dec  decoder
+	buf  []byte
+	rest []byte // the messages left in the current frame
+}
+
+// NewCompressedReader returns a
+// CompressedReader that reads from 'r'.
+func NewCompressedReader(r io.Reader) *CompressedReader {
+	return &CompressedReader{fr: FrameReader{r: r}}
+}
+
+// Reset changes the io.Reader that
+// frames are read from.
+func (cr *CompressedReader) Reset(r io.Reader) {
+	cr.fr.Reset(r)
+	cr.rest = nil
+}
+
+// NextFrame returns the next message, which is only
+// valid until the next call to a method of the
+// CompressedReader. Frames holding a batch of
+// messages are decompressed once, and their messages
+// returned one by one. It returns the same errors as
+// FrameReader.NextFrame, and a CodecError for an
+// unknown codec.
+func (cr *CompressedReader) NextFrame() ([]byte, error) {
+	if len(cr.rest) == 0 {
+		cr.fr.MaxSize = cr.MaxSize
+		p, err := cr.fr.NextFrame()
+		if err != nil {
+			return nil, err
+		}
+		msgs, err := decompress(p, cr.buf, maxFrameSize(cr.MaxSize))
+		if err != nil {
+			return nil, err
+		}
+		if p[0] != CodecNone {
+			cr.buf = msgs
+		}
+		if len(msgs) == 0 {
+			return msgs, nil
+		}
+		cr.rest = msgs
+	}
+	o, err := Skip(cr.rest)
+	if err != nil {
+		// the rest of the frame is unreadable
+		cr.rest = nil
+		return nil, err
+	}
+	msg := cr.rest[:len(cr.rest)-len(o)]
+	cr.rest = o
+	return msg, nil
+}
+
+// ReadFrame reads the next frame into 'd'. Values
+// that implement Unmarshaler are unmarshaled from
+// the message directly.
+func (cr *CompressedReader) ReadFrame(d Decodable) error {
+	msg, err := cr.NextFrame()
+	if err != nil {
+		return err
+	}
+	return cr.dec.decode(msg, d)
+}
+
+// SkipFrame skips the next message. Since a frame
+// may hold a batch of messages, it is decompressed
+// like it is by NextFrame (see SkipBatch).
+func (cr *CompressedReader) SkipFrame() error {
+	_, err := cr.NextFrame()
+	return err
+}
+
+// SkipBatch skips the messages left in the
+// current frame, if any, or otherwise the
+// next frame without decompressing it.
+func (cr *CompressedReader) SkipBatch() error {
+	if len(cr.rest) > 0 {
+		cr.rest = nil
+		return nil
+	}
+	return cr.fr.SkipFrame()
+}
+
+// AppendCompressed appends 'msg', which may be one
+// message or several one after another, to 'b' as a
+// frame compressed with the codec 'codec' if it is at
+// least 'threshold' bytes long, and with a checksum
+// if 'checksum' is set. The frame can be read with
+// ReadCompressedBytes or a CompressedReader.
+func AppendCompressed(b []byte, msg []byte, codec uint8, threshold int, checksum bool) ([]byte, error) {
+	hdr := 4
+	if checksum {
+		hdr = 8
+	}
+	o, err := compress(append(b, blankHeader[:hdr]...), msg, codec, threshold)
+	if err != nil {
+		return b, err
+	}
+	sz := len(o) - len(b) - hdr
+	if sz > DefaultMaxFrameSize {
+		return b, FrameSizeError{Size: sz, Max: DefaultMaxFrameSize}
+	}
+	n := uint32(sz)
+	if checksum {
+		n |= frameChecksumBit
+	}
+	binary.BigEndian.PutUint32(o[len(b):], n)
+	if checksum {
+		binary.BigEndian.PutUint32(o[len(b)+4:], frameChecksum(o[len(b):], o[len(b)+hdr:]))
+	}
+	return o, nil
+}
+
+// ReadCompressedBytes reads a frame written by
+// AppendCompressed or a CompressedWriter from 'b'
+// and returns its messages, one after another,
+// and the remaining bytes.
+// Compressed messages are decompressed into 'scratch'
+// if it is large enough; others alias 'b'.
+func ReadCompressedBytes(b []byte, scratch []byte) (msg []byte, o []byte, err error) {
+	p, o, err := readFrameBytes(b)
+	if err != nil {
+		return nil, b, err
+	}
+	msg, err = decompress(p, scratch, DefaultMaxFrameSize)
+	if err != nil {
+		return nil, b, err
+	}
+	return msg, o, nil
+}
+
+// the built-in codecs reuse their
+// compressors and decompressors,
+// which are expensive to allocate
+
+var (
+	flateWriters sync.Pool
+	flateReaders sync.Pool
+	gzipWriters  sync.Pool
+	gzipReaders  sync.Pool
+)
+
+type flateCodec struct{}
+
+type flateWriter struct{ *flate.Writer }
+
+func (w flateWriter) Close() error {
+	err := w.Writer.Close()
+	flateWriters.Put(w.Writer)
+	return err
+}
+
+type flateReader struct{ io.ReadCloser }
+
+func (r flateReader) Close() error {
+	err := r.ReadCloser.Close()
+	flateReaders.Put(r.ReadCloser)
+	return err
+}
+
+func (flateCodec) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	if fw, ok := flateWriters.Get().(*flate.Writer); ok {
+		fw.Reset(w)
+		return flateWriter{fw}, nil
+	}
+	fw, err := flate.NewWriter(w, flate.DefaultCompression)
+	if err != nil {
+		return nil, err
+	}
+	return flateWriter{fw}, nil
+}
+
+func (flateCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	if fr, ok := flateReaders.Get().(io.ReadCloser); ok {
+		if err := fr.(flate.Resetter).Reset(r, nil); err != nil {
+			return nil, err
+		}
+		return flateReader{fr}, nil
+	}
+	return flateReader{flate.NewReader(r)}, nil
+}
+
+type gzipCodec struct{}
+
+type gzipWriter struct{ *gzip.Writer }
+
+func (w gzipWriter) Close() error {
+	err := w.Writer.Close()
+	gzipWriters.Put(w.Writer)
+	return err
+}
+
+type gzipReader struct{ *gzip.Reader }
+
+func (r gzipReader) Close() error {
+	err := r.Reader.Close()
+	gzipReaders.Put(r.Reader)
+	return err
+}
+
+func (gzipCodec) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	if gw, ok := gzipWriters.Get().(*gzip.Writer); ok {
+		gw.Reset(w)
+		return gzipWriter{gw}, nil
+	}
+	return gzipWriter{gzip.NewWriter(w)}, nil
+}
+
+func (gzipCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	if gr, ok := gzipReaders.Get().(*gzip.Reader); ok {
+		if err := gr.Reset(r); err != nil {
+			return nil, err
+		}
+		return gzipReader{gr}, nil
+	}
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return gzipReader{gr}, nil
+}
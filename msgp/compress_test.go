@@ -0,0 +1,266 @@
+package msgp
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+const codecIdentity = 100
+
+// identityCodec doesn't compress,
+// but prefixes its output with "id"
+type identityCodec struct{}
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+func (identityCodec) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	_, err := io.WriteString(w, "id")
+	return nopWriteCloser{w}, err
+}
+
+func (identityCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	var prefix [2]byte
+	if _, err := io.ReadFull(r, prefix[:]); err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(r), nil
+}
+
+func init() {
+	RegisterCodec(codecIdentity, identityCodec{})
+}
+
+func TestCompressed(t *testing.T) {
+	long := frameString(strings.Repeat("compressible ", 100))
+	for _, codec := range []uint8{CodecNone, CodecFlate, CodecGzip} {
+		var buf bytes.Buffer
+		cw := NewCompressedWriter(&buf, codec)
+		cw.Checksum = true
+		if err := cw.Encode(long); err != nil {
+			t.Fatal(err)
+		}
+		if err := cw.Encode(frameString("short")); err != nil {
+			t.Fatal(err)
+		}
+		if err := cw.Encode(long); err != nil {
+			t.Fatal(err)
+		}
+		if codec != CodecNone && buf.Len() > len(long)+100 {
+			t.Errorf("codec %d: %d bytes written", codec, buf.Len())
+		}
+
+		// the codec is the first byte of each frame
+		fr := NewFrameReader(bytes.NewReader(buf.Bytes()))
+		for i, want := range []uint8{codec, CodecNone, codec} {
+			p, err := fr.NextFrame()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p[0] != want {
+				t.Errorf("codec %d: frame %d has codec %d", codec, i, p[0])
+			}
+		}
+
+		cr := NewCompressedReader(&buf)
+		var s frameString
+		if err := cr.ReadFrame(&s); err != nil || s != long {
+			t.Errorf("codec %d: read %d bytes, %v", codec, len(s), err)
+		}
+		if err := cr.ReadFrame(&s); err != nil || s != "short" {
+			t.Errorf("codec %d: read %q, %v", codec, s, err)
+		}
+		if err := cr.SkipFrame(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := cr.NextFrame(); err != io.EOF {
+			t.Errorf("codec %d: got %v at the end of the stream", codec, err)
+		}
+	}
+}
+
+func TestCompressedIncompressible(t *testing.T) {
+	msg := AppendBytes(nil, []byte{0x8e, 0x13, 0x57, 0xa1, 0x09, 0xfe, 0x42, 0x6d})
+	b, err := AppendCompressed(nil, msg, CodecGzip, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) != 4+1+len(msg) || b[4] != CodecNone {
+		t.Errorf("incompressible message was written as %x", b)
+	}
+}
+
+func TestCompressedBytes(t *testing.T) {
+	msg := AppendString(nil, strings.Repeat("a", 1000))
+	var b []byte
+	var err error
+	for _, codec := range []uint8{CodecFlate, CodecGzip, codecIdentity} {
+		if b, err = AppendCompressed(b, msg, codec, 0, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// readable as bytes and as a stream
+	o := b
+	for i := 0; i < 3; i++ {
+		var got []byte
+		got, o, err = ReadCompressedBytes(o, nil)
+		if err != nil || !bytes.Equal(got, msg) {
+			t.Errorf("frame %d: read %d bytes, %v", i, len(got), err)
+		}
+	}
+	if len(o) != 0 {
+		t.Errorf("%d bytes left", len(o))
+	}
+	cr := NewCompressedReader(bytes.NewReader(b))
+	for i := 0; i < 3; i++ {
+		got, err := cr.NextFrame()
+		if err != nil || !bytes.Equal(got, msg) {
+			t.Errorf("frame %d: read %d bytes, %v", i, len(got), err)
+		}
+	}
+}
+
+func TestCompressedErrors(t *testing.T) {
+	msg := AppendString(nil, strings.Repeat("a", 1000))
+	if _, err := AppendCompressed(nil, msg, 99, 0, false); err != (CodecError{Codec: 99}) {
+		t.Errorf("got %v for an unregistered codec", err)
+	}
+
+	// an unknown codec doesn't stop the stream
+	var buf bytes.Buffer
+	fw := NewFrameWriter(&buf)
+	fw.WriteFrame(append([]byte{99}, msg...))
+	cw := NewCompressedWriter(&buf, CodecFlate)
+	cw.WriteFrame(msg)
+	cr := NewCompressedReader(&buf)
+	if _, err := cr.NextFrame(); err != (CodecError{Codec: 99}) {
+		t.Errorf("got %v for an unknown codec", err)
+	}
+	if got, err := cr.NextFrame(); err != nil || !bytes.Equal(got, msg) {
+		t.Errorf("after an unknown codec, read %d bytes, %v", len(got), err)
+	}
+
+	// messages that decompress to more than MaxSize
+	cw.WriteFrame(msg)
+	cr.MaxSize = 100
+	if _, err := cr.NextFrame(); err != (FrameSizeError{Size: 101, Max: 100}) {
+		t.Errorf("got %v for a large message", err)
+	}
+}
+
+func TestCompressedBatch(t *testing.T) {
+	var one, batched bytes.Buffer
+	cw := NewCompressedWriter(&one, CodecFlate)
+	bw := NewCompressedWriter(&batched, CodecFlate)
+	bw.BatchMessages = 40
+	bw.BatchBytes = 1 << 10
+	for i := 0; i < 100; i++ {
+		s := frameString(fmt.Sprintf("message %d", i))
+		if err := cw.Encode(s); err != nil {
+			t.Fatal(err)
+		}
+		if err := bw.Encode(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	n := batched.Len()
+	if err := bw.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if batched.Len() == n {
+		t.Error("Flush didn't write the last batch")
+	}
+	if batched.Len() >= one.Len()/2 {
+		t.Errorf("batches are %d bytes; single messages are %d", batched.Len(), one.Len())
+	}
+
+	// two full batches and the rest
+	fr := NewFrameReader(bytes.NewReader(batched.Bytes()))
+	for i := 0; i < 3; i++ {
+		if err := fr.SkipFrame(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := fr.SkipFrame(); err != io.EOF {
+		t.Errorf("got %v after three frames", err)
+	}
+
+	cr := NewCompressedReader(&batched)
+	var s frameString
+	for i := 0; i < 100; i++ {
+		switch i {
+		case 10:
+			// skip one message
+			if err := cr.SkipFrame(); err != nil {
+				t.Fatal(err)
+			}
+			i++
+		case 50:
+			// skip the rest of the second batch
+			if err := cr.SkipBatch(); err != nil {
+				t.Fatal(err)
+			}
+			i = 80
+		}
+		if err := cr.ReadFrame(&s); err != nil || string(s) != fmt.Sprintf("message %d", i) {
+			t.Fatalf("message %d: read %q, %v", i, s, err)
+		}
+	}
+	if _, err := cr.NextFrame(); err != io.EOF {
+		t.Errorf("got %v at the end of the stream", err)
+	}
+
+	// a batch is flushed before it gets too large for a frame
+	batched.Reset()
+	bw.MaxSize = 100
+	for i := 0; i < 10; i++ {
+		if err := bw.Encode(frameString(strings.Repeat("x", 30))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	cr = NewCompressedReader(&batched)
+	for i := 0; i < 10; i++ {
+		if err := cr.ReadFrame(&s); err != nil || len(s) != 30 {
+			t.Fatalf("message %d: read %q, %v", i, s, err)
+		}
+	}
+}
+
+func TestCompressedBytesChecksum(t *testing.T) {
+	msg := AppendString(nil, strings.Repeat("a", 1000))
+	b, err := AppendCompressed(nil, msg, CodecFlate, 0, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _, err := ReadCompressedBytes(b, nil); err != nil || !bytes.Equal(got, msg) {
+		t.Fatalf("read %d bytes, %v", len(got), err)
+	}
+	b[len(b)-1] ^= 0xff
+	if _, _, err = ReadCompressedBytes(b, nil); err == nil {
+		t.Error("read a corrupted frame")
+	} else if _, ok := err.(ChecksumError); !ok {
+		t.Errorf("got %v; want a ChecksumError", err)
+	}
+}
+
+func TestRegisterCodecPanics(t *testing.T) {
+	for _, id := range []uint8{CodecNone, CodecGzip, codecIdentity} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("RegisterCodec(%d) didn't panic", id)
+				}
+			}()
+			RegisterCodec(id, identityCodec{})
+		}()
+	}
+}
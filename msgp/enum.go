@@ -0,0 +1,226 @@
+package msgp
+
+import (
+	"strconv"
+)
+
+// Enum describes the constants of an integer
+// type. The code generated by the //msgp:enum
+// directive uses it to write the constants by
+// name and to read them from either their name
+// or their integer value.
+type Enum struct {
+	typ      string
+	names    []string
+	values   []int64
+	maxlen   int
+	unsigned bool // the values are uint64s
+}
+
+// NewEnum returns an Enum for the type 'typ'
+// with the given constant names and values,
+// which must have the same length.
+func NewEnum(typ string, names []string, values []int64) *Enum {
+	if len(names) != len(values) {
+		panic("msgp: NewEnum called with different numbers of names and values")
+	}
+	e := &Enum{typ: typ, names: names, values: values}
+	for _, n := range names {
+		if len(n) > e.maxlen {
+			e.maxlen = len(n)
+		}
+	}
+	return e
+}
+
+// NewEnumUint64 is NewEnum for a type whose constants
+// may not fit in an int64. The values are converted
+// to int64 (and wrap around), and integers are read
+// as uint64s.
+func NewEnumUint64(typ string, names []string, values []uint64) *Enum {
+	v := make([]int64, len(values))
+	for i := range values {
+		v[i] = int64(values[i])
+	}
+	e := NewEnum(typ, names, v)
+	e.unsigned = true
+	return e
+}
+
+// Name returns the name of the constant
+// with the value 'v', if there is one.
+func (e *Enum) Name(v int64) (string, bool) {
+	for i := range e.values {
+		if e.values[i] == v {
+			return e.names[i], true
+		}
+	}
+	return "", false
+}
+
+// String returns the name of the constant with the
+// value 'v', or the type and value (e.g. "Color(7)")
+// if there is no such constant.
+func (e *Enum) String(v int64) string {
+	if name, ok := e.Name(v); ok {
+		return name
+	}
+	if e.unsigned {
+		return e.typ + "(" + strconv.FormatUint(uint64(v), 10) + ")"
+	}
+	return e.typ + "(" + strconv.FormatInt(v, 10) + ")"
+}
+
+// Parse returns the value of the constant named 's',
+// or an EnumError if there is no such constant.
+func (e *Enum) Parse(s string) (int64, error) {
+	for i := range e.names {
+		if e.names[i] == s {
+			return e.values[i], nil
+		}
+	}
+	return 0, EnumError{Type: e.typ, Value: s}
+}
+
+// parse is Parse for a name that
+// may alias a buffer
+func (e *Enum) parse(b []byte) (int64, error) {
+	for i := range e.names {
+		if e.names[i] == string(b) {
+			return e.values[i], nil
+		}
+	}
+	return 0, EnumError{Type: e.typ, Value: string(b)}
+}
+
+// Check returns 'v' if it is the value of
+// one of the constants, or an EnumError.
+func (e *Enum) Check(v int64) (int64, error) {
+	if _, ok := e.Name(v); !ok {
+		return 0, e.valueError(v)
+	}
+	return v, nil
+}
+
+// valueError returns the EnumError for the value 'v'
+func (e *Enum) valueError(v int64) error {
+	if e.unsigned {
+		return EnumError{Type: e.typ, Value: uint64(v)}
+	}
+	return EnumError{Type: e.typ, Value: v}
+}
+
+// Size returns the maximum encoded size
+// of a constant written by name.
+func (e *Enum) Size() int {
+	return StringPrefixSize + e.maxlen
+}
+
+// EnumError is returned when reading or writing
+// a value that isn't one of the constants of an enum.
+type EnumError struct {
+	Type  string      // the enum type
+	Value interface{} // the name (string) or value (int64, or uint64 for NewEnumUint64)
+}
+
+// Error implements the error interface
+func (e EnumError) Error() string {
+	switch v := e.Value.(type) {
+	case string:
+		return "msgp: " + strconv.Quote(v) + " is not a valid " + e.Type
+	case uint64:
+		return "msgp: " + e.Type + "(" + strconv.FormatUint(v, 10) + ") is not a valid " + e.Type
+	}
+	return "msgp: " + e.Type + "(" + strconv.FormatInt(e.Value.(int64), 10) + ") is not a valid " + e.Type
+}
+
+// Resumable is always 'true' for EnumErrors
+func (e EnumError) Resumable() bool { return true }
+
+// WriteEnum writes the name of the constant
+// with the value 'v', returning an EnumError
+// if there is no such constant.
+func (mw *Writer) WriteEnum(e *Enum, v int64) error {
+	name, ok := e.Name(v)
+	if !ok {
+		return e.valueError(v)
+	}
+	return mw.WriteString(name)
+}
+
+// ReadEnum reads a constant of 'e' written either
+// by name or as an integer, and returns its value.
+// Names and values that aren't constants of 'e'
+// return an EnumError.
+func (m *Reader) ReadEnum(e *Enum) (int64, error) {
+	t, err := m.NextType()
+	if err != nil {
+		return 0, err
+	}
+	if t == StrType {
+		p, err := m.ReadMapKeyPtr()
+		if err != nil {
+			return 0, err
+		}
+		return e.parse(p)
+	}
+	var v int64
+	if e.unsigned {
+		var u uint64
+		u, err = m.ReadUint64()
+		v = int64(u)
+	} else {
+		v, err = m.ReadInt64()
+	}
+	if err != nil {
+		return 0, err
+	}
+	return e.Check(v)
+}
+
+// AppendEnum appends the name of the constant
+// with the value 'v', returning an EnumError
+// if there is no such constant.
+func AppendEnum(b []byte, e *Enum, v int64) ([]byte, error) {
+	name, ok := e.Name(v)
+	if !ok {
+		return b, e.valueError(v)
+	}
+	return AppendString(b, name), nil
+}
+
+// ReadEnumBytes reads a constant of 'e' written
+// either by name or as an integer from 'b', and
+// returns its value and the remaining bytes.
+// Names and values that aren't constants of 'e'
+// return an EnumError.
+func ReadEnumBytes(b []byte, e *Enum) (int64, []byte, error) {
+	if NextType(b) == StrType {
+		p, o, err := ReadStringZC(b)
+		if err != nil {
+			return 0, b, err
+		}
+		v, err := e.parse(p)
+		if err != nil {
+			return 0, b, err
+		}
+		return v, o, nil
+	}
+	var v int64
+	var o []byte
+	var err error
+	if e.unsigned {
+		var u uint64
+		u, o, err = ReadUint64Bytes(b)
+		v = int64(u)
+	} else {
+		v, o, err = ReadInt64Bytes(b)
+	}
+	if err != nil {
+		return 0, b, err
+	}
+	if _, err = e.Check(v); err != nil {
+		return 0, b, err
+	}
+	return v, o, nil
+}
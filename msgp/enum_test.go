@@ -0,0 +1,87 @@
+package msgp
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEnum(t *testing.T) {
+	e := NewEnum("Color", []string{"Red", "Green"}, []int64{0, 5})
+	if e.Size() != StringPrefixSize+5 {
+		t.Errorf("Size: got %d", e.Size())
+	}
+	if s := e.String(5); s != "Green" {
+		t.Errorf("String(5) = %q", s)
+	}
+	if s := e.String(3); s != "Color(3)" {
+		t.Errorf("String(3) = %q", s)
+	}
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	for _, v := range []int64{5, 0} {
+		if err := w.WriteEnum(e, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.WriteEnum(e, 3); err == nil {
+		t.Error("expected an error writing an unknown value")
+	}
+	w.WriteInt64(5)
+	w.WriteString("Blue")
+	w.WriteInt(3)
+	w.Flush()
+	bts := append([]byte(nil), buf.Bytes()...)
+
+	r := NewReader(&buf)
+	for _, want := range []int64{5, 0, 5} {
+		v, err := r.ReadEnum(e)
+		if err != nil || v != want {
+			t.Errorf("ReadEnum: got %d, %v; want %d", v, err, want)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		// the bad value is consumed
+		_, err := r.ReadEnum(e)
+		if _, ok := err.(EnumError); !ok {
+			t.Errorf("ReadEnum: got error %v; want an EnumError", err)
+		}
+	}
+
+	for _, want := range []int64{5, 0, 5} {
+		var v int64
+		var err error
+		v, bts, err = ReadEnumBytes(bts, e)
+		if err != nil || v != want {
+			t.Errorf("ReadEnumBytes: got %d, %v; want %d", v, err, want)
+		}
+	}
+	if _, o, err := ReadEnumBytes(bts, e); err == nil || len(o) != len(bts) {
+		t.Errorf("ReadEnumBytes: got error %v and %d bytes left; want an error and %d", err, len(o), len(bts))
+	}
+
+	b, err := AppendEnum(nil, e, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s, _, _ := ReadStringBytes(b); s != "Red" {
+		t.Errorf("AppendEnum wrote %q", s)
+	}
+}
+
+func TestEnumUint64(t *testing.T) {
+	e := NewEnumUint64("Mask", []string{"Low", "High"}, []uint64{1, 1 << 63})
+	b := AppendUint64(nil, 1<<63)
+	b = AppendUint64(b, 1<<63+1)
+	v, b, err := ReadEnumBytes(b, e)
+	if err != nil || uint64(v) != 1<<63 {
+		t.Errorf("got %d, %v", uint64(v), err)
+	}
+	_, _, err = ReadEnumBytes(b, e)
+	if err != (EnumError{Type: "Mask", Value: uint64(1<<63 + 1)}) {
+		t.Errorf("got error %v", err)
+	}
+	if s := e.String(-1); s != "Mask(18446744073709551615)" {
+		t.Errorf("String(-1) = %q", s)
+	}
+}
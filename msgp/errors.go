@@ -56,6 +56,37 @@ func (a ArrayError) Error() string {
 // Resumable is always 'true' for ArrayErrors
 func (a ArrayError) Resumable() bool { return true }
 
+// PackedError is returned when decoding
+// a packed slice whose length in bytes
+// isn't a multiple of its element size
+type PackedError struct {
+	Width int // the size of an element
+	Len   int // the length of the packed bytes
+}
+
+// Error implements the error interface
+func (p PackedError) Error() string {
+	return fmt.Sprintf("msgp: %d packed bytes can't hold elements of size %d", p.Len, p.Width)
+}
+
+// Resumable is always 'true' for PackedErrors
+func (p PackedError) Resumable() bool { return true }
+
+// PackedSizeError is returned when writing a packed
+// slice that is larger than a bin object can hold
+type PackedSizeError struct {
+	Size uint64 // the size of the slice in bytes
+}
+
+// Error implements the error interface
+func (p PackedSizeError) Error() string {
+	return fmt.Sprintf("msgp: packed slice of %d bytes is too large for a bin object", p.Size)
+}
+
+// Resumable is always 'true' for PackedSizeErrors,
+// since nothing is written.
+func (p PackedSizeError) Resumable() bool { return true }
+
 // IntOverflow is returned when a call
 // would downcast an integer to a type
 // with too few bits to hold its value.
@@ -155,3 +186,19 @@ func (e *ErrUnsupportedType) Error() string { return fmt.Sprintf("msgp: type %q
 
 // Resumable returns 'true' for ErrUnsupportedType
 func (e *ErrUnsupportedType) Resumable() bool { return true }
+
+// MappingFaultError is returned by WriteFile when
+// writing to the file mapping faults, e.g. because
+// the file system has run out of space. The contents
+// of the file are undefined.
+type MappingFaultError struct {
+	Err interface{} // the value recovered from the fault
+}
+
+// Error implements the error interface
+func (m MappingFaultError) Error() string {
+	return fmt.Sprintf("msgp: fault writing file mapping: %v", m.Err)
+}
+
+// Resumable is always 'false' for MappingFaultErrors
+func (m MappingFaultError) Resumable() bool { return false }
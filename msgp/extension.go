@@ -14,6 +14,10 @@ const (
 
 	// TimeExtension is the extension number used for time.Time
 	TimeExtension = 5
+
+	// TimestampExtension is the extension number that the
+	// MessagePack specification defines for timestamps
+	TimestampExtension = -1
 )
 
 // our extensions live here
@@ -26,7 +30,8 @@ var extensionReg = make(map[int8]func() Extension)
 // a newly-initialized zero value of the extension. Keep in
 // mind that extensions 3, 4, and 5 are reserved for
 // complex64, complex128, and time.Time, respectively,
-// and that MessagePack reserves extension types from -127 to -1.
+// and that MessagePack reserves extension types from -127 to -1
+// (-1 is the timestamp extension).
 //
 // For example, if you wanted to register a user-defined struct:
 //
@@ -34,10 +39,10 @@ var extensionReg = make(map[int8]func() Extension)
 //
 // RegisterExtension will panic if you call it multiple times
 // with the same 'typ' argument, or if you use a reserved
-// type (3, 4, or 5).
+// type (-1, 3, 4, or 5).
 func RegisterExtension(typ int8, f func() Extension) {
 	switch typ {
-	case Complex64Extension, Complex128Extension, TimeExtension:
+	case Complex64Extension, Complex128Extension, TimeExtension, TimestampExtension:
 		panic(fmt.Sprint("msgp: forbidden extension type:", typ))
 	}
 	if _, ok := extensionReg[typ]; ok {
@@ -120,6 +125,40 @@ func (r *RawExtension) UnmarshalBinary(b []byte) error {
 	return nil
 }
 
+// MarshalLen returns the number of bytes written
+// by m.MarshalMsg, or 0 if it returns an error.
+// Types generated with the //msgp:extension directive
+// use it to implement Extension.Len.
+func MarshalLen(m Marshaler) int {
+	scratch := scratchPool.Get().([]byte)
+	o, err := m.MarshalMsg(scratch[:0])
+	if cap(o) <= 4096 {
+		scratchPool.Put(o)
+	} else {
+		scratchPool.Put(scratch)
+	}
+	if err != nil {
+		return 0
+	}
+	return len(o)
+}
+
+// MarshalTo writes the output of m.MarshalMsg
+// to 'b', which must be exactly MarshalLen(m) bytes
+// long. Types generated with the //msgp:extension
+// directive use it to implement Extension.MarshalBinaryTo.
+func MarshalTo(m Marshaler, b []byte) error {
+	o, err := m.MarshalMsg(b[:0])
+	if err != nil {
+		return err
+	}
+	if len(o) != len(b) {
+		return fmt.Errorf("msgp: %T encoded to %d bytes; expected %d", m, len(o), len(b))
+	}
+	copy(b, o)
+	return nil
+}
+
 // WriteExtension writes an extension type to the writer
 func (mw *Writer) WriteExtension(e Extension) error {
 	l := e.Len()
@@ -204,7 +243,7 @@ func (mw *Writer) WriteExtension(e Extension) error {
 		if err != nil {
 			return err
 		}
-		return e.MarshalBinaryTo(mw.buf[o:])
+		return e.MarshalBinaryTo(mw.buf[o : o+l])
 	}
 	// here we create a new buffer
 	// just large enough for the body
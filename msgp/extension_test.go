@@ -72,3 +72,48 @@ func TestAppendAndWriteCompatibility(t *testing.T) {
 		}
 	}
 }
+
+func TestMarshalLenTo(t *testing.T) {
+	r := Raw(AppendString(nil, "hello, world"))
+	if n := MarshalLen(r); n != len(r) {
+		t.Fatalf("MarshalLen: got %d; want %d", n, len(r))
+	}
+	b := make([]byte, len(r))
+	if err := MarshalTo(r, b); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, r) {
+		t.Errorf("MarshalTo wrote % x; want % x", b, r)
+	}
+	if err := MarshalTo(r, make([]byte, len(r)+1)); err == nil {
+		t.Error("expected an error for a buffer of the wrong size")
+	}
+}
+
+// countMarshal counts its calls to MarshalMsg
+type countMarshal struct {
+	s     string
+	calls int
+}
+
+func (c *countMarshal) MarshalMsg(b []byte) ([]byte, error) {
+	c.calls++
+	return AppendString(b, c.s), nil
+}
+
+func TestMarshalLenToModified(t *testing.T) {
+	c := &countMarshal{s: "hello, world"}
+	b := make([]byte, MarshalLen(c))
+	// a value that changes between the
+	// calls is encoded as it is then
+	c.s = "jello, world"
+	if err := MarshalTo(c, b); err != nil {
+		t.Fatal(err)
+	}
+	if s, _, err := ReadStringBytes(b); err != nil || s != c.s {
+		t.Fatalf("MarshalTo wrote %q, %v", s, err)
+	}
+	if c.calls != 2 {
+		t.Errorf("MarshalMsg was called %d times", c.calls)
+	}
+}
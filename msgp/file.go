@@ -5,6 +5,9 @@ package msgp
 
 import (
 	"os"
+	"reflect"
+	"runtime"
+	"runtime/debug"
 	"syscall"
 )
 
@@ -52,10 +55,14 @@ type MarshalSizer interface {
 // contents of the previous file.
 // The mapping size is calculated
 // using the `Msgsize()` method
-// of 'src', so it must produce a result
-// equal to or greater than the actual encoded
-// size of the object. Otherwise,
-// a fault (SIGBUS) will occur.
+// of 'src'. If that is less than the actual
+// encoded size of the object (which can happen
+// with shims or msgp.Any), the encoding is
+// written from memory instead, and if the
+// mapping faults (e.g. because the disk is full),
+// an error is returned. (Before Go 1.17, faults
+// can't be told apart, so the encoding is always
+// written from memory.)
 //
 // Reading and writing through file mappings
 // is only efficient for large files; small
@@ -70,6 +77,11 @@ type MarshalSizer interface {
 // that support fallocate(2) for the best results.)
 func WriteFile(src MarshalSizer, file *os.File) error {
 	sz := src.Msgsize()
+	if sz <= 0 || !faultAddrs {
+		// mappings can't be empty, and faults
+		// can't be recovered without their address
+		return writeFileBuffered(src, file)
+	}
 	err := fallocate(file, int64(sz))
 	if err != nil {
 		return err
@@ -79,14 +91,76 @@ func WriteFile(src MarshalSizer, file *os.File) error {
 		return err
 	}
 	adviseWrite(data)
-	chunk := data[:0]
-	chunk, err = src.MarshalMsg(chunk)
+	chunk, err := marshalMapped(src, data)
+	uerr := syscall.Munmap(data)
 	if err != nil {
 		return err
 	}
-	uerr := syscall.Munmap(data)
 	if uerr != nil {
 		return uerr
 	}
+	if len(chunk) > 0 && &chunk[0] != &data[0] {
+		// Msgsize was too small, so MarshalMsg
+		// moved the encoding out of the mapping
+		if _, err = file.WriteAt(chunk, 0); err != nil {
+			return err
+		}
+	}
 	return file.Truncate(int64(len(chunk)))
 }
+
+// marshalMapped marshals 'src' into 'data', and
+// returns a MappingFaultError instead of crashing
+// if the mapping faults
+func marshalMapped(src Marshaler, data []byte) (chunk []byte, err error) {
+	defer debug.SetPanicOnFault(debug.SetPanicOnFault(true))
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(runtime.Error); !ok || !inMapping(r, data) {
+				panic(r)
+			}
+			err = MappingFaultError{Err: r}
+		}
+	}()
+	return src.MarshalMsg(data[:0])
+}
+
+// inMapping returns whether the fault 'r' is at an
+// address in 'data'. Errors without an address (e.g.
+// from a nil dereference, or any fault before Go 1.17)
+// aren't.
+func inMapping(r interface{}, data []byte) bool {
+	a, ok := r.(interface{ Addr() uintptr })
+	if !ok {
+		return false
+	}
+	start := reflect.ValueOf(data).Pointer()
+	return a.Addr() >= start && a.Addr() < start+uintptr(len(data))
+}
+
+// writeFileBuffered writes 'src' at the
+// start of 'file' and truncates it
+func writeFileBuffered(src Marshaler, file *os.File) error {
+	b, err := src.MarshalMsg(nil)
+	if err != nil {
+		return err
+	}
+	if _, err = file.WriteAt(b, 0); err != nil {
+		return err
+	}
+	return file.Truncate(int64(len(b)))
+}
+
+// syncDir commits the directory
+// entries in 'dir' to stable storage
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	err = d.Sync()
+	if cerr := d.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
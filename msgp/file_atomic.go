@@ -0,0 +1,51 @@
+package msgp
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// WriteFileAtomic writes 'src' to the file 'name'
+// with WriteFile, so that the file holds either its
+// old contents or all of the new ones, even if the
+// process or machine crashes. It writes a temporary
+// file in the same directory, syncs it, gives it the
+// permissions 'perm' and renames it to 'name', and
+// then syncs the directory.
+func WriteFileAtomic(src MarshalSizer, name string, perm os.FileMode) error {
+	dir, base := filepath.Split(name)
+	if dir == "" {
+		dir = "."
+	}
+	tmp, err := ioutil.TempFile(dir, "."+base+".tmp")
+	if err != nil {
+		return err
+	}
+	if err = writeTemp(src, tmp, perm); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err = os.Rename(tmp.Name(), name); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return syncDir(dir)
+}
+
+// writeTemp writes and syncs
+// the temporary file
+func writeTemp(src MarshalSizer, tmp *os.File, perm os.FileMode) error {
+	if err := WriteFile(src, tmp); err != nil {
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		return err
+	}
+	return tmp.Sync()
+}
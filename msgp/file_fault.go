@@ -0,0 +1,9 @@
+// +build go1.17
+
+package msgp
+
+// faultAddrs is whether the errors that faults
+// panic with have an Addr method (since Go 1.17),
+// which WriteFile needs to tell faults in its
+// mapping from others
+const faultAddrs = true
@@ -0,0 +1,9 @@
+// +build !go1.17
+
+package msgp
+
+// faultAddrs is whether the errors that faults
+// panic with have an Addr method (since Go 1.17).
+// Without it, WriteFile can't tell faults in its
+// mapping from others, so it doesn't map the file.
+const faultAddrs = false
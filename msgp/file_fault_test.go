@@ -0,0 +1,67 @@
+// +build linux darwin dragonfly freebsd netbsd openbsd
+// +build !appengine
+
+package msgp
+
+import (
+	"io/ioutil"
+	"os"
+	"runtime"
+	"syscall"
+	"testing"
+)
+
+func TestMarshalMappedFault(t *testing.T) {
+	if !faultAddrs {
+		t.Skip("fault addresses aren't known before Go 1.17")
+	}
+	f, err := ioutil.TempFile("", "msgp-fault")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		f.Close()
+		os.Remove(f.Name())
+	}()
+
+	// the file is empty, so writing
+	// to the mapping faults
+	data, err := syscall.Mmap(int(f.Fd()), 0, 1<<16, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer syscall.Munmap(data)
+
+	_, err = marshalMapped(Raw(AppendString(nil, "fault")), data)
+	if _, ok := err.(MappingFaultError); !ok {
+		t.Fatalf("got error %v", err)
+	}
+
+	// other panics aren't recovered
+	func() {
+		defer func() {
+			if r := recover(); r != "boom" {
+				t.Errorf("recovered %v", r)
+			}
+		}()
+		marshalMapped(panicMarshal{}, data)
+	}()
+
+	// nor are faults outside the mapping
+	defer func() {
+		if _, ok := recover().(runtime.Error); !ok {
+			t.Error("a nil dereference wasn't re-panicked")
+		}
+	}()
+	marshalMapped(nilMarshal{}, data)
+}
+
+type panicMarshal struct{}
+
+func (panicMarshal) MarshalMsg(b []byte) ([]byte, error) { panic("boom") }
+
+type nilMarshal struct {
+	p *[]byte
+}
+
+func (n nilMarshal) MarshalMsg(b []byte) ([]byte, error) { return append(b, *n.p...), nil }
@@ -45,3 +45,8 @@ func WriteFile(src MarshalSizer, file *os.File) error {
 	_, err = file.Write(raw)
 	return err
 }
+
+// syncDir is a no-op, since
+// directories can't be synced
+// on these platforms
+func syncDir(dir string) error { return nil }
@@ -5,8 +5,11 @@ package msgp_test
 import (
 	"bytes"
 	"crypto/rand"
+	"errors"
+	"io/ioutil"
 	prand "math/rand"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/bytedance/msgp/msgp"
@@ -60,6 +63,102 @@ func TestReadWriteFile(t *testing.T) {
 	}
 }
 
+// badSize reports the wrong Msgsize
+type badSize struct {
+	rawBytes
+	size int
+}
+
+func (b badSize) Msgsize() int { return b.size }
+
+func TestWriteFileBadMsgsize(t *testing.T) {
+	t.Parallel()
+
+	f, err := ioutil.TempFile("", "msgp-file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		f.Close()
+		os.Remove(f.Name())
+	}()
+
+	data := make([]byte, 64*1024)
+	rand.Read(data)
+	for _, size := range []int{0, 1, 100, len(data) + 5, 1 << 20} {
+		f.Seek(0, os.SEEK_SET)
+		if err = msgp.WriteFile(badSize{rawBytes(data), size}, f); err != nil {
+			t.Fatalf("Msgsize %d: %s", size, err)
+		}
+		var out rawBytes
+		f.Seek(0, os.SEEK_SET)
+		if err = msgp.ReadFile(&out, f); err != nil {
+			t.Fatalf("Msgsize %d: %s", size, err)
+		}
+		if !bytes.Equal(out, data) {
+			t.Errorf("Msgsize %d: the file doesn't match", size)
+		}
+		st, _ := f.Stat()
+		if want := int64(rawBytes(data).Msgsize()); st.Size() != want {
+			t.Errorf("Msgsize %d: file is %d bytes; want %d", size, st.Size(), want)
+		}
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "msgp-atomic")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "data")
+
+	for _, s := range []string{"first", "second"} {
+		if err = msgp.WriteFileAtomic(rawBytes(s), name, 0640); err != nil {
+			t.Fatal(err)
+		}
+		f, err := os.Open(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out rawBytes
+		err = msgp.ReadFile(&out, f)
+		f.Close()
+		if err != nil || string(out) != s {
+			t.Errorf("got %q, %v; want %q", []byte(out), err, s)
+		}
+	}
+	if st, _ := os.Stat(name); st.Mode().Perm() != 0640 {
+		t.Errorf("file has mode %s", st.Mode())
+	}
+
+	// a failed write leaves the old file
+	if err = msgp.WriteFileAtomic(failMarshal{}, name, 0640); err == nil {
+		t.Fatal("no error from a failed write")
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var out rawBytes
+	if err = msgp.ReadFile(&out, f); err != nil || string(out) != "second" {
+		t.Errorf("got %q, %v after a failed write", []byte(out), err)
+	}
+	if names, _ := ioutil.ReadDir(dir); len(names) != 1 {
+		t.Errorf("left %d files in the directory", len(names))
+	}
+}
+
+// failMarshal always fails to marshal
+type failMarshal struct{}
+
+func (failMarshal) MarshalMsg(b []byte) ([]byte, error) { return b, errors.New("failed") }
+
+func (failMarshal) Msgsize() int { return 10 }
+
 var blobstrings = []string{"", "a string", "a longer string here!"}
 var blobfloats = []float64{0.0, -1.0, 1.0, 3.1415926535}
 var blobints = []int64{0, 1, -1, 80000, 1 << 30}
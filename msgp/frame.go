@@ -0,0 +1,366 @@
+package msgp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+)
+
+// Frames wrap each message in a stream with a
+// header holding its length, so that a reader can
+// skip messages without parsing them and stay in
+// sync after a message it can't decode.
+//
+// The header is a 4-byte big-endian integer, where
+// the low 31 bits are the length of the message and
+// the high bit is set if a 4-byte big-endian CRC-32C
+// (Castagnoli) checksum follows. The checksum covers
+// the 4-byte integer and then the message, so that a
+// corrupted length is caught too.
+
+const (
+	frameChecksumBit = 1 << 31
+
+	// DefaultMaxFrameSize is the largest frame
+	// written or read by a FrameWriter or
+	// FrameReader that doesn't set MaxSize.
+	DefaultMaxFrameSize = 64 << 20
+)
+
+var (
+	castagnoli  = crc32.MakeTable(crc32.Castagnoli)
+	blankHeader [8]byte
+)
+
+// FrameSizeError is returned when writing or
+// reading a frame larger than the maximum size.
+type FrameSizeError struct {
+	Size int // the size of the frame
+	Max  int // the maximum size
+}
+
+// Error implements the error interface
+func (f FrameSizeError) Error() string {
+	return fmt.Sprintf("msgp: frame of %d bytes is larger than the maximum of %d", f.Size, f.Max)
+}
+
+// Resumable is always 'true' for FrameSizeErrors,
+// since the frame is skipped.
+func (f FrameSizeError) Resumable() bool { return true }
+
+// ChecksumError is returned when the
+// checksum of a frame doesn't match.
+type ChecksumError struct {
+	Want uint32 // the checksum in the header
+	Got  uint32 // the checksum of the frame
+}
+
+// Error implements the error interface
+func (c ChecksumError) Error() string {
+	return fmt.Sprintf("msgp: frame checksum %08x doesn't match %08x", c.Got, c.Want)
+}
+
+// Resumable is always 'true' for ChecksumErrors,
+// since the frame is skipped. (If it was its length
+// that was corrupted, the frames that follow are
+// misread, and fail their checksums too.)
+func (c ChecksumError) Resumable() bool { return true }
+
+// frameChecksum returns the checksum of the frame
+// with the header 'hdr' and the contents 'p'
+func frameChecksum(hdr []byte, p []byte) uint32 {
+	return crc32.Update(crc32.Checksum(hdr[:4], castagnoli), castagnoli, p)
+}
+
+// maxFrameSize returns the limit set by 'max', which
+// is at most the largest size a header can hold
+func maxFrameSize(max int) int {
+	switch {
+	case max <= 0:
+		return DefaultMaxFrameSize
+	case max > 1<<31-1:
+		return 1<<31 - 1
+	}
+	return max
+}
+
+// FrameWriter writes messages to an
+// io.Writer, each in its own frame.
+type FrameWriter struct {
+	// Checksum sets whether frames are
+	// written with a CRC-32C checksum.
+	Checksum bool
+
+	// MaxSize is the largest message that
+	// can be written. If it is zero,
+	// DefaultMaxFrameSize is used. It is
+	// at most 1<<31-1.
+	MaxSize int
+
+	w       io.Writer
+	enc     encoder
+	scratch []byte
+}
+
+// NewFrameWriter returns a FrameWriter
+// that writes to 'w'.
+func NewFrameWriter(w io.Writer) *FrameWriter {
+	return &FrameWriter{w: w}
+}
+
+// Reset changes the io.Writer that
+// frames are written to.
+func (fw *FrameWriter) Reset(w io.Writer) { fw.w = w }
+
+func (fw *FrameWriter) headerSize() int {
+	if fw.Checksum {
+		return 8
+	}
+	return 4
+}
+
+// Encode writes 'e' as one frame. Values that
+// implement Marshaler are appended directly
+// to the frame.
+func (fw *FrameWriter) Encode(e Encodable) error {
+	hdr := fw.headerSize()
+	b, err := fw.enc.encode(append(fw.scratch[:0], blankHeader[:hdr]...), e)
+	if err != nil {
+		return err
+	}
+	fw.scratch = b
+	return fw.writeFrame(b, hdr)
+}
+
+// WriteFrame writes 'p', which should
+// be one message, as one frame.
+func (fw *FrameWriter) WriteFrame(p []byte) error {
+	hdr := fw.headerSize()
+	fw.scratch = append(append(fw.scratch[:0], blankHeader[:hdr]...), p...)
+	return fw.writeFrame(fw.scratch, hdr)
+}
+
+// writeFrame fills in the header at the start
+// of 'b' and writes it with a single call to Write
+func (fw *FrameWriter) writeFrame(b []byte, hdr int) error {
+	sz := len(b) - hdr
+	if max := maxFrameSize(fw.MaxSize); sz > max {
+		return FrameSizeError{Size: sz, Max: max}
+	}
+	n := uint32(sz)
+	if fw.Checksum {
+		n |= frameChecksumBit
+	}
+	binary.BigEndian.PutUint32(b, n)
+	if fw.Checksum {
+		binary.BigEndian.PutUint32(b[4:], frameChecksum(b, b[hdr:]))
+	}
+	_, err := fw.w.Write(b)
+	return err
+}
+
+// FrameReader reads messages written
+// by a FrameWriter from an io.Reader.
+type FrameReader struct {
+	// MaxSize is the largest frame that
+	// can be read; larger frames are skipped.
+	// If it is zero, DefaultMaxFrameSize is used.
+	// It is at most 1<<31-1.
+	MaxSize int
+
+	r   io.Reader
+	hdr [8]byte
+	buf []byte
+	dec decoder
+}
+
+// NewFrameReader returns a FrameReader
+// that reads from 'r'.
+func NewFrameReader(r io.Reader) *FrameReader {
+	return &FrameReader{r: r}
+}
+
+// Reset changes the io.Reader that
+// frames are read from.
+func (fr *FrameReader) Reset(r io.Reader) { fr.r = r }
+
+// readHeader reads the next frame header and returns
+// the size of the frame and whether it has a checksum.
+// It returns io.EOF at the end of the stream and
+// io.ErrUnexpectedEOF for a partial header.
+func (fr *FrameReader) readHeader() (sz int, checksum bool, err error) {
+	if _, err = io.ReadFull(fr.r, fr.hdr[:4]); err != nil {
+		return 0, false, err
+	}
+	n := binary.BigEndian.Uint32(fr.hdr[:4])
+	checksum = n&frameChecksumBit != 0
+	if checksum {
+		if _, err = io.ReadFull(fr.r, fr.hdr[4:]); err != nil {
+			return 0, false, noEOF(err)
+		}
+	}
+	return int(n &^ frameChecksumBit), checksum, nil
+}
+
+// NextFrame reads the next frame and returns its
+// contents, which are only valid until the next call
+// to a method of the FrameReader. It returns io.EOF
+// at the end of the stream, io.ErrUnexpectedEOF if
+// the stream ends within a frame, a ChecksumError
+// if the checksum doesn't match, and a FrameSizeError
+// if the frame is larger than MaxSize. Frames with
+// checksum or size errors are skipped, so that the
+// next call reads the following frame.
+func (fr *FrameReader) NextFrame() ([]byte, error) {
+	sz, checksum, err := fr.readHeader()
+	if err != nil {
+		return nil, err
+	}
+	if max := maxFrameSize(fr.MaxSize); sz > max {
+		if err = fr.skip(sz); err != nil {
+			return nil, err
+		}
+		return nil, FrameSizeError{Size: sz, Max: max}
+	}
+	if cap(fr.buf) < sz {
+		fr.buf = make([]byte, sz)
+	}
+	fr.buf = fr.buf[:sz]
+	if _, err = io.ReadFull(fr.r, fr.buf); err != nil {
+		return nil, noEOF(err)
+	}
+	if checksum {
+		want := binary.BigEndian.Uint32(fr.hdr[4:])
+		if got := frameChecksum(fr.hdr[:], fr.buf); got != want {
+			return nil, ChecksumError{Want: want, Got: got}
+		}
+	}
+	return fr.buf, nil
+}
+
+// ReadFrame reads the next frame into 'd'. Values
+// that implement Unmarshaler are unmarshaled from
+// the frame directly. It returns the same errors
+// as NextFrame, and any error from decoding 'd',
+// after which the next frame can still be read.
+func (fr *FrameReader) ReadFrame(d Decodable) error {
+	p, err := fr.NextFrame()
+	if err != nil {
+		return err
+	}
+	return fr.dec.decode(p, d)
+}
+
+// SkipFrame skips the next frame without
+// reading it into memory or checking its
+// checksum. It returns io.EOF at the end
+// of the stream.
+func (fr *FrameReader) SkipFrame() error {
+	sz, _, err := fr.readHeader()
+	if err != nil {
+		return err
+	}
+	return fr.skip(sz)
+}
+
+// skip discards the next 'sz' bytes
+func (fr *FrameReader) skip(sz int) error {
+	if s, ok := fr.r.(io.Seeker); ok && sz > 0 {
+		// seeking past the end succeeds, so the
+		// last byte is read to check that it exists
+		if _, err := s.Seek(int64(sz-1), io.SeekCurrent); err != nil {
+			return err
+		}
+		var last [1]byte
+		_, err := io.ReadFull(fr.r, last[:])
+		return noEOF(err)
+	}
+	_, err := io.CopyN(ioutil.Discard, fr.r, int64(sz))
+	return noEOF(err)
+}
+
+// noEOF returns io.ErrUnexpectedEOF
+// for an io.EOF within a frame
+func noEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// readFrameBytes reads a frame from 'b' and
+// returns its contents and the remaining bytes
+func readFrameBytes(b []byte) (p []byte, o []byte, err error) {
+	if len(b) < 4 {
+		return nil, b, ErrShortBytes
+	}
+	n := binary.BigEndian.Uint32(b)
+	hdr, sz := 4, int(n&^frameChecksumBit)
+	if n&frameChecksumBit != 0 {
+		hdr = 8
+	}
+	if len(b) < hdr+sz {
+		return nil, b, ErrShortBytes
+	}
+	p = b[hdr : hdr+sz]
+	if hdr == 8 {
+		want := binary.BigEndian.Uint32(b[4:])
+		if got := frameChecksum(b, p); got != want {
+			return nil, b, ChecksumError{Want: want, Got: got}
+		}
+	}
+	return p, b[hdr+sz:], nil
+}
+
+// encoder appends Encodables to a []byte
+type encoder struct {
+	buf bytes.Buffer
+	mw  *Writer
+}
+
+// encode appends 'e' to 'b'. Values that
+// implement Marshaler are appended directly.
+func (c *encoder) encode(b []byte, e Encodable) ([]byte, error) {
+	if m, ok := e.(Marshaler); ok {
+		return m.MarshalMsg(b)
+	}
+	c.buf.Reset()
+	if c.mw == nil {
+		c.mw = NewWriter(&c.buf)
+	} else {
+		c.mw.Reset(&c.buf)
+	}
+	if err := e.EncodeMsg(c.mw); err != nil {
+		return b, err
+	}
+	if err := c.mw.Flush(); err != nil {
+		return b, err
+	}
+	return append(b, c.buf.Bytes()...), nil
+}
+
+// decoder reads Decodables from a []byte
+type decoder struct {
+	br bytes.Reader
+	mr *Reader
+}
+
+// decode reads 'd' from 'p'. Values that
+// implement Unmarshaler are unmarshaled
+// from 'p' directly.
+func (c *decoder) decode(p []byte, d Decodable) error {
+	if u, ok := d.(Unmarshaler); ok {
+		_, err := u.UnmarshalMsg(p)
+		return err
+	}
+	c.br.Reset(p)
+	if c.mr == nil {
+		c.mr = NewReader(&c.br)
+	} else {
+		c.mr.Reset(&c.br)
+	}
+	return d.DecodeMsg(c.mr)
+}
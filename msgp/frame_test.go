@@ -0,0 +1,176 @@
+package msgp
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+// frameString only implements
+// Encodable and Decodable
+type frameString string
+
+func (f frameString) EncodeMsg(w *Writer) error { return w.WriteString(string(f)) }
+
+func (f *frameString) DecodeMsg(r *Reader) error {
+	s, err := r.ReadString()
+	*f = frameString(s)
+	return err
+}
+
+func TestFrames(t *testing.T) {
+	for _, checksum := range []bool{false, true} {
+		var buf bytes.Buffer
+		fw := NewFrameWriter(&buf)
+		fw.Checksum = checksum
+		if err := fw.Encode(frameString("hello")); err != nil {
+			t.Fatal(err)
+		}
+		if err := fw.Encode(Raw(AppendInt(nil, 42))); err != nil {
+			t.Fatal(err)
+		}
+		if err := fw.WriteFrame(AppendString(nil, "skipped")); err != nil {
+			t.Fatal(err)
+		}
+		if err := fw.Encode(frameString("world")); err != nil {
+			t.Fatal(err)
+		}
+
+		fr := NewFrameReader(&buf)
+		var s frameString
+		if err := fr.ReadFrame(&s); err != nil || s != "hello" {
+			t.Errorf("checksum=%v: read %q, %v", checksum, s, err)
+		}
+		p, err := fr.NextFrame()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n, _, err := ReadIntBytes(p); n != 42 || err != nil {
+			t.Errorf("checksum=%v: read %d, %v", checksum, n, err)
+		}
+		if err = fr.SkipFrame(); err != nil {
+			t.Fatal(err)
+		}
+		var r Raw
+		if err = fr.ReadFrame(&r); err != nil {
+			t.Fatal(err)
+		}
+		if s, _, _ := ReadStringBytes(r); s != "world" {
+			t.Errorf("checksum=%v: read %q", checksum, s)
+		}
+		if err = fr.SkipFrame(); err != io.EOF {
+			t.Errorf("checksum=%v: got %v at the end of the stream", checksum, err)
+		}
+	}
+}
+
+func TestFrameChecksum(t *testing.T) {
+	var buf bytes.Buffer
+	fw := NewFrameWriter(&buf)
+	fw.Checksum = true
+	fw.Encode(frameString("one"))
+	fw.Encode(frameString("two"))
+	b := buf.Bytes()
+	b[len(b)/2-1] ^= 0xff // corrupt the first frame
+
+	fr := NewFrameReader(bytes.NewReader(b))
+	var s frameString
+	err := fr.ReadFrame(&s)
+	if _, ok := err.(ChecksumError); !ok {
+		t.Fatalf("got %v; want a ChecksumError", err)
+	}
+	if err = fr.ReadFrame(&s); err != nil || s != "two" {
+		t.Errorf("after a checksum error, read %q, %v", s, err)
+	}
+}
+
+func TestFrameMaxSize(t *testing.T) {
+	var buf bytes.Buffer
+	fw := NewFrameWriter(&buf)
+	fw.MaxSize = 8
+	if err := fw.Encode(frameString("far too long")); err != (FrameSizeError{Size: 13, Max: 8}) {
+		t.Errorf("got %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("%d bytes were written", buf.Len())
+	}
+	fw.MaxSize = 0
+	fw.Encode(frameString("far too long"))
+	fw.Encode(frameString("short"))
+
+	fr := NewFrameReader(&buf)
+	fr.MaxSize = 8
+	var s frameString
+	if err := fr.ReadFrame(&s); err != (FrameSizeError{Size: 13, Max: 8}) {
+		t.Errorf("got %v", err)
+	}
+	if err := fr.ReadFrame(&s); err != nil || s != "short" {
+		t.Errorf("after a size error, read %q, %v", s, err)
+	}
+
+	// limits that don't fit in a header are
+	// clamped, not replaced by the default
+	if max := maxFrameSize(int(^uint(0) >> 1)); max != 1<<31-1 {
+		t.Errorf("a limit above 1<<31-1 is %d", max)
+	}
+}
+
+func TestFrameTruncated(t *testing.T) {
+	var buf bytes.Buffer
+	fw := NewFrameWriter(&buf)
+	fw.Encode(frameString("complete"))
+	fw.Encode(frameString("cut off"))
+	b := buf.Bytes()
+
+	for _, cut := range []int{2, 6} {
+		fr := NewFrameReader(bytes.NewReader(b[:len(b)-cut]))
+		if _, err := fr.NextFrame(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := fr.NextFrame(); err != io.ErrUnexpectedEOF {
+			t.Errorf("cut %d: got %v; want io.ErrUnexpectedEOF", cut, err)
+		}
+	}
+	fr := NewFrameReader(bytes.NewReader(b[:len(b)-10]))
+	fr.NextFrame()
+	if _, err := fr.NextFrame(); err != io.ErrUnexpectedEOF {
+		t.Errorf("partial header: got %v; want io.ErrUnexpectedEOF", err)
+	}
+}
+
+func TestFrameChecksumLength(t *testing.T) {
+	var buf bytes.Buffer
+	fw := NewFrameWriter(&buf)
+	fw.Checksum = true
+	fw.Encode(frameString("one"))
+	fw.Encode(frameString("two"))
+	b := buf.Bytes()
+	b[3]-- // the first frame is one byte shorter
+
+	fr := NewFrameReader(bytes.NewReader(b))
+	if _, err := fr.NextFrame(); err == nil {
+		t.Fatal("read a frame with a corrupted length")
+	} else if _, ok := err.(ChecksumError); !ok {
+		t.Fatalf("got %v; want a ChecksumError", err)
+	}
+}
+
+func TestFrameSkipPastEnd(t *testing.T) {
+	var buf bytes.Buffer
+	fw := NewFrameWriter(&buf)
+	fw.Encode(frameString("cut off"))
+	b := buf.Bytes()
+
+	// bytes.Reader is an io.Seeker
+	fr := NewFrameReader(bytes.NewReader(b[:len(b)-1]))
+	if err := fr.SkipFrame(); err != io.ErrUnexpectedEOF {
+		t.Errorf("got %v; want io.ErrUnexpectedEOF", err)
+	}
+	fr = NewFrameReader(bytes.NewReader(b))
+	if err := fr.SkipFrame(); err != nil {
+		t.Fatal(err)
+	}
+	if err := fr.SkipFrame(); err != io.EOF {
+		t.Errorf("got %v at the end of the stream", err)
+	}
+}
@@ -0,0 +1,110 @@
+package msgp
+
+import (
+	"sync"
+)
+
+// Interner deduplicates decoded strings. Once a
+// string has been interned, reading the same bytes
+// again returns the stored string instead of
+// allocating a new one. This is useful for data
+// with many repeated values, such as enum-like
+// fields and map keys.
+//
+// An Interner is safe for concurrent use, so it
+// can be shared between Readers.
+type Interner struct {
+	mu  sync.RWMutex
+	m   map[string]string
+	max int
+}
+
+// NewInterner returns a new Interner that holds
+// at most 'max' distinct strings. Once it is full,
+// strings that are not already interned are
+// returned as ordinary copies. If 'max' is
+// less than or equal to zero, the Interner
+// is unbounded.
+func NewInterner(max int) *Interner {
+	return &Interner{m: make(map[string]string), max: max}
+}
+
+// Intern returns a string with the contents of 'b',
+// which may be shared with previous calls to Intern.
+// 'b' is not retained.
+func (in *Interner) Intern(b []byte) string {
+	in.mu.RLock()
+	s, ok := in.m[string(b)] // does not allocate
+	in.mu.RUnlock()
+	if ok {
+		return s
+	}
+	s = string(b)
+	in.mu.Lock()
+	if old, ok := in.m[s]; ok {
+		s = old
+	} else if in.max <= 0 || len(in.m) < in.max {
+		in.m[s] = s
+	}
+	in.mu.Unlock()
+	return s
+}
+
+// Len returns the number of interned strings.
+func (in *Interner) Len() int {
+	in.mu.RLock()
+	n := len(in.m)
+	in.mu.RUnlock()
+	return n
+}
+
+// Reset discards all of the interned strings.
+func (in *Interner) Reset() {
+	in.mu.Lock()
+	in.m = make(map[string]string)
+	in.mu.Unlock()
+}
+
+// InternUnmarshaler is implemented by the types
+// listed in a //msgp:intern directive. Their
+// generated UnmarshalMsgWithInterner method reads
+// strings and map keys through 'in', and passes
+// it on to the types they contain.
+type InternUnmarshaler interface {
+	UnmarshalMsgWithInterner(bts []byte, in *Interner) ([]byte, error)
+}
+
+// Unmarshal unmarshals 'u' from 'b' through its
+// UnmarshalMsgWithInterner method if it has one,
+// or otherwise through UnmarshalMsg, and returns
+// the remaining bytes. A nil Interner doesn't intern.
+func (in *Interner) Unmarshal(b []byte, u Unmarshaler) ([]byte, error) {
+	if iu, ok := u.(InternUnmarshaler); ok {
+		return iu.UnmarshalMsgWithInterner(b, in)
+	}
+	return u.UnmarshalMsg(b)
+}
+
+// ReadStringBytes reads a 'str' object from 'b'
+// like ReadStringBytes, but interns the result.
+// The generated UnmarshalMsgWithInterner methods
+// use it. A nil Interner doesn't intern.
+func (in *Interner) ReadStringBytes(b []byte) (string, []byte, error) {
+	if in == nil {
+		return ReadStringBytes(b)
+	}
+	v, o, err := ReadStringZC(b)
+	if err != nil {
+		return "", o, err
+	}
+	return in.Intern(v), o, nil
+}
+
+// SetInterner sets the Interner used by the
+// Reader for strings returned by ReadString, which
+// the generated DecodeMsg methods use for string
+// values and map keys. Passing nil disables interning.
+//
+// Strings longer than the Reader's buffer size
+// are never interned.
+func (m *Reader) SetInterner(in *Interner) { m.intern = in }
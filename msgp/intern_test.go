@@ -0,0 +1,81 @@
+package msgp
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestInterner(t *testing.T) {
+	in := NewInterner(2)
+	a := in.Intern([]byte("hello"))
+	b := in.Intern([]byte("hello"))
+	if a != "hello" || b != "hello" {
+		t.Fatalf("got %q and %q", a, b)
+	}
+	if in.Len() != 1 {
+		t.Errorf("expected 1 interned string; got %d", in.Len())
+	}
+	in.Intern([]byte("world"))
+	if s := in.Intern([]byte("full")); s != "full" {
+		t.Errorf("got %q", s)
+	}
+	if in.Len() != 2 {
+		t.Errorf("expected 2 interned strings; got %d", in.Len())
+	}
+	in.Reset()
+	if in.Len() != 0 {
+		t.Errorf("expected 0 interned strings after Reset; got %d", in.Len())
+	}
+}
+
+func TestReaderInterner(t *testing.T) {
+	var buf bytes.Buffer
+	wr := NewWriter(&buf)
+	// AllocsPerRun calls the function once more to warm up
+	for i := 0; i < 200; i++ {
+		wr.WriteString("repeated")
+	}
+	wr.WriteString("")
+	wr.Flush()
+
+	in := NewInterner(0)
+	rd := NewReader(bytes.NewReader(buf.Bytes()))
+	rd.SetInterner(in)
+	allocs := testing.AllocsPerRun(1, func() {
+		for i := 0; i < 100; i++ {
+			s, err := rd.ReadString()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s != "repeated" {
+				t.Fatalf("got %q", s)
+			}
+		}
+	})
+	if allocs > 1 {
+		t.Errorf("expected at most 1 allocation; got %v", allocs)
+	}
+	if s, err := rd.ReadString(); err != nil || s != "" {
+		t.Errorf("got %q, %v", s, err)
+	}
+	if in.Len() != 1 {
+		t.Errorf("expected 1 interned string; got %d", in.Len())
+	}
+}
+
+func TestInternerReadStringBytes(t *testing.T) {
+	in := NewInterner(0)
+	b := AppendString(nil, "key")
+	b = AppendString(b, "key")
+	s, o, err := in.ReadStringBytes(b)
+	if err != nil || s != "key" {
+		t.Fatalf("got %q, %v", s, err)
+	}
+	s, o, err = in.ReadStringBytes(o)
+	if err != nil || s != "key" || len(o) != 0 {
+		t.Fatalf("got %q, %v, %d bytes left", s, err, len(o))
+	}
+	if _, _, err = in.ReadStringBytes(AppendInt(nil, 1)); err == nil {
+		t.Error("expected an error")
+	}
+}
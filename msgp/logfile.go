@@ -0,0 +1,265 @@
+package msgp
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+)
+
+// A LogFile is a sequence of records in the same
+// format as frames written by a FrameWriter with
+// Checksum set, so it can also be read as a stream
+// with a FrameReader. Records are numbered from
+// zero in the order they were appended, and are
+// read at random through a memory mapping of the
+// file where the platform supports it.
+
+// ErrNoRecord is returned when reading a LogFile
+// at a sequence number past the last record, or
+// at an offset that isn't the start of a record.
+var ErrNoRecord = errors.New("msgp: no record at that position in the log")
+
+// LogCorruptError is returned by Recover (and
+// OpenLogFile) when a record that isn't at the end
+// of the file is damaged. That isn't left behind by
+// an interrupted Append, so nothing is truncated:
+// to drop the damaged record and everything after
+// it, truncate the file to Offset and recover again.
+type LogCorruptError struct {
+	Offset int64 // the offset of the damaged record
+	Size   int64 // the size of the file
+}
+
+// Error implements the error interface
+func (e LogCorruptError) Error() string {
+	return fmt.Sprintf("msgp: damaged log record at offset %d of %d", e.Offset, e.Size)
+}
+
+// LogFile is an append-only log of records.
+// It is safe for concurrent use.
+//
+// Records are unmarshaled from the mapping of the
+// file, so values that keep references to their
+// source (e.g. fields tagged `msg:",zerocopy"`)
+// are only valid until Close. (Mappings that are
+// replaced by larger ones as the log grows are
+// kept until then.)
+type LogFile struct {
+	mu      sync.RWMutex
+	file    *os.File
+	offsets []int64 // the offset of each record
+	size    int64   // the end of the last record
+	scratch []byte
+	data    []byte   // the mapping, if any
+	old     [][]byte // replaced mappings
+}
+
+// OpenLogFile opens the log in 'file', which must be
+// open for reading and writing. It reads through the
+// file to build an index of its records, and truncates
+// an incomplete or damaged last record, which is what
+// an interrupted Append leaves behind (see Recover).
+func OpenLogFile(file *os.File) (*LogFile, error) {
+	l := &LogFile{file: file}
+	if _, err := l.Recover(); err != nil {
+		l.unmap()
+		return nil, err
+	}
+	return l, nil
+}
+
+// Recover rebuilds the index of the log from the
+// file, and truncates an incomplete last record or
+// one with a bad checksum. It returns the number of
+// bytes truncated. If a record before the last one
+// is damaged, it returns a LogCorruptError, and
+// only the records before it are indexed. (Appends
+// are then written after the end of the file.)
+func (l *LogFile) Recover() (int64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	stat, err := l.file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	end := stat.Size()
+	if err = l.mapTo(end); err != nil {
+		return 0, err
+	}
+	l.offsets = l.offsets[:0]
+	var off int64
+	for off+8 <= end {
+		hdr, err := l.view(off, 8)
+		if err != nil {
+			return 0, err
+		}
+		n := binary.BigEndian.Uint32(hdr)
+		next := off + 8 + int64(n&^frameChecksumBit)
+		if next > end {
+			break
+		}
+		p, err := l.view(off+8, int(next-off-8))
+		if err != nil {
+			return 0, err
+		}
+		if n&frameChecksumBit == 0 || frameChecksum(hdr, p) != binary.BigEndian.Uint32(hdr[4:]) {
+			if next == end {
+				break
+			}
+			l.size = end
+			return 0, LogCorruptError{Offset: off, Size: end}
+		}
+		l.offsets = append(l.offsets, off)
+		off = next
+	}
+	l.size = off
+	if off == end {
+		return 0, nil
+	}
+	return end - off, l.file.Truncate(off)
+}
+
+// Append appends 'm' as a record and
+// returns its sequence number.
+func (l *LogFile) Append(m Marshaler) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	b, err := m.MarshalMsg(append(l.scratch[:0], blankHeader[:]...))
+	if err != nil {
+		return 0, err
+	}
+	l.scratch = b
+	sz := len(b) - 8
+	if sz > DefaultMaxFrameSize {
+		return 0, FrameSizeError{Size: sz, Max: DefaultMaxFrameSize}
+	}
+	binary.BigEndian.PutUint32(b, uint32(sz)|frameChecksumBit)
+	binary.BigEndian.PutUint32(b[4:], frameChecksum(b, b[8:]))
+	if _, err = l.file.WriteAt(b, l.size); err == nil {
+		err = l.mapTo(l.size + int64(len(b)))
+	}
+	if err != nil {
+		// drop what was written of the record, so that
+		// a shorter one appended next doesn't leave the
+		// rest of it behind
+		l.file.Truncate(l.size)
+		return 0, err
+	}
+	l.offsets = append(l.offsets, l.size)
+	l.size += int64(len(b))
+	return len(l.offsets) - 1, nil
+}
+
+// Len returns the number of records.
+func (l *LogFile) Len() int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return len(l.offsets)
+}
+
+// Size returns the size of the log in bytes.
+func (l *LogFile) Size() int64 {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.size
+}
+
+// Offset returns the offset in the file
+// of the record numbered 'seq'.
+func (l *LogFile) Offset(seq int) (int64, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if seq < 0 || seq >= len(l.offsets) {
+		return 0, ErrNoRecord
+	}
+	return l.offsets[seq], nil
+}
+
+// Read unmarshals the record numbered 'seq' into 'dst'.
+func (l *LogFile) Read(seq int, dst Unmarshaler) error {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if seq < 0 || seq >= len(l.offsets) {
+		return ErrNoRecord
+	}
+	return l.read(l.offsets[seq], dst)
+}
+
+// ReadOffset unmarshals the record at the offset
+// 'off' in the file (see Offset) into 'dst'.
+func (l *LogFile) ReadOffset(off int64, dst Unmarshaler) error {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	i := sort.Search(len(l.offsets), func(i int) bool { return l.offsets[i] >= off })
+	if i == len(l.offsets) || l.offsets[i] != off {
+		return ErrNoRecord
+	}
+	return l.read(off, dst)
+}
+
+// read unmarshals the record at 'off', which
+// is known to be the start of a valid record
+func (l *LogFile) read(off int64, dst Unmarshaler) error {
+	hdr, err := l.view(off, 4)
+	if err != nil {
+		return err
+	}
+	sz := int(binary.BigEndian.Uint32(hdr) &^ frameChecksumBit)
+	p, err := l.view(off+8, sz)
+	if err != nil {
+		return err
+	}
+	_, err = dst.UnmarshalMsg(p)
+	return err
+}
+
+// Iter returns a LogIter that reads the
+// records from the one numbered 'seq'.
+func (l *LogFile) Iter(seq int) *LogIter {
+	return &LogIter{l: l, seq: seq}
+}
+
+// Sync commits the log to stable storage.
+func (l *LogFile) Sync() error {
+	return l.file.Sync()
+}
+
+// Close unmaps and closes the file.
+func (l *LogFile) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	err := l.unmap()
+	if cerr := l.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// LogIter reads the records of a LogFile
+// in order. Records appended while
+// iterating are read too.
+type LogIter struct {
+	l   *LogFile
+	seq int
+}
+
+// Seq returns the sequence number
+// of the next record.
+func (it *LogIter) Seq() int { return it.seq }
+
+// Next unmarshals the next record into 'dst'. It
+// returns io.EOF after the last record. After an
+// error from UnmarshalMsg, the next call reads
+// the following record.
+func (it *LogIter) Next(dst Unmarshaler) error {
+	err := it.l.Read(it.seq, dst)
+	if err == ErrNoRecord {
+		return io.EOF
+	}
+	it.seq++
+	return err
+}
@@ -0,0 +1,55 @@
+package msgp
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+	"testing"
+)
+
+func TestLogFileFailedAppend(t *testing.T) {
+	name := tempLogName(t)
+	defer os.Remove(name)
+	l := openTestLog(t, name)
+
+	for i := 0; i < 3; i++ {
+		if _, err := l.Append(logRecord(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// limit the size of files, so that
+	// the next record is partly written
+	var lim syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_FSIZE, &lim); err != nil {
+		t.Fatal(err)
+	}
+	signal.Ignore(syscall.SIGXFSZ)
+	defer signal.Reset(syscall.SIGXFSZ)
+	short := lim
+	short.Cur = uint64(l.Size()) + 40
+	if err := syscall.Setrlimit(syscall.RLIMIT_FSIZE, &short); err != nil {
+		t.Skip("can't limit the size of files:", err)
+	}
+	// after the shorter record below, what is left of
+	// this one reads as a header of a short record
+	long := make(Raw, 100)
+	copy(long[9:], []byte{0x80, 0, 0, 1})
+	_, err := l.Append(long)
+	if err := syscall.Setrlimit(syscall.RLIMIT_FSIZE, &lim); err != nil {
+		t.Fatal(err)
+	}
+	if err == nil {
+		t.Fatal("the append didn't fail")
+	}
+
+	// a shorter record leaves nothing
+	// of the failed one behind
+	if _, err = l.Append(logRecord(3)); err != nil {
+		t.Fatal(err)
+	}
+	l.Close()
+	l = openTestLog(t, name)
+	defer l.Close()
+	checkLog(t, l, 4)
+}
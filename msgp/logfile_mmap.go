@@ -0,0 +1,63 @@
+// +build linux darwin dragonfly freebsd netbsd openbsd
+// +build !appengine
+
+package msgp
+
+import (
+	"syscall"
+)
+
+// the smallest mapping of a LogFile
+const minLogMapping = 1 << 20
+
+// mapTo makes sure that the first 'size' bytes of
+// the file are mapped. The mapping is grown to
+// twice its size, so that appends rarely remap.
+// (Pages of the mapping past the end of the file
+// are never read.) The old mapping is kept until
+// Close, since records may still refer to it.
+func (l *LogFile) mapTo(size int64) error {
+	if size <= int64(len(l.data)) {
+		return nil
+	}
+	n := int64(len(l.data)) * 2
+	if n < minLogMapping {
+		n = minLogMapping
+	}
+	for n < size {
+		n *= 2
+	}
+	data, err := syscall.Mmap(int(l.file.Fd()), 0, int(n), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return err
+	}
+	adviseRead(data)
+	if l.data != nil {
+		l.old = append(l.old, l.data)
+	}
+	l.data = data
+	return nil
+}
+
+// view returns 'n' bytes at the offset 'off'
+func (l *LogFile) view(off int64, n int) ([]byte, error) {
+	if off+int64(n) > int64(len(l.data)) {
+		return nil, ErrShortBytes
+	}
+	return l.data[off : off+int64(n) : off+int64(n)], nil
+}
+
+// unmap unmaps the mapping and the ones it replaced
+func (l *LogFile) unmap() error {
+	var err error
+	for _, data := range append(l.old, l.data) {
+		if data == nil {
+			continue
+		}
+		if merr := syscall.Munmap(data); err == nil {
+			err = merr
+		}
+	}
+	l.data, l.old = nil, nil
+	return err
+}
@@ -0,0 +1,26 @@
+// +build windows appengine
+
+package msgp
+
+import (
+	"io"
+)
+
+// LogFiles aren't mapped on these platforms,
+// so records are read with ReadAt.
+
+func (l *LogFile) mapTo(size int64) error { return nil }
+
+// view returns 'n' bytes at the offset 'off'
+func (l *LogFile) view(off int64, n int) ([]byte, error) {
+	b := make([]byte, n)
+	if _, err := l.file.ReadAt(b, off); err != nil {
+		if err == io.EOF {
+			err = ErrShortBytes
+		}
+		return nil, err
+	}
+	return b, nil
+}
+
+func (l *LogFile) unmap() error { return nil }
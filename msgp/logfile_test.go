@@ -0,0 +1,283 @@
+package msgp
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+)
+
+func logRecord(i int) Raw {
+	return Raw(AppendString(nil, fmt.Sprintf("record %d", i)))
+}
+
+func openTestLog(t *testing.T, name string) *LogFile {
+	f, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := OpenLogFile(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+func tempLogName(t *testing.T) string {
+	f, err := ioutil.TempFile("", "msgp-log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	return f.Name()
+}
+
+func checkLog(t *testing.T, l *LogFile, n int) {
+	if l.Len() != n {
+		t.Fatalf("log has %d records; want %d", l.Len(), n)
+	}
+	var r Raw
+	for i := 0; i < n; i++ {
+		if err := l.Read(i, &r); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(r, logRecord(i)) {
+			t.Fatalf("record %d is %q", i, []byte(r))
+		}
+	}
+}
+
+func TestLogFile(t *testing.T) {
+	name := tempLogName(t)
+	defer os.Remove(name)
+
+	l := openTestLog(t, name)
+	const n = 5000
+	for i := 0; i < n; i++ {
+		seq, err := l.Append(logRecord(i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if seq != i {
+			t.Fatalf("Append returned %d; want %d", seq, i)
+		}
+	}
+	checkLog(t, l, n)
+
+	var r Raw
+	off, err := l.Offset(1234)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = l.ReadOffset(off, &r); err != nil || !bytes.Equal(r, logRecord(1234)) {
+		t.Errorf("ReadOffset returned %q, %v", []byte(r), err)
+	}
+	if err = l.ReadOffset(off+1, &r); err != ErrNoRecord {
+		t.Errorf("ReadOffset in a record returned %v", err)
+	}
+	if err = l.Read(n, &r); err != ErrNoRecord {
+		t.Errorf("Read past the end returned %v", err)
+	}
+
+	it := l.Iter(n - 2)
+	for i := n - 2; i < n; i++ {
+		if err = it.Next(&r); err != nil || !bytes.Equal(r, logRecord(i)) {
+			t.Fatalf("Next returned %q, %v", []byte(r), err)
+		}
+	}
+	if err = it.Next(&r); err != io.EOF {
+		t.Errorf("Next at the end returned %v", err)
+	}
+	if _, err = l.Append(logRecord(n)); err != nil {
+		t.Fatal(err)
+	}
+	if err = it.Next(&r); err != nil || !bytes.Equal(r, logRecord(n)) {
+		t.Errorf("Next after Append returned %q, %v", []byte(r), err)
+	}
+
+	size := l.Size()
+	if err = l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the index is rebuilt on open
+	l = openTestLog(t, name)
+	checkLog(t, l, n+1)
+	if l.Size() != size {
+		t.Errorf("size is %d after reopening; want %d", l.Size(), size)
+	}
+	l.Close()
+
+	// the log is a stream of frames
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	fr := NewFrameReader(f)
+	for i := 0; i <= n; i++ {
+		if err = fr.ReadFrame(&r); err != nil || !bytes.Equal(r, logRecord(i)) {
+			t.Fatalf("frame %d is %q, %v", i, []byte(r), err)
+		}
+	}
+}
+
+func TestLogFileRecover(t *testing.T) {
+	name := tempLogName(t)
+	defer os.Remove(name)
+
+	l := openTestLog(t, name)
+	for i := 0; i < 10; i++ {
+		if _, err := l.Append(logRecord(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	off, _ := l.Offset(8)
+	size := l.Size()
+	l.Close()
+
+	// a torn write of the last record
+	if err := os.Truncate(name, size-3); err != nil {
+		t.Fatal(err)
+	}
+	l = openTestLog(t, name)
+	checkLog(t, l, 9)
+	l.Close()
+
+	// a damaged last record
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[off+10] ^= 0xff
+	if err = ioutil.WriteFile(name, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	l = openTestLog(t, name)
+	checkLog(t, l, 8)
+	if l.Size() != off {
+		t.Errorf("size is %d; want %d", l.Size(), off)
+	}
+	if st, _ := os.Stat(name); st.Size() != off {
+		t.Errorf("file is %d bytes; want %d", st.Size(), off)
+	}
+
+	// appends continue after the last good record
+	if _, err = l.Append(logRecord(8)); err != nil {
+		t.Fatal(err)
+	}
+	checkLog(t, l, 9)
+	if n, err := l.Recover(); n != 0 || err != nil {
+		t.Errorf("Recover returned %d, %v", n, err)
+	}
+	checkLog(t, l, 9)
+	off, _ = l.Offset(5)
+	size = l.Size()
+	l.Close()
+
+	// a damaged record with records after it
+	// isn't truncated
+	data, err = ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[off+10] ^= 0xff
+	if err = ioutil.WriteFile(name, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.OpenFile(name, os.O_RDWR, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = OpenLogFile(f)
+	if err != (LogCorruptError{Offset: off, Size: size}) {
+		t.Fatalf("got error %v", err)
+	}
+	f.Close()
+	if st, _ := os.Stat(name); st.Size() != size {
+		t.Errorf("file is %d bytes; want %d", st.Size(), size)
+	}
+
+	// until the caller truncates it
+	if err = os.Truncate(name, off); err != nil {
+		t.Fatal(err)
+	}
+	l = openTestLog(t, name)
+	defer l.Close()
+	checkLog(t, l, 5)
+}
+
+// aliasRecord keeps a reference to
+// the bytes it is unmarshaled from
+type aliasRecord struct {
+	b []byte
+}
+
+func (a *aliasRecord) UnmarshalMsg(b []byte) ([]byte, error) {
+	a.b = b
+	return nil, nil
+}
+
+func TestLogFileAliasing(t *testing.T) {
+	name := tempLogName(t)
+	defer os.Remove(name)
+	l := openTestLog(t, name)
+	defer l.Close()
+
+	if _, err := l.Append(logRecord(0)); err != nil {
+		t.Fatal(err)
+	}
+	var a aliasRecord
+	if err := l.Read(0, &a); err != nil {
+		t.Fatal(err)
+	}
+	// grow the log past its first mapping
+	big := Raw(AppendBytes(nil, make([]byte, 64<<10)))
+	for l.Size() < 4<<20 {
+		if _, err := l.Append(big); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !bytes.Equal(a.b, logRecord(0)) {
+		t.Errorf("record is %q", a.b)
+	}
+}
+
+func TestLogFileConcurrent(t *testing.T) {
+	name := tempLogName(t)
+	defer os.Remove(name)
+	l := openTestLog(t, name)
+	defer l.Close()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 2000; i++ {
+			if _, err := l.Append(logRecord(i)); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		var r Raw
+		it := l.Iter(0)
+		for it.Seq() < 2000 {
+			i := it.Seq()
+			err := it.Next(&r)
+			if err == io.EOF {
+				continue
+			}
+			if err != nil || !bytes.Equal(r, logRecord(i)) {
+				t.Errorf("record %d is %q, %v", i, []byte(r), err)
+				return
+			}
+		}
+	}()
+	wg.Wait()
+}
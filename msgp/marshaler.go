@@ -0,0 +1,133 @@
+package msgp
+
+import (
+	"encoding"
+)
+
+// The functions in this file encode values through
+// the interfaces in package encoding. The generated
+// code uses them for fields tagged `msg:",binary"` or
+// `msg:",text"`. BinaryMarshaler output is written
+// as 'bin', and TextMarshaler output as 'str'.
+
+// MarshaledSizer is implemented by types that can
+// report the length of their MarshalBinary or
+// MarshalText output without producing it.
+type MarshaledSizer interface {
+	// MarshaledSize returns an upper bound
+	// on the length of the marshaled data.
+	MarshaledSize() int
+}
+
+// ReadBinaryUnmarshaler reads a 'bin' object
+// from the reader and passes it to u.UnmarshalBinary.
+func (m *Reader) ReadBinaryUnmarshaler(u encoding.BinaryUnmarshaler) error {
+	b, err := m.ReadBytes(m.scratch[:0])
+	if err != nil {
+		return err
+	}
+	m.scratch = b
+	return u.UnmarshalBinary(b)
+}
+
+// ReadBinaryUnmarshalerBytes reads a 'bin' object
+// from 'b', passes it to u.UnmarshalBinary and
+// returns the remaining bytes.
+func ReadBinaryUnmarshalerBytes(b []byte, u encoding.BinaryUnmarshaler) ([]byte, error) {
+	v, o, err := ReadBytesZC(b)
+	if err != nil {
+		return b, err
+	}
+	if err = u.UnmarshalBinary(v); err != nil {
+		return b, err
+	}
+	return o, nil
+}
+
+// WriteBinaryMarshaler writes the output
+// of m.MarshalBinary to the writer as 'bin'.
+func (mw *Writer) WriteBinaryMarshaler(m encoding.BinaryMarshaler) error {
+	data, err := m.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	return mw.WriteBytes(data)
+}
+
+// AppendBinaryMarshaler appends the output
+// of m.MarshalBinary to the slice as 'bin'.
+func AppendBinaryMarshaler(b []byte, m encoding.BinaryMarshaler) ([]byte, error) {
+	data, err := m.MarshalBinary()
+	if err != nil {
+		return b, err
+	}
+	return AppendBytes(b, data), nil
+}
+
+// BinaryMarshalerSize returns the encoded size of 'm'
+// if it implements MarshaledSizer. Otherwise it returns
+// only BytesPrefixSize, as finding the size would mean
+// calling m.MarshalBinary.
+func BinaryMarshalerSize(m encoding.BinaryMarshaler) int {
+	return BytesPrefixSize + marshaledSize(m)
+}
+
+// ReadTextUnmarshaler reads a 'str' object
+// from the reader and passes it to u.UnmarshalText.
+func (m *Reader) ReadTextUnmarshaler(u encoding.TextUnmarshaler) error {
+	b, err := m.ReadStringAsBytes(m.scratch[:0])
+	if err != nil {
+		return err
+	}
+	m.scratch = b
+	return u.UnmarshalText(b)
+}
+
+// ReadTextUnmarshalerBytes reads a 'str' object
+// from 'b', passes it to u.UnmarshalText and
+// returns the remaining bytes.
+func ReadTextUnmarshalerBytes(b []byte, u encoding.TextUnmarshaler) ([]byte, error) {
+	v, o, err := ReadStringZC(b)
+	if err != nil {
+		return b, err
+	}
+	if err = u.UnmarshalText(v); err != nil {
+		return b, err
+	}
+	return o, nil
+}
+
+// WriteTextMarshaler writes the output
+// of m.MarshalText to the writer as 'str'.
+func (mw *Writer) WriteTextMarshaler(m encoding.TextMarshaler) error {
+	data, err := m.MarshalText()
+	if err != nil {
+		return err
+	}
+	return mw.WriteStringFromBytes(data)
+}
+
+// AppendTextMarshaler appends the output
+// of m.MarshalText to the slice as 'str'.
+func AppendTextMarshaler(b []byte, m encoding.TextMarshaler) ([]byte, error) {
+	data, err := m.MarshalText()
+	if err != nil {
+		return b, err
+	}
+	return AppendStringFromBytes(b, data), nil
+}
+
+// TextMarshalerSize returns the encoded size of 'm'
+// if it implements MarshaledSizer. Otherwise it returns
+// only StringPrefixSize, as finding the size would mean
+// calling m.MarshalText.
+func TextMarshalerSize(m encoding.TextMarshaler) int {
+	return StringPrefixSize + marshaledSize(m)
+}
+
+func marshaledSize(m interface{}) int {
+	if s, ok := m.(MarshaledSizer); ok {
+		return s.MarshaledSize()
+	}
+	return 0
+}
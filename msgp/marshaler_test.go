@@ -0,0 +1,51 @@
+package msgp
+
+import (
+	"testing"
+)
+
+type countingMarshaler struct {
+	calls int
+}
+
+func (c *countingMarshaler) MarshalBinary() ([]byte, error) {
+	c.calls++
+	return []byte("abc"), nil
+}
+
+func (c *countingMarshaler) MarshalText() ([]byte, error) {
+	c.calls++
+	return []byte("abc"), nil
+}
+
+type sizedMarshaler struct{ countingMarshaler }
+
+func (s *sizedMarshaler) MarshaledSize() int { return 3 }
+
+func TestMarshalerSize(t *testing.T) {
+	var c countingMarshaler
+	if n := BinaryMarshalerSize(&c); n != BytesPrefixSize {
+		t.Errorf("BinaryMarshalerSize = %d; want %d", n, BytesPrefixSize)
+	}
+	if n := TextMarshalerSize(&c); n != StringPrefixSize {
+		t.Errorf("TextMarshalerSize = %d; want %d", n, StringPrefixSize)
+	}
+	if c.calls != 0 {
+		t.Errorf("the size functions marshaled %d times", c.calls)
+	}
+
+	var s sizedMarshaler
+	if n := BinaryMarshalerSize(&s); n != BytesPrefixSize+3 {
+		t.Errorf("BinaryMarshalerSize = %d; want %d", n, BytesPrefixSize+3)
+	}
+	if n := TextMarshalerSize(&s); n != StringPrefixSize+3 {
+		t.Errorf("TextMarshalerSize = %d; want %d", n, StringPrefixSize+3)
+	}
+	if s.calls != 0 {
+		t.Errorf("the size functions marshaled %d times", s.calls)
+	}
+	b, _ := AppendTextMarshaler(nil, &s)
+	if len(b) > TextMarshalerSize(&s) {
+		t.Errorf("encoded %d bytes; TextMarshalerSize is %d", len(b), TextMarshalerSize(&s))
+	}
+}
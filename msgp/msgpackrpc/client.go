@@ -0,0 +1,265 @@
+package msgpackrpc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/bytedance/msgp/msgp"
+)
+
+// response is the error and result of a call
+type response struct {
+	err    msgp.Raw
+	result msgp.Raw
+}
+
+// Client makes calls and sends notifications over a
+// connection. It is safe for concurrent use, and calls
+// are pipelined: each call is written as soon as it is
+// made, and responses are matched to calls as they
+// arrive, in any order.
+type Client struct {
+	rwc io.ReadWriteCloser
+
+	enc *encoder
+
+	mu      sync.Mutex // guards the fields below
+	seq     uint32
+	pending map[uint32]chan response
+	err     error // set when the connection fails
+
+	notify func(method string, params msgp.Raw)
+}
+
+// NewClient returns a Client that uses 'rwc', which
+// it reads responses from in its own goroutine.
+// Notifications sent by the peer are passed to
+// 'notify' in that goroutine, or dropped if it is nil.
+func NewClient(rwc io.ReadWriteCloser, notify func(method string, params msgp.Raw)) *Client {
+	c := &Client{
+		rwc:     rwc,
+		enc:     newEncoder(rwc),
+		pending: make(map[uint32]chan response),
+		notify:  notify,
+	}
+	go c.read()
+	return c
+}
+
+// Call calls the method 'method' with the params array
+// 'params' (see Notify) and decodes the result into
+// 'result', which must be a msgp.Decodable, an
+// *interface{} or nil. It returns an *Error if the peer
+// returns an error, or ctx.Err() if 'ctx' is done
+// before the response arrives.
+func (c *Client) Call(ctx context.Context, method string, params interface{}, result interface{}) error {
+	ch := make(chan response, 1)
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return c.err
+	}
+	c.seq++
+	msgid := c.seq
+	c.pending[msgid] = ch
+	c.mu.Unlock()
+
+	err := c.write(func(w *msgp.Writer) error {
+		err := w.WriteArrayHeader(4)
+		if err == nil {
+			err = w.WriteInt(typeRequest)
+		}
+		if err == nil {
+			err = w.WriteUint32(msgid)
+		}
+		if err == nil {
+			err = w.WriteString(method)
+		}
+		if err == nil {
+			err = writeParams(w, params)
+		}
+		return err
+	})
+	if err != nil {
+		c.forget(msgid)
+		return err
+	}
+
+	select {
+	case res, ok := <-ch:
+		if !ok {
+			return c.failure()
+		}
+		if len(res.err) > 0 {
+			v, _, err := msgp.ReadIntfBytes(res.err)
+			if err != nil {
+				return err
+			}
+			return &Error{Value: v}
+		}
+		return decodeRaw(res.result, result)
+	case <-ctx.Done():
+		c.forget(msgid)
+		return ctx.Err()
+	}
+}
+
+// Notify sends a notification for the method 'method'
+// with the params array 'params', which is written with
+// its EncodeMsg method if it has one (e.g. a generated
+// tuple type), or otherwise with WriteIntf (e.g. a
+// []interface{}). A nil 'params' is an empty array.
+func (c *Client) Notify(method string, params interface{}) error {
+	c.mu.Lock()
+	err := c.err
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return c.write(func(w *msgp.Writer) error {
+		err := w.WriteArrayHeader(3)
+		if err == nil {
+			err = w.WriteInt(typeNotification)
+		}
+		if err == nil {
+			err = w.WriteString(method)
+		}
+		if err == nil {
+			err = writeParams(w, params)
+		}
+		return err
+	})
+}
+
+// Close closes the connection. Pending
+// calls return ErrShutdown.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	if c.err == nil {
+		c.err = ErrShutdown
+	}
+	c.mu.Unlock()
+	return c.rwc.Close()
+}
+
+// write writes one message, or nothing
+// if it can't be encoded
+func (c *Client) write(fn func(w *msgp.Writer) error) error {
+	c.enc.mu.Lock()
+	defer c.enc.mu.Unlock()
+	if err := c.enc.encode(fn); err != nil {
+		return err
+	}
+	return c.enc.send()
+}
+
+// forget removes a call that has
+// failed or is no longer waited for
+func (c *Client) forget(msgid uint32) {
+	c.mu.Lock()
+	delete(c.pending, msgid)
+	c.mu.Unlock()
+}
+
+// failure returns the error that
+// closed the connection
+func (c *Client) failure() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// read reads messages until the connection
+// fails, and then fails the pending calls
+func (c *Client) read() {
+	r := msgp.NewReader(c.rwc)
+	err := c.readLoop(r)
+	c.mu.Lock()
+	if c.err == nil {
+		if err == nil || err == io.EOF {
+			err = ErrShutdown
+		}
+		c.err = err
+	}
+	for msgid, ch := range c.pending {
+		close(ch)
+		delete(c.pending, msgid)
+	}
+	c.mu.Unlock()
+}
+
+func (c *Client) readLoop(r *msgp.Reader) error {
+	for {
+		typ, err := readHeader(r)
+		if err != nil {
+			return err
+		}
+		switch typ {
+		case typeResponse:
+			msgid, err := r.ReadUint32()
+			if err != nil {
+				return err
+			}
+			var res response
+			if err = res.err.DecodeMsg(r); err != nil {
+				return err
+			}
+			if err = res.result.DecodeMsg(r); err != nil {
+				return err
+			}
+			c.mu.Lock()
+			ch, ok := c.pending[msgid]
+			delete(c.pending, msgid)
+			c.mu.Unlock()
+			// responses to calls that timed out are dropped
+			if ok {
+				ch <- res
+			}
+		case typeNotification:
+			method, err := r.ReadString()
+			if err != nil {
+				return err
+			}
+			var params msgp.Raw
+			if err = params.DecodeMsg(r); err != nil {
+				return err
+			}
+			if c.notify != nil {
+				c.notify(method, params)
+			}
+		case typeRequest:
+			// the client doesn't serve requests,
+			// but answers them to keep the peer going
+			msgid, err := r.ReadUint32()
+			if err != nil {
+				return err
+			}
+			if err = r.Skip(); err != nil { // method
+				return err
+			}
+			if err = r.Skip(); err != nil { // params
+				return err
+			}
+			c.write(func(w *msgp.Writer) error {
+				err := w.WriteArrayHeader(4)
+				if err == nil {
+					err = w.WriteInt(typeResponse)
+				}
+				if err == nil {
+					err = w.WriteUint32(msgid)
+				}
+				if err == nil {
+					err = w.WriteString("msgpackrpc: the client doesn't handle requests")
+				}
+				if err == nil {
+					err = w.WriteNil()
+				}
+				return err
+			})
+		default:
+			return fmt.Errorf("msgpackrpc: unexpected message type %d", typ)
+		}
+	}
+}
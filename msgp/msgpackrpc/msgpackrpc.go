@@ -0,0 +1,138 @@
+// Package msgpackrpc implements the MessagePack-RPC protocol
+// (https://github.com/msgpack-rpc/msgpack-rpc/blob/master/spec.md)
+// over any io.ReadWriteCloser.
+//
+// Requests are written as [0, msgid, method, params],
+// responses as [1, msgid, error, result] and notifications
+// as [2, method, params]. The params array of a method can be
+// read and written by a generated type that is a tuple (see
+// the //msgp:tuple directive), which is encoded as an array.
+package msgpackrpc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/bytedance/msgp/msgp"
+)
+
+// the message types
+const (
+	typeRequest      = 0
+	typeResponse     = 1
+	typeNotification = 2
+)
+
+// ErrShutdown is returned by calls on a
+// Client whose connection has been closed.
+var ErrShutdown = errors.New("msgpackrpc: connection is shut down")
+
+// Error is an error returned by the peer
+// in the error field of a response.
+type Error struct {
+	Value interface{} // the error, as returned by ReadIntf
+}
+
+// Error implements the error interface
+func (e *Error) Error() string {
+	if s, ok := e.Value.(string); ok {
+		return s
+	}
+	return fmt.Sprint(e.Value)
+}
+
+// encoder writes whole messages to a connection.
+// Each message is encoded into a buffer first, so
+// that one that fails to encode isn't partly sent.
+type encoder struct {
+	mu   sync.Mutex // held while a message is encoded and sent
+	conn io.Writer
+	buf  bytes.Buffer
+	w    *msgp.Writer
+}
+
+func newEncoder(conn io.Writer) *encoder {
+	e := &encoder{conn: conn}
+	e.w = msgp.NewWriter(&e.buf)
+	return e
+}
+
+// encode encodes a message with 'fn'. If it
+// fails, what it has encoded is discarded.
+func (e *encoder) encode(fn func(w *msgp.Writer) error) error {
+	err := fn(e.w)
+	if err == nil {
+		err = e.w.Flush()
+	}
+	if err != nil {
+		e.buf.Reset()
+		e.w.Reset(&e.buf)
+	}
+	return err
+}
+
+// send writes the encoded messages to the connection
+func (e *encoder) send() error {
+	_, err := e.conn.Write(e.buf.Bytes())
+	e.buf.Reset()
+	return err
+}
+
+// writeParams writes the params array 'params', which
+// is written with its EncodeMsg method if it has one,
+// or otherwise with WriteIntf. A nil 'params' is
+// written as an empty array.
+func writeParams(w *msgp.Writer, params interface{}) error {
+	if params == nil {
+		return w.WriteArrayHeader(0)
+	}
+	return w.WriteIntf(params)
+}
+
+// decodeRaw reads the object 'raw' into 'v', which
+// must be a msgp.Decodable or an *interface{}
+func decodeRaw(raw msgp.Raw, v interface{}) error {
+	if len(raw) == 0 {
+		// msgp.Raw reads nil as empty
+		raw = msgp.AppendNil(nil)
+	}
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case msgp.Unmarshaler:
+		_, err := v.UnmarshalMsg(raw)
+		return err
+	case msgp.Decodable:
+		return msgp.Decode(bytes.NewReader(raw), v)
+	case *interface{}:
+		var err error
+		*v, _, err = msgp.ReadIntfBytes(raw)
+		return err
+	default:
+		return fmt.Errorf("msgpackrpc: can't decode into %T", v)
+	}
+}
+
+// readHeader reads the array header and type of
+// a message and checks the length of the array
+func readHeader(r *msgp.Reader) (typ int, err error) {
+	sz, err := r.ReadArrayHeader()
+	if err != nil {
+		return 0, err
+	}
+	typ, err = r.ReadInt()
+	if err != nil {
+		return 0, err
+	}
+	want := uint32(4)
+	if typ == typeNotification {
+		want = 3
+	}
+	if sz != want {
+		return typ, msgp.ArrayError{Wanted: want, Got: sz}
+	}
+	return typ, nil
+}
@@ -0,0 +1,363 @@
+package msgpackrpc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bytedance/msgp/msgp"
+)
+
+// Pair is a tuple that implements
+// msgp.Encodable and msgp.Decodable
+type Pair struct {
+	A, B int64
+}
+
+func (p *Pair) EncodeMsg(w *msgp.Writer) error {
+	if err := w.WriteArrayHeader(2); err != nil {
+		return err
+	}
+	if err := w.WriteInt64(p.A); err != nil {
+		return err
+	}
+	return w.WriteInt64(p.B)
+}
+
+func (p *Pair) DecodeMsg(r *msgp.Reader) (err error) {
+	sz, err := r.ReadArrayHeader()
+	if err != nil {
+		return err
+	}
+	if sz != 2 {
+		return msgp.ArrayError{Wanted: 2, Got: sz}
+	}
+	if p.A, err = r.ReadInt64(); err != nil {
+		return err
+	}
+	p.B, err = r.ReadInt64()
+	return err
+}
+
+func testServer(t *testing.T) (*Server, chan string) {
+	notes := make(chan string, 1)
+	srv := NewServer()
+	handlers := map[string]interface{}{
+		"add": func(p *Pair) (int64, error) {
+			return p.A + p.B, nil
+		},
+		"swap": func(p *Pair) (*Pair, error) {
+			return &Pair{A: p.B, B: p.A}, nil
+		},
+		"sleep": func(p *Pair) (int64, error) {
+			time.Sleep(time.Duration(p.A) * time.Millisecond)
+			return p.A, nil
+		},
+		"unencodable": func(p *msgp.Raw) (interface{}, error) {
+			return struct{ X int }{1}, nil
+		},
+		"fail": func(p *msgp.Raw) error {
+			return errors.New("failed")
+		},
+		"log": func(p *msgp.Raw) error {
+			v, _, err := msgp.ReadIntfBytes(*p)
+			if err != nil {
+				return err
+			}
+			notes <- v.([]interface{})[0].(string)
+			return nil
+		},
+	}
+	for name, fn := range handlers {
+		if err := srv.Register(name, fn); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return srv, notes
+}
+
+func testClient(t *testing.T) (*Client, chan string) {
+	srv, notes := testServer(t)
+	cli, conn := net.Pipe()
+	go srv.ServeConn(conn)
+	return NewClient(cli, nil), notes
+}
+
+func TestCall(t *testing.T) {
+	client, _ := testClient(t)
+	defer client.Close()
+	ctx := context.Background()
+
+	var sum interface{}
+	if err := client.Call(ctx, "add", &Pair{A: 3, B: 4}, &sum); err != nil {
+		t.Fatal(err)
+	}
+	if sum != int64(7) {
+		t.Errorf("add returned %v", sum)
+	}
+
+	var out Pair
+	if err := client.Call(ctx, "swap", &Pair{A: 1, B: 2}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out != (Pair{A: 2, B: 1}) {
+		t.Errorf("swap returned %v", out)
+	}
+
+	// params that aren't Encodable
+	if err := client.Call(ctx, "add", []interface{}{5, 6}, &sum); err != nil {
+		t.Fatal(err)
+	}
+	if sum != int64(11) {
+		t.Errorf("add returned %v", sum)
+	}
+}
+
+func TestCallUnencodable(t *testing.T) {
+	client, _ := testClient(t)
+	defer client.Close()
+	ctx := context.Background()
+
+	var sum interface{}
+	if err := client.Call(ctx, "add", struct{ X int }{1}, &sum); err == nil {
+		t.Error("expected an error for params that can't be encoded")
+	}
+	err := client.Call(ctx, "unencodable", nil, &sum)
+	if _, ok := err.(*Error); !ok {
+		t.Errorf("expected an *Error for a result that can't be encoded; got %v", err)
+	}
+
+	// nothing was left half written
+	done := make(chan error, 1)
+	go func() { done <- client.Call(ctx, "add", []interface{}{1, 2}, &sum) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("call after an encoding error hung")
+	}
+	if sum != int64(3) {
+		t.Errorf("add returned %v", sum)
+	}
+}
+
+func TestCallError(t *testing.T) {
+	client, _ := testClient(t)
+	defer client.Close()
+	ctx := context.Background()
+
+	err := client.Call(ctx, "fail", nil, nil)
+	if e, ok := err.(*Error); !ok || e.Error() != "failed" {
+		t.Errorf("got error %v", err)
+	}
+	err = client.Call(ctx, "missing", nil, nil)
+	if _, ok := err.(*Error); !ok {
+		t.Errorf("got error %v for an unknown method", err)
+	}
+	// bad params are returned by the server
+	err = client.Call(ctx, "add", []interface{}{"x"}, nil)
+	if _, ok := err.(*Error); !ok {
+		t.Errorf("got error %v for bad params", err)
+	}
+
+	// the connection is still usable
+	var sum interface{}
+	if err = client.Call(ctx, "add", &Pair{A: 1, B: 1}, &sum); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPipelined(t *testing.T) {
+	client, _ := testClient(t)
+	defer client.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int64) {
+			defer wg.Done()
+			var out Pair
+			if err := client.Call(context.Background(), "swap", &Pair{A: i, B: -i}, &out); err != nil {
+				t.Error(err)
+				return
+			}
+			if out.A != -i || out.B != i {
+				t.Errorf("call %d returned %v", i, out)
+			}
+		}(int64(i))
+	}
+	// later calls sleep less, so
+	// responses arrive out of order
+	for i := 5; i > 0; i-- {
+		wg.Add(1)
+		go func(i int64) {
+			defer wg.Done()
+			var out interface{}
+			if err := client.Call(context.Background(), "sleep", &Pair{A: i * 5}, &out); err != nil {
+				t.Error(err)
+				return
+			}
+			if out != i*5 {
+				t.Errorf("sleep returned %v", out)
+			}
+		}(int64(i))
+	}
+	wg.Wait()
+}
+
+func TestTimeout(t *testing.T) {
+	client, _ := testClient(t)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := client.Call(ctx, "sleep", &Pair{A: 200}, nil)
+	if err != context.DeadlineExceeded {
+		t.Fatalf("got error %v", err)
+	}
+
+	// the late response is dropped
+	var sum interface{}
+	if err = client.Call(context.Background(), "add", &Pair{A: 2, B: 2}, &sum); err != nil {
+		t.Fatal(err)
+	}
+	if sum != int64(4) {
+		t.Errorf("add returned %v", sum)
+	}
+}
+
+func TestNotify(t *testing.T) {
+	client, notes := testClient(t)
+	defer client.Close()
+
+	if err := client.Notify("log", []interface{}{"hello"}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case s := <-notes:
+		if s != "hello" {
+			t.Errorf("got notification %q", s)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("notification wasn't handled")
+	}
+}
+
+func TestNotifyOrder(t *testing.T) {
+	client, notes := testClient(t)
+	defer client.Close()
+
+	for i := 0; i < 50; i++ {
+		if err := client.Notify("log", []interface{}{strconv.Itoa(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 50; i++ {
+		select {
+		case s := <-notes:
+			if s != strconv.Itoa(i) {
+				t.Fatalf("notification %d was %q", i, s)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("notification %d wasn't handled", i)
+		}
+	}
+}
+
+func TestClientNotifications(t *testing.T) {
+	cli, conn := net.Pipe()
+	got := make(chan string, 1)
+	client := NewClient(cli, func(method string, params msgp.Raw) {
+		v, _, err := msgp.ReadIntfBytes(params)
+		if err != nil {
+			t.Error(err)
+		}
+		got <- method + ":" + v.([]interface{})[0].(string)
+	})
+	defer client.Close()
+
+	// the peer sends a notification and a request
+	w := msgp.NewWriter(conn)
+	w.WriteArrayHeader(3)
+	w.WriteInt(typeNotification)
+	w.WriteString("event")
+	w.WriteIntf([]interface{}{"redraw"})
+	w.WriteArrayHeader(4)
+	w.WriteInt(typeRequest)
+	w.WriteUint32(9)
+	w.WriteString("method")
+	w.WriteArrayHeader(0)
+	go w.Flush()
+
+	if s := <-got; s != "event:redraw" {
+		t.Errorf("got notification %q", s)
+	}
+
+	// which is answered with an error
+	r := msgp.NewReader(conn)
+	typ, err := readHeader(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgid, err := r.ReadUint32()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if typ != typeResponse || msgid != 9 {
+		t.Errorf("got response type %d msgid %d", typ, msgid)
+	}
+	if typ, err := r.NextType(); err != nil || typ != msgp.StrType {
+		t.Errorf("got error of type %s (%v)", typ, err)
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	cli, conn := net.Pipe()
+	client := NewClient(cli, nil)
+	done := make(chan error, 1)
+	go func() {
+		done <- client.Call(context.Background(), "add", &Pair{}, nil)
+	}()
+	// read the request and hang up
+	if _, err := msgp.NewReader(conn).ReadIntf(); err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+	if err := <-done; err != ErrShutdown {
+		t.Errorf("got error %v", err)
+	}
+	if err := client.Call(context.Background(), "add", &Pair{}, nil); err != ErrShutdown {
+		t.Errorf("got error %v after shutdown", err)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	srv := NewServer()
+	bad := []interface{}{
+		42,
+		func(p Pair) error { return nil },
+		func(p *int) error { return nil },
+		func(p *Pair) {},
+		func(p *Pair) int { return 0 },
+		func(p *Pair) (int, int) { return 0, 0 },
+		func(a, b *Pair) error { return nil },
+	}
+	for _, fn := range bad {
+		if err := srv.Register("bad", fn); err == nil {
+			t.Errorf("registered %T", fn)
+		}
+	}
+	ok := func(p *Pair) error { return nil }
+	if err := srv.Register("ok", ok); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.Register("ok", ok); err == nil {
+		t.Error("registered a method twice")
+	}
+}
@@ -0,0 +1,231 @@
+package msgpackrpc
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"sync"
+
+	"github.com/bytedance/msgp/msgp"
+)
+
+var (
+	typeOfError     = reflect.TypeOf((*error)(nil)).Elem()
+	typeOfDecodable = reflect.TypeOf((*msgp.Decodable)(nil)).Elem()
+)
+
+// method is a registered handler
+type method struct {
+	fn        reflect.Value
+	params    reflect.Type // the type that params are decoded into
+	hasResult bool
+}
+
+// Server dispatches the requests and
+// notifications it reads to the handlers
+// registered for their methods.
+type Server struct {
+	mu      sync.RWMutex
+	methods map[string]*method
+}
+
+// NewServer returns a Server
+// with no registered methods.
+func NewServer() *Server {
+	return &Server{methods: make(map[string]*method)}
+}
+
+// Register registers 'fn' as the handler for the method 'name'.
+// It must be a func(P) (R, error) or a func(P) error, where P is
+// a pointer type that implements msgp.Decodable (e.g. a pointer
+// to a generated tuple type, or *msgp.Raw), into which the params
+// array is decoded. R is written as the result with WriteIntf,
+// which uses its EncodeMsg method if it has one. An error is
+// written as its Error() string, unless it implements
+// msgp.Encodable.
+func (s *Server) Register(name string, fn interface{}) error {
+	v := reflect.ValueOf(fn)
+	t := v.Type()
+	if t.Kind() != reflect.Func || t.NumIn() != 1 || t.In(0).Kind() != reflect.Ptr || !t.In(0).Implements(typeOfDecodable) {
+		return fmt.Errorf("msgpackrpc: handler for %s must take one pointer to a msgp.Decodable; found %s", name, t)
+	}
+	if n := t.NumOut(); n < 1 || n > 2 || t.Out(n-1) != typeOfError {
+		return fmt.Errorf("msgpackrpc: handler for %s must return (result, error) or error; found %s", name, t)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.methods[name]; ok {
+		return fmt.Errorf("msgpackrpc: method %s is already registered", name)
+	}
+	s.methods[name] = &method{fn: v, params: t.In(0).Elem(), hasResult: t.NumOut() == 2}
+	return nil
+}
+
+// call decodes 'params' and calls
+// the handler for the method 'name'
+func (s *Server) call(name string, params msgp.Raw) (interface{}, error) {
+	s.mu.RLock()
+	m, ok := s.methods[name]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("msgpackrpc: method %s not found", name)
+	}
+	p := reflect.New(m.params)
+	if err := decodeRaw(params, p.Interface()); err != nil {
+		return nil, err
+	}
+	out := m.fn.Call([]reflect.Value{p})
+	if err, _ := out[len(out)-1].Interface().(error); err != nil {
+		return nil, err
+	}
+	if !m.hasResult {
+		return nil, nil
+	}
+	return out[0].Interface(), nil
+}
+
+// conn writes the responses for a connection
+type conn struct {
+	enc *encoder
+}
+
+// respond writes the response to the request 'msgid'.
+// If 'result' or 'err' can't be encoded, an error
+// response saying so is written instead, and the
+// encoding error is returned.
+func (c *conn) respond(msgid uint32, result interface{}, err error) error {
+	c.enc.mu.Lock()
+	defer c.enc.mu.Unlock()
+	werr := c.enc.encode(encodeResponse(msgid, result, err))
+	if werr != nil {
+		c.enc.encode(encodeResponse(msgid, nil, fmt.Errorf("msgpackrpc: encoding the response: %v", werr)))
+	}
+	if err := c.enc.send(); err != nil {
+		return err
+	}
+	return werr
+}
+
+// encodeResponse returns a func that writes
+// a response with 'result' and 'err'
+func encodeResponse(msgid uint32, result interface{}, err error) func(w *msgp.Writer) error {
+	return func(w *msgp.Writer) error {
+		werr := w.WriteArrayHeader(4)
+		if werr == nil {
+			werr = w.WriteInt(typeResponse)
+		}
+		if werr == nil {
+			werr = w.WriteUint32(msgid)
+		}
+		if werr == nil {
+			switch e := err.(type) {
+			case nil:
+				werr = w.WriteNil()
+			case msgp.Encodable:
+				werr = e.EncodeMsg(w)
+			default:
+				werr = w.WriteString(err.Error())
+			}
+		}
+		if werr == nil {
+			if err != nil {
+				result = nil
+			}
+			werr = w.WriteIntf(result)
+		}
+		return werr
+	}
+}
+
+// notifyQueue is how many notifications of a
+// connection can wait for the ones before them
+// to be handled before reading stops
+const notifyQueue = 64
+
+// notification is a notification
+// that is waiting to be handled
+type notification struct {
+	name   string
+	params msgp.Raw
+}
+
+// ServeConn reads requests and notifications from 'rwc' until
+// it returns an error, and then closes it. Each request is
+// handled in its own goroutine, so responses may be written in
+// any order. Notifications are handled one at a time, in the
+// order they were sent, since peers may depend on it (e.g.
+// for batches of updates).
+func (s *Server) ServeConn(rwc io.ReadWriteCloser) error {
+	c := &conn{enc: newEncoder(rwc)}
+	r := msgp.NewReader(rwc)
+	notes := make(chan notification, notifyQueue)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for n := range notes {
+			s.call(n.name, n.params)
+		}
+	}()
+	defer func() {
+		close(notes)
+		wg.Wait()
+		rwc.Close()
+	}()
+	for {
+		typ, err := readHeader(r)
+		if err != nil {
+			return noEOF(err)
+		}
+		var msgid uint32
+		switch typ {
+		case typeRequest:
+			if msgid, err = r.ReadUint32(); err != nil {
+				return err
+			}
+		case typeNotification:
+		default:
+			return fmt.Errorf("msgpackrpc: unexpected message type %d", typ)
+		}
+		name, err := r.ReadString()
+		if err != nil {
+			return err
+		}
+		var params msgp.Raw
+		if err = params.DecodeMsg(r); err != nil {
+			return err
+		}
+		if typ == typeNotification {
+			notes <- notification{name: name, params: params}
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := s.call(name, params)
+			c.respond(msgid, result, err)
+		}()
+	}
+}
+
+// Serve accepts connections from 'l' and serves each
+// of them in its own goroutine, until Accept fails.
+func (s *Server) Serve(l net.Listener) error {
+	for {
+		rwc, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go s.ServeConn(rwc)
+	}
+}
+
+// noEOF returns nil for io.EOF, which
+// is the normal end of a connection
+func noEOF(err error) error {
+	if err == io.EOF {
+		return nil
+	}
+	return err
+}
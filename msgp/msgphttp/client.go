@@ -0,0 +1,116 @@
+package msgphttp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/bytedance/msgp/msgp"
+)
+
+// StatusError is returned by DecodeResponse
+// for a response with a non-2xx status.
+type StatusError struct {
+	StatusCode int
+	Status     string
+	Body       []byte // the first 512 bytes of the body
+}
+
+// Error implements the error interface
+func (e *StatusError) Error() string {
+	if len(e.Body) > 0 && strings.HasPrefix(http.DetectContentType(e.Body), "text/") {
+		return fmt.Sprintf("msgphttp: %s: %q", e.Status, e.Body)
+	}
+	return "msgphttp: " + e.Status
+}
+
+// NewRequest returns a request for 'url' with 'v' encoded as
+// the body, and headers that ask for a MessagePack response.
+// A nil 'v' sends no body. Since the body is buffered, the
+// request can be retried and redirected.
+func NewRequest(ctx context.Context, method, url string, v msgp.Encodable) (*http.Request, error) {
+	var body io.Reader
+	if v != nil {
+		buf := new(bytes.Buffer)
+		if err := msgp.Encode(buf, v); err != nil {
+			return nil, err
+		}
+		body = buf
+	}
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	if v != nil {
+		req.Header.Set("Content-Type", ContentType)
+	}
+	req.Header.Set("Accept", ContentType)
+	return req.WithContext(ctx), nil
+}
+
+// DecodeResponse decodes 'v' from the body of 'resp', which
+// must be at most 'max' bytes, or DefaultMaxBodySize if 'max'
+// is 0. It returns a *StatusError if the status isn't 2xx, and
+// a *ContentTypeError if the body isn't MessagePack. A nil 'v'
+// ignores the body. The body is always closed.
+func DecodeResponse(resp *http.Response, v msgp.Decodable, max int64) error {
+	defer drain(resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return &StatusError{StatusCode: resp.StatusCode, Status: resp.Status, Body: body}
+	}
+	if v == nil {
+		return nil
+	}
+	if ct := resp.Header.Get("Content-Type"); !IsMsgpack(ct) {
+		return &ContentTypeError{ContentType: ct}
+	}
+	return decodeBody(resp.Body, v, max)
+}
+
+// Client makes MessagePack calls
+// with an *http.Client.
+type Client struct {
+	// HTTP is the client that sends requests,
+	// so it sets the transport, timeouts, etc.
+	// If it is nil, http.DefaultClient is used.
+	HTTP *http.Client
+
+	// MaxBodySize is the limit on the size of
+	// response bodies. If it is 0,
+	// DefaultMaxBodySize is used.
+	MaxBodySize int64
+}
+
+// Do sends 'req' and decodes the response into 'out'
+// (see DecodeResponse).
+func (c *Client) Do(req *http.Request, out msgp.Decodable) error {
+	hc := c.HTTP
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return err
+	}
+	return DecodeResponse(resp, out, c.MaxBodySize)
+}
+
+// Call sends 'in' to 'url' with 'method' (see NewRequest)
+// and decodes the response into 'out' (see DecodeResponse).
+func (c *Client) Call(ctx context.Context, method, url string, in msgp.Encodable, out msgp.Decodable) error {
+	req, err := NewRequest(ctx, method, url, in)
+	if err != nil {
+		return err
+	}
+	return c.Do(req, out)
+}
+
+// Post is Call with the POST method.
+func (c *Client) Post(ctx context.Context, url string, in msgp.Encodable, out msgp.Decodable) error {
+	return c.Call(ctx, http.MethodPost, url, in, out)
+}
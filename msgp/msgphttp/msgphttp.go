@@ -0,0 +1,249 @@
+// Package msgphttp implements the usual plumbing for
+// MessagePack over HTTP: reading request bodies with a size
+// limit, writing responses with the right Content-Type,
+// negotiating MessagePack or JSON from the Accept header,
+// and making calls from a client.
+package msgphttp
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/bytedance/msgp/msgp"
+)
+
+const (
+	// ContentType is the media type
+	// written for MessagePack bodies.
+	ContentType = "application/msgpack"
+
+	// ContentTypeJSON is the media type written
+	// for bodies translated to JSON.
+	ContentTypeJSON = "application/json"
+
+	// DefaultMaxBodySize is the limit on the
+	// size of bodies read by DecodeRequest
+	// and DecodeResponse.
+	DefaultMaxBodySize = 4 << 20
+)
+
+// IsMsgpack returns whether the media type 'ct'
+// (e.g. the value of a Content-Type header)
+// is MessagePack. Both application/msgpack and
+// application/x-msgpack are accepted.
+func IsMsgpack(ct string) bool {
+	mt, _, err := mime.ParseMediaType(ct)
+	if err != nil {
+		return false
+	}
+	switch mt {
+	case ContentType, "application/x-msgpack", "application/vnd.msgpack":
+		return true
+	}
+	return false
+}
+
+// BodySizeError is returned when a
+// body is larger than the limit.
+type BodySizeError struct {
+	Max int64
+}
+
+// Error implements the error interface
+func (e *BodySizeError) Error() string {
+	return fmt.Sprintf("msgphttp: body is larger than %d bytes", e.Max)
+}
+
+// StatusCode returns http.StatusRequestEntityTooLarge
+func (e *BodySizeError) StatusCode() int { return http.StatusRequestEntityTooLarge }
+
+// ContentTypeError is returned when
+// a body isn't MessagePack.
+type ContentTypeError struct {
+	ContentType string
+}
+
+// Error implements the error interface
+func (e *ContentTypeError) Error() string {
+	return fmt.Sprintf("msgphttp: unsupported content type %q", e.ContentType)
+}
+
+// StatusCode returns http.StatusUnsupportedMediaType
+func (e *ContentTypeError) StatusCode() int { return http.StatusUnsupportedMediaType }
+
+// limitReader reads at most 'n' more bytes,
+// and fails if the body has more than that
+type limitReader struct {
+	r        io.Reader
+	n        int64
+	max      int64
+	exceeded bool
+}
+
+func (l *limitReader) Read(p []byte) (int, error) {
+	if l.n <= 0 {
+		// check that the body ends here
+		var b [1]byte
+		n, err := l.r.Read(b[:])
+		if n > 0 {
+			l.exceeded = true
+			return 0, &BodySizeError{Max: l.max}
+		}
+		return 0, err
+	}
+	if int64(len(p)) > l.n {
+		p = p[:l.n]
+	}
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	return n, err
+}
+
+// decodeBody decodes 'v' from 'body',
+// which may be at most 'max' bytes
+func decodeBody(body io.Reader, v msgp.Decodable, max int64) error {
+	if max <= 0 {
+		max = DefaultMaxBodySize
+	}
+	lr := &limitReader{r: body, n: max, max: max}
+	err := msgp.Decode(lr, v)
+	if lr.exceeded {
+		return &BodySizeError{Max: max}
+	}
+	return err
+}
+
+// DecodeRequest decodes 'v' from the body of 'r', which must
+// be at most DefaultMaxBodySize bytes. It returns a
+// *ContentTypeError if the request has a Content-Type that
+// isn't MessagePack, and a *BodySizeError if the body is too
+// large. Both have a StatusCode method that returns the status
+// to respond with.
+func DecodeRequest(r *http.Request, v msgp.Decodable) error {
+	return DecodeRequestLimit(r, v, DefaultMaxBodySize)
+}
+
+// DecodeRequestLimit is like DecodeRequest, but reads a body of
+// at most 'max' bytes, or DefaultMaxBodySize if 'max' is 0.
+func DecodeRequestLimit(r *http.Request, v msgp.Decodable, max int64) error {
+	if max <= 0 {
+		max = DefaultMaxBodySize
+	}
+	if ct := r.Header.Get("Content-Type"); ct != "" && !IsMsgpack(ct) {
+		return &ContentTypeError{ContentType: ct}
+	}
+	if r.ContentLength > max {
+		return &BodySizeError{Max: max}
+	}
+	return decodeBody(r.Body, v, max)
+}
+
+// WriteResponse writes 'v' as the body of
+// the response with a MessagePack Content-Type.
+// It is encoded before anything is written, so
+// that encoding errors can still set the status.
+func WriteResponse(w http.ResponseWriter, v msgp.Encodable) error {
+	var buf bytes.Buffer
+	if err := msgp.Encode(&buf, v); err != nil {
+		return err
+	}
+	return writeBody(w, ContentType, buf.Bytes())
+}
+
+// Respond writes 'v' as the body of the response to 'r' in
+// MessagePack or JSON, whichever the Accept header of 'r'
+// prefers (see PrefersJSON). JSON is translated from the
+// MessagePack encoding of 'v' with msgp.CopyToJSON. Like
+// WriteResponse, nothing is written if that fails.
+func Respond(w http.ResponseWriter, r *http.Request, v msgp.Encodable) error {
+	if !PrefersJSON(r.Header.Get("Accept")) {
+		return WriteResponse(w, v)
+	}
+	var buf, js bytes.Buffer
+	if err := msgp.Encode(&buf, v); err != nil {
+		return err
+	}
+	if _, err := msgp.CopyToJSON(&js, &buf); err != nil {
+		return err
+	}
+	return writeBody(w, ContentTypeJSON, js.Bytes())
+}
+
+// writeBody writes the body 'b'
+// with the Content-Type 'ct'
+func writeBody(w http.ResponseWriter, ct string, b []byte) error {
+	w.Header().Set("Content-Type", ct)
+	w.Header().Set("Content-Length", strconv.Itoa(len(b)))
+	_, err := w.Write(b)
+	return err
+}
+
+// PrefersJSON returns whether the Accept header 'accept'
+// gives JSON a higher quality than MessagePack, or refuses
+// MessagePack (with q=0) but not JSON. Each type gets the
+// quality of the most specific range that matches it (e.g.
+// application/json, then application/*, then */*).
+// MessagePack is preferred if the header is missing or
+// accepts neither.
+func PrefersJSON(accept string) bool {
+	var mp, js quality
+	for _, part := range strings.Split(accept, ",") {
+		mt, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if s, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(s, 64); err != nil {
+				continue
+			}
+		}
+		switch {
+		case mt == "*/*":
+			mp.match(1, q)
+			js.match(1, q)
+		case mt == "application/*":
+			mp.match(2, q)
+			js.match(2, q)
+		case IsMsgpack(mt):
+			mp.match(3, q)
+		case mt == ContentTypeJSON:
+			js.match(3, q)
+		}
+	}
+	return js.q > mp.q || mp.refused() && !js.refused()
+}
+
+// quality is the quality that an
+// Accept header gives a media type
+type quality struct {
+	q    float64
+	spec int // how specific the matching range is, or 0 if none matched
+}
+
+// match applies a range of the
+// specificity 'spec' and quality 'q'
+func (a *quality) match(spec int, q float64) {
+	if spec > a.spec || spec == a.spec && q > a.q {
+		a.spec, a.q = spec, q
+	}
+}
+
+// refused returns whether the type is
+// matched by a range with q=0
+func (a *quality) refused() bool {
+	return a.spec > 0 && a.q == 0
+}
+
+// drain reads the rest of 'body' and closes
+// it, so that the connection can be reused
+func drain(body io.ReadCloser) {
+	io.CopyN(ioutil.Discard, body, 4<<10)
+	body.Close()
+}
@@ -0,0 +1,231 @@
+package msgphttp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/bytedance/msgp/msgp"
+)
+
+// Greeting implements msgp.Encodable
+// and msgp.Decodable as a map
+type Greeting struct {
+	Name string
+}
+
+func (g *Greeting) EncodeMsg(w *msgp.Writer) error {
+	if err := w.WriteMapHeader(1); err != nil {
+		return err
+	}
+	if err := w.WriteString("name"); err != nil {
+		return err
+	}
+	return w.WriteString(g.Name)
+}
+
+func (g *Greeting) DecodeMsg(r *msgp.Reader) error {
+	sz, err := r.ReadMapHeader()
+	if err != nil {
+		return err
+	}
+	for i := uint32(0); i < sz; i++ {
+		key, err := r.ReadString()
+		if err != nil {
+			return err
+		}
+		if key != "name" {
+			if err = r.Skip(); err != nil {
+				return err
+			}
+			continue
+		}
+		if g.Name, err = r.ReadString(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func encode(t *testing.T, v msgp.Encodable) []byte {
+	var buf bytes.Buffer
+	if err := msgp.Encode(&buf, v); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// hello greets the name in the request
+func hello(w http.ResponseWriter, r *http.Request) {
+	var g Greeting
+	if err := DecodeRequestLimit(r, &g, 64); err != nil {
+		code := http.StatusBadRequest
+		if s, ok := err.(interface{ StatusCode() int }); ok {
+			code = s.StatusCode()
+		}
+		http.Error(w, err.Error(), code)
+		return
+	}
+	g.Name = "hello, " + g.Name
+	Respond(w, r, &g)
+}
+
+func TestDecodeRequest(t *testing.T) {
+	cases := []struct {
+		ct   string
+		body []byte
+		code int
+	}{
+		{ContentType, encode(t, &Greeting{Name: "a"}), http.StatusOK},
+		{"application/x-msgpack", encode(t, &Greeting{Name: "a"}), http.StatusOK},
+		{"", encode(t, &Greeting{Name: "a"}), http.StatusOK},
+		{"application/json", []byte(`{"name":"a"}`), http.StatusUnsupportedMediaType},
+		{ContentType, encode(t, &Greeting{Name: strings.Repeat("a", 100)}), http.StatusRequestEntityTooLarge},
+		{ContentType, []byte{0xc1}, http.StatusBadRequest},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest("POST", "/", bytes.NewReader(c.body))
+		if c.ct != "" {
+			req.Header.Set("Content-Type", c.ct)
+		}
+		rec := httptest.NewRecorder()
+		hello(rec, req)
+		if rec.Code != c.code {
+			t.Errorf("%q: got status %d; want %d (%s)", c.ct, rec.Code, c.code, rec.Body)
+		}
+	}
+
+	// a body that's too large, without a Content-Length
+	body := encode(t, &Greeting{Name: strings.Repeat("a", 100)})
+	req := httptest.NewRequest("POST", "/", ioutil.NopCloser(bytes.NewReader(body)))
+	req.ContentLength = -1
+	var g Greeting
+	if err := DecodeRequestLimit(req, &g, 64); err == nil {
+		t.Error("read a body over the limit")
+	} else if _, ok := err.(*BodySizeError); !ok {
+		t.Errorf("got error %v", err)
+	}
+
+	// a body of exactly the limit
+	req = httptest.NewRequest("POST", "/", ioutil.NopCloser(bytes.NewReader(body)))
+	req.ContentLength = -1
+	if err := DecodeRequestLimit(req, &g, int64(len(body))); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRespond(t *testing.T) {
+	cases := []struct {
+		accept string
+		ct     string
+	}{
+		{"", ContentType},
+		{"*/*", ContentType},
+		{ContentType, ContentType},
+		{"application/json", ContentTypeJSON},
+		{"application/json, application/msgpack", ContentType},
+		{"application/json, application/msgpack;q=0.5", ContentTypeJSON},
+		{"application/x-msgpack;q=0.9, application/json;q=0.1", ContentType},
+		{"text/html", ContentType},
+		{"application/msgpack;q=0", ContentTypeJSON},
+		{"application/msgpack;q=0, application/json;q=0", ContentType},
+		{"application/json;q=0.5, application/*;q=0.8", ContentType},
+		{"application/json, */*;q=0.1", ContentTypeJSON},
+		{"application/*;q=0.2, application/json;q=0.1", ContentType},
+		{"*/*;q=0.5, application/json", ContentTypeJSON},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Accept", c.accept)
+		rec := httptest.NewRecorder()
+		if err := Respond(rec, req, &Greeting{Name: "x"}); err != nil {
+			t.Fatal(err)
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != c.ct {
+			t.Errorf("Accept %q: got %q; want %q", c.accept, ct, c.ct)
+			continue
+		}
+		if cl := rec.Header().Get("Content-Length"); cl != strconv.Itoa(rec.Body.Len()) {
+			t.Errorf("Accept %q: Content-Length is %q for %d bytes", c.accept, cl, rec.Body.Len())
+		}
+		if c.ct == ContentTypeJSON {
+			if s := rec.Body.String(); s != `{"name":"x"}` {
+				t.Errorf("got JSON %s", s)
+			}
+		} else {
+			var g Greeting
+			if err := msgp.Decode(rec.Body, &g); err != nil || g.Name != "x" {
+				t.Errorf("got %v, %v", g, err)
+			}
+		}
+	}
+
+	// an encoding error writes nothing
+	for _, accept := range []string{ContentType, ContentTypeJSON} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Accept", accept)
+		rec := httptest.NewRecorder()
+		if err := Respond(rec, req, failEncode{}); err == nil {
+			t.Errorf("Accept %q: no error", accept)
+		}
+		if rec.Body.Len() != 0 || rec.Header().Get("Content-Type") != "" {
+			t.Errorf("Accept %q: wrote %d bytes and Content-Type %q", accept, rec.Body.Len(), rec.Header().Get("Content-Type"))
+		}
+	}
+}
+
+// failEncode writes part of a
+// map and then fails
+type failEncode struct{}
+
+func (failEncode) EncodeMsg(w *msgp.Writer) error {
+	w.WriteMapHeader(1)
+	w.WriteString(strings.Repeat("x", 8<<10))
+	return errors.New("can't encode")
+}
+
+func TestClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(hello))
+	defer srv.Close()
+	c := &Client{HTTP: srv.Client()}
+	ctx := context.Background()
+
+	var out Greeting
+	if err := c.Post(ctx, srv.URL, &Greeting{Name: "client"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Name != "hello, client" {
+		t.Errorf("got %q", out.Name)
+	}
+
+	err := c.Post(ctx, srv.URL, &Greeting{Name: strings.Repeat("a", 100)}, &out)
+	if se, ok := err.(*StatusError); !ok || se.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("got error %v", err)
+	} else if !strings.Contains(se.Error(), "larger than 64 bytes") {
+		t.Errorf("got error %q", se.Error())
+	}
+
+	// a response over the limit
+	c.MaxBodySize = 8
+	err = c.Post(ctx, srv.URL, &Greeting{Name: "client"}, &out)
+	if _, ok := err.(*BodySizeError); !ok {
+		t.Errorf("got error %v", err)
+	}
+
+	// a response that isn't MessagePack
+	js := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{}`))
+	}))
+	defer js.Close()
+	err = (&Client{}).Call(ctx, "GET", js.URL, nil, &out)
+	if _, ok := err.(*ContentTypeError); !ok {
+		t.Errorf("got error %v", err)
+	}
+}
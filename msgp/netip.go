@@ -0,0 +1,92 @@
+//go:build go1.18
+// +build go1.18
+
+package msgp
+
+import (
+	"net/netip"
+)
+
+// netip.Addr and netip.Prefix are written as bin
+// holding the output of their MarshalBinary methods:
+// the 4 or 16 address bytes (followed by the zone, if
+// any), and for prefixes, one more byte with the
+// number of prefix bits. The zero values are empty.
+
+// ReadNetipAddr reads a netip.Addr from the reader.
+func (m *Reader) ReadNetipAddr() (a netip.Addr, err error) {
+	var b []byte
+	b, err = m.ReadBytes(m.scratch[:0])
+	if err != nil {
+		return
+	}
+	m.scratch = b
+	err = a.UnmarshalBinary(b)
+	return
+}
+
+// ReadNetipAddrBytes reads a netip.Addr from 'b'
+// and returns the remaining bytes.
+func ReadNetipAddrBytes(b []byte) (a netip.Addr, o []byte, err error) {
+	var v []byte
+	v, o, err = ReadBytesZC(b)
+	if err != nil {
+		return
+	}
+	err = a.UnmarshalBinary(v)
+	return
+}
+
+// WriteNetipAddr writes a netip.Addr to the writer.
+func (mw *Writer) WriteNetipAddr(a netip.Addr) error {
+	b, _ := a.MarshalBinary() // never fails
+	return mw.WriteBytes(b)
+}
+
+// AppendNetipAddr appends a netip.Addr to the slice.
+func AppendNetipAddr(b []byte, a netip.Addr) []byte {
+	v, _ := a.MarshalBinary() // never fails
+	return AppendBytes(b, v)
+}
+
+// NetipAddrSize returns the maximum encoded size of 'a'.
+func NetipAddrSize(a netip.Addr) int { return BytesPrefixSize + 16 + len(a.Zone()) }
+
+// ReadNetipPrefix reads a netip.Prefix from the reader.
+func (m *Reader) ReadNetipPrefix() (p netip.Prefix, err error) {
+	var b []byte
+	b, err = m.ReadBytes(m.scratch[:0])
+	if err != nil {
+		return
+	}
+	m.scratch = b
+	err = p.UnmarshalBinary(b)
+	return
+}
+
+// ReadNetipPrefixBytes reads a netip.Prefix from 'b'
+// and returns the remaining bytes.
+func ReadNetipPrefixBytes(b []byte) (p netip.Prefix, o []byte, err error) {
+	var v []byte
+	v, o, err = ReadBytesZC(b)
+	if err != nil {
+		return
+	}
+	err = p.UnmarshalBinary(v)
+	return
+}
+
+// WriteNetipPrefix writes a netip.Prefix to the writer.
+func (mw *Writer) WriteNetipPrefix(p netip.Prefix) error {
+	b, _ := p.MarshalBinary() // never fails
+	return mw.WriteBytes(b)
+}
+
+// AppendNetipPrefix appends a netip.Prefix to the slice.
+func AppendNetipPrefix(b []byte, p netip.Prefix) []byte {
+	v, _ := p.MarshalBinary() // never fails
+	return AppendBytes(b, v)
+}
+
+// NetipPrefixSize returns the maximum encoded size of 'p'.
+func NetipPrefixSize(p netip.Prefix) int { return BytesPrefixSize + 17 }
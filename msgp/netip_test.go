@@ -0,0 +1,48 @@
+//go:build go1.18
+// +build go1.18
+
+package msgp
+
+import (
+	"bytes"
+	"net/netip"
+	"testing"
+)
+
+func TestNetipAddr(t *testing.T) {
+	for _, a := range []netip.Addr{{}, netip.MustParseAddr("10.0.0.1"), netip.MustParseAddr("fe80::1%eth0")} {
+		b := AppendNetipAddr(nil, a)
+		if len(b) > NetipAddrSize(a) {
+			t.Errorf("%v: NetipAddrSize %d is less than %d", a, NetipAddrSize(a), len(b))
+		}
+		got, left, err := ReadNetipAddrBytes(b)
+		if err != nil || len(left) != 0 || got != a {
+			t.Errorf("%v: ReadNetipAddrBytes returned %v, %v", a, got, err)
+		}
+		got, err = NewReader(bytes.NewReader(b)).ReadNetipAddr()
+		if err != nil || got != a {
+			t.Errorf("%v: ReadNetipAddr returned %v, %v", a, got, err)
+		}
+	}
+}
+
+func TestNetipPrefix(t *testing.T) {
+	for _, p := range []netip.Prefix{{}, netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("2001:db8::/32")} {
+		b := AppendNetipPrefix(nil, p)
+		if len(b) > NetipPrefixSize(p) {
+			t.Errorf("%v: NetipPrefixSize %d is less than %d", p, NetipPrefixSize(p), len(b))
+		}
+		got, left, err := ReadNetipPrefixBytes(b)
+		if err != nil || len(left) != 0 || got != p {
+			t.Errorf("%v: ReadNetipPrefixBytes returned %v, %v", p, got, err)
+		}
+		var buf bytes.Buffer
+		wr := NewWriter(&buf)
+		wr.WriteNetipPrefix(p)
+		wr.Flush()
+		got, err = NewReader(&buf).ReadNetipPrefix()
+		if err != nil || got != p {
+			t.Errorf("%v: ReadNetipPrefix returned %v, %v", p, got, err)
+		}
+	}
+}
@@ -0,0 +1,685 @@
+package msgp
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// Packed slices are written as a single 'bin' object
+// holding their elements in little-endian order,
+// rather than as an array of separately prefixed
+// numbers. On little-endian hosts, the elements are
+// copied in and out of the 'bin' object in bulk.
+
+// packer is a slice of fixed-size numbers
+type packer interface {
+	// width returns the size of an element
+	width() int
+	// length returns the number of elements
+	length() int
+	// put writes the elements starting at 'i'
+	// into 'dst' until it is full
+	put(dst []byte, i int)
+	// get reads the elements starting at 'i'
+	// from 'src' until it is empty
+	get(src []byte, i int)
+}
+
+// packedSize returns the size of 'p' in bytes,
+// or a PackedSizeError if a bin object can't hold it
+func packedSize(p packer) (int, error) {
+	sz := uint64(p.length()) * uint64(p.width())
+	if sz > math.MaxUint32 {
+		return 0, PackedSizeError{Size: sz}
+	}
+	return int(sz), nil
+}
+
+// appendPacked appends the bin object for 'p' to 'b'
+func appendPacked(b []byte, p packer) ([]byte, error) {
+	sz, err := packedSize(p)
+	if err != nil {
+		return b, err
+	}
+	var o []byte
+	var n int
+	switch {
+	case sz <= math.MaxUint8:
+		o, n = ensure(b, 2+sz)
+		prefixu8(o[n:], mbin8, uint8(sz))
+		n += 2
+	case sz <= math.MaxUint16:
+		o, n = ensure(b, 3+sz)
+		prefixu16(o[n:], mbin16, uint16(sz))
+		n += 3
+	default:
+		o, n = ensure(b, 5+sz)
+		prefixu32(o[n:], mbin32, uint32(sz))
+		n += 5
+	}
+	if raw := packedBytes(p); raw != nil {
+		copy(o[n:], raw)
+	} else {
+		p.put(o[n:], 0)
+	}
+	return o, nil
+}
+
+// writePacked writes the bin object for 'p'
+func (mw *Writer) writePacked(p packer) error {
+	w, n := p.width(), p.length()
+	sz, err := packedSize(p)
+	if err != nil {
+		return err
+	}
+	err = mw.WriteBytesHeader(uint32(sz))
+	if err != nil {
+		return err
+	}
+	if raw := packedBytes(p); raw != nil {
+		_, err = mw.Write(raw)
+		return err
+	}
+	var scratch [512]byte
+	for i := 0; i < n; i += len(scratch) / w {
+		chunk := scratch[:]
+		if rest := (n - i) * w; rest < len(chunk) {
+			chunk = chunk[:rest]
+		}
+		p.put(chunk, i)
+		if _, err = mw.Write(chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readPackedBytes reads a bin object holding
+// elements of size 'w' from 'b' without copying
+func readPackedBytes(b []byte, w int) (src []byte, o []byte, err error) {
+	src, o, err = ReadBytesZC(b)
+	if err != nil {
+		return nil, b, err
+	}
+	if len(src)%w != 0 {
+		return nil, b, PackedError{Width: w, Len: len(src)}
+	}
+	return src, o, nil
+}
+
+// getPacked reads 'src' into 'p'
+func getPacked(p packer, src []byte) {
+	if raw := packedBytes(p); raw != nil {
+		copy(raw, src)
+	} else {
+		p.get(src, 0)
+	}
+}
+
+// readPackedHeader reads the header of a bin object
+// holding elements of size 'w' and returns the number
+// of elements. If the size isn't a multiple of 'w',
+// the object is skipped and a PackedError is returned.
+func (m *Reader) readPackedHeader(w int) (int, error) {
+	sz, err := m.ReadBytesHeader()
+	if err != nil {
+		return 0, err
+	}
+	if int(sz)%w != 0 {
+		if _, err = m.R.Skip(int(sz)); err != nil {
+			return 0, err
+		}
+		return 0, PackedError{Width: w, Len: int(sz)}
+	}
+	return int(sz) / w, nil
+}
+
+// readPacked reads the elements of 'p' after
+// the header read by readPackedHeader
+func (m *Reader) readPacked(p packer) error {
+	if raw := packedBytes(p); raw != nil {
+		_, err := m.R.ReadFull(raw)
+		return err
+	}
+	w, n := p.width(), p.length()
+	var scratch [512]byte
+	for i := 0; i < n; i += len(scratch) / w {
+		chunk := scratch[:]
+		if rest := (n - i) * w; rest < len(chunk) {
+			chunk = chunk[:rest]
+		}
+		if _, err := m.R.ReadFull(chunk); err != nil {
+			return err
+		}
+		p.get(chunk, i)
+	}
+	return nil
+}
+
+type packedInt16s []int16
+
+func (s packedInt16s) width() int  { return 2 }
+func (s packedInt16s) length() int { return len(s) }
+
+func (s packedInt16s) put(dst []byte, i int) {
+	for j := 0; j < len(dst); j, i = j+2, i+1 {
+		binary.LittleEndian.PutUint16(dst[j:], uint16(s[i]))
+	}
+}
+
+func (s packedInt16s) get(src []byte, i int) {
+	for j := 0; j < len(src); j, i = j+2, i+1 {
+		s[i] = int16(binary.LittleEndian.Uint16(src[j:]))
+	}
+}
+
+// AppendPackedInt16s appends a []int16 to 'b'
+// as a bin object holding its elements in
+// little-endian order. It returns a PackedSizeError
+// if the slice is larger than a bin object can hold.
+func AppendPackedInt16s(b []byte, v []int16) ([]byte, error) {
+	return appendPacked(b, packedInt16s(v))
+}
+
+// WritePackedInt16s writes a []int16 as a
+// bin object holding its elements in
+// little-endian order. It returns a PackedSizeError
+// if the slice is larger than a bin object can hold.
+func (mw *Writer) WritePackedInt16s(v []int16) error {
+	return mw.writePacked(packedInt16s(v))
+}
+
+// ReadPackedInt16s reads a []int16 written by
+// WritePackedInt16s, reusing the capacity of 'v'.
+func (m *Reader) ReadPackedInt16s(v []int16) ([]int16, error) {
+	n, err := m.readPackedHeader(2)
+	if err != nil {
+		return v, err
+	}
+	if v == nil || cap(v) < n {
+		v = make([]int16, n)
+	} else {
+		v = v[:n]
+	}
+	return v, m.readPacked(packedInt16s(v))
+}
+
+// ReadPackedInt16sBytes reads a []int16 written by
+// AppendPackedInt16s from 'b', reusing the capacity
+// of 'v', and returns the remaining bytes.
+func ReadPackedInt16sBytes(b []byte, v []int16) ([]int16, []byte, error) {
+	src, o, err := readPackedBytes(b, 2)
+	if err != nil {
+		return v, b, err
+	}
+	n := len(src) / 2
+	if v == nil || cap(v) < n {
+		v = make([]int16, n)
+	} else {
+		v = v[:n]
+	}
+	getPacked(packedInt16s(v), src)
+	return v, o, nil
+}
+
+type packedInt32s []int32
+
+func (s packedInt32s) width() int  { return 4 }
+func (s packedInt32s) length() int { return len(s) }
+
+func (s packedInt32s) put(dst []byte, i int) {
+	for j := 0; j < len(dst); j, i = j+4, i+1 {
+		binary.LittleEndian.PutUint32(dst[j:], uint32(s[i]))
+	}
+}
+
+func (s packedInt32s) get(src []byte, i int) {
+	for j := 0; j < len(src); j, i = j+4, i+1 {
+		s[i] = int32(binary.LittleEndian.Uint32(src[j:]))
+	}
+}
+
+// AppendPackedInt32s appends a []int32 to 'b'
+// as a bin object holding its elements in
+// little-endian order. It returns a PackedSizeError
+// if the slice is larger than a bin object can hold.
+func AppendPackedInt32s(b []byte, v []int32) ([]byte, error) {
+	return appendPacked(b, packedInt32s(v))
+}
+
+// WritePackedInt32s writes a []int32 as a
+// bin object holding its elements in
+// little-endian order. It returns a PackedSizeError
+// if the slice is larger than a bin object can hold.
+func (mw *Writer) WritePackedInt32s(v []int32) error {
+	return mw.writePacked(packedInt32s(v))
+}
+
+// ReadPackedInt32s reads a []int32 written by
+// WritePackedInt32s, reusing the capacity of 'v'.
+func (m *Reader) ReadPackedInt32s(v []int32) ([]int32, error) {
+	n, err := m.readPackedHeader(4)
+	if err != nil {
+		return v, err
+	}
+	if v == nil || cap(v) < n {
+		v = make([]int32, n)
+	} else {
+		v = v[:n]
+	}
+	return v, m.readPacked(packedInt32s(v))
+}
+
+// ReadPackedInt32sBytes reads a []int32 written by
+// AppendPackedInt32s from 'b', reusing the capacity
+// of 'v', and returns the remaining bytes.
+func ReadPackedInt32sBytes(b []byte, v []int32) ([]int32, []byte, error) {
+	src, o, err := readPackedBytes(b, 4)
+	if err != nil {
+		return v, b, err
+	}
+	n := len(src) / 4
+	if v == nil || cap(v) < n {
+		v = make([]int32, n)
+	} else {
+		v = v[:n]
+	}
+	getPacked(packedInt32s(v), src)
+	return v, o, nil
+}
+
+type packedInt64s []int64
+
+func (s packedInt64s) width() int  { return 8 }
+func (s packedInt64s) length() int { return len(s) }
+
+func (s packedInt64s) put(dst []byte, i int) {
+	for j := 0; j < len(dst); j, i = j+8, i+1 {
+		binary.LittleEndian.PutUint64(dst[j:], uint64(s[i]))
+	}
+}
+
+func (s packedInt64s) get(src []byte, i int) {
+	for j := 0; j < len(src); j, i = j+8, i+1 {
+		s[i] = int64(binary.LittleEndian.Uint64(src[j:]))
+	}
+}
+
+// AppendPackedInt64s appends a []int64 to 'b'
+// as a bin object holding its elements in
+// little-endian order. It returns a PackedSizeError
+// if the slice is larger than a bin object can hold.
+func AppendPackedInt64s(b []byte, v []int64) ([]byte, error) {
+	return appendPacked(b, packedInt64s(v))
+}
+
+// WritePackedInt64s writes a []int64 as a
+// bin object holding its elements in
+// little-endian order. It returns a PackedSizeError
+// if the slice is larger than a bin object can hold.
+func (mw *Writer) WritePackedInt64s(v []int64) error {
+	return mw.writePacked(packedInt64s(v))
+}
+
+// ReadPackedInt64s reads a []int64 written by
+// WritePackedInt64s, reusing the capacity of 'v'.
+func (m *Reader) ReadPackedInt64s(v []int64) ([]int64, error) {
+	n, err := m.readPackedHeader(8)
+	if err != nil {
+		return v, err
+	}
+	if v == nil || cap(v) < n {
+		v = make([]int64, n)
+	} else {
+		v = v[:n]
+	}
+	return v, m.readPacked(packedInt64s(v))
+}
+
+// ReadPackedInt64sBytes reads a []int64 written by
+// AppendPackedInt64s from 'b', reusing the capacity
+// of 'v', and returns the remaining bytes.
+func ReadPackedInt64sBytes(b []byte, v []int64) ([]int64, []byte, error) {
+	src, o, err := readPackedBytes(b, 8)
+	if err != nil {
+		return v, b, err
+	}
+	n := len(src) / 8
+	if v == nil || cap(v) < n {
+		v = make([]int64, n)
+	} else {
+		v = v[:n]
+	}
+	getPacked(packedInt64s(v), src)
+	return v, o, nil
+}
+
+type packedUint16s []uint16
+
+func (s packedUint16s) width() int  { return 2 }
+func (s packedUint16s) length() int { return len(s) }
+
+func (s packedUint16s) put(dst []byte, i int) {
+	for j := 0; j < len(dst); j, i = j+2, i+1 {
+		binary.LittleEndian.PutUint16(dst[j:], s[i])
+	}
+}
+
+func (s packedUint16s) get(src []byte, i int) {
+	for j := 0; j < len(src); j, i = j+2, i+1 {
+		s[i] = binary.LittleEndian.Uint16(src[j:])
+	}
+}
+
+// AppendPackedUint16s appends a []uint16 to 'b'
+// as a bin object holding its elements in
+// little-endian order. It returns a PackedSizeError
+// if the slice is larger than a bin object can hold.
+func AppendPackedUint16s(b []byte, v []uint16) ([]byte, error) {
+	return appendPacked(b, packedUint16s(v))
+}
+
+// WritePackedUint16s writes a []uint16 as a
+// bin object holding its elements in
+// little-endian order. It returns a PackedSizeError
+// if the slice is larger than a bin object can hold.
+func (mw *Writer) WritePackedUint16s(v []uint16) error {
+	return mw.writePacked(packedUint16s(v))
+}
+
+// ReadPackedUint16s reads a []uint16 written by
+// WritePackedUint16s, reusing the capacity of 'v'.
+func (m *Reader) ReadPackedUint16s(v []uint16) ([]uint16, error) {
+	n, err := m.readPackedHeader(2)
+	if err != nil {
+		return v, err
+	}
+	if v == nil || cap(v) < n {
+		v = make([]uint16, n)
+	} else {
+		v = v[:n]
+	}
+	return v, m.readPacked(packedUint16s(v))
+}
+
+// ReadPackedUint16sBytes reads a []uint16 written by
+// AppendPackedUint16s from 'b', reusing the capacity
+// of 'v', and returns the remaining bytes.
+func ReadPackedUint16sBytes(b []byte, v []uint16) ([]uint16, []byte, error) {
+	src, o, err := readPackedBytes(b, 2)
+	if err != nil {
+		return v, b, err
+	}
+	n := len(src) / 2
+	if v == nil || cap(v) < n {
+		v = make([]uint16, n)
+	} else {
+		v = v[:n]
+	}
+	getPacked(packedUint16s(v), src)
+	return v, o, nil
+}
+
+type packedUint32s []uint32
+
+func (s packedUint32s) width() int  { return 4 }
+func (s packedUint32s) length() int { return len(s) }
+
+func (s packedUint32s) put(dst []byte, i int) {
+	for j := 0; j < len(dst); j, i = j+4, i+1 {
+		binary.LittleEndian.PutUint32(dst[j:], s[i])
+	}
+}
+
+func (s packedUint32s) get(src []byte, i int) {
+	for j := 0; j < len(src); j, i = j+4, i+1 {
+		s[i] = binary.LittleEndian.Uint32(src[j:])
+	}
+}
+
+// AppendPackedUint32s appends a []uint32 to 'b'
+// as a bin object holding its elements in
+// little-endian order. It returns a PackedSizeError
+// if the slice is larger than a bin object can hold.
+func AppendPackedUint32s(b []byte, v []uint32) ([]byte, error) {
+	return appendPacked(b, packedUint32s(v))
+}
+
+// WritePackedUint32s writes a []uint32 as a
+// bin object holding its elements in
+// little-endian order. It returns a PackedSizeError
+// if the slice is larger than a bin object can hold.
+func (mw *Writer) WritePackedUint32s(v []uint32) error {
+	return mw.writePacked(packedUint32s(v))
+}
+
+// ReadPackedUint32s reads a []uint32 written by
+// WritePackedUint32s, reusing the capacity of 'v'.
+func (m *Reader) ReadPackedUint32s(v []uint32) ([]uint32, error) {
+	n, err := m.readPackedHeader(4)
+	if err != nil {
+		return v, err
+	}
+	if v == nil || cap(v) < n {
+		v = make([]uint32, n)
+	} else {
+		v = v[:n]
+	}
+	return v, m.readPacked(packedUint32s(v))
+}
+
+// ReadPackedUint32sBytes reads a []uint32 written by
+// AppendPackedUint32s from 'b', reusing the capacity
+// of 'v', and returns the remaining bytes.
+func ReadPackedUint32sBytes(b []byte, v []uint32) ([]uint32, []byte, error) {
+	src, o, err := readPackedBytes(b, 4)
+	if err != nil {
+		return v, b, err
+	}
+	n := len(src) / 4
+	if v == nil || cap(v) < n {
+		v = make([]uint32, n)
+	} else {
+		v = v[:n]
+	}
+	getPacked(packedUint32s(v), src)
+	return v, o, nil
+}
+
+type packedUint64s []uint64
+
+func (s packedUint64s) width() int  { return 8 }
+func (s packedUint64s) length() int { return len(s) }
+
+func (s packedUint64s) put(dst []byte, i int) {
+	for j := 0; j < len(dst); j, i = j+8, i+1 {
+		binary.LittleEndian.PutUint64(dst[j:], s[i])
+	}
+}
+
+func (s packedUint64s) get(src []byte, i int) {
+	for j := 0; j < len(src); j, i = j+8, i+1 {
+		s[i] = binary.LittleEndian.Uint64(src[j:])
+	}
+}
+
+// AppendPackedUint64s appends a []uint64 to 'b'
+// as a bin object holding its elements in
+// little-endian order. It returns a PackedSizeError
+// if the slice is larger than a bin object can hold.
+func AppendPackedUint64s(b []byte, v []uint64) ([]byte, error) {
+	return appendPacked(b, packedUint64s(v))
+}
+
+// WritePackedUint64s writes a []uint64 as a
+// bin object holding its elements in
+// little-endian order. It returns a PackedSizeError
+// if the slice is larger than a bin object can hold.
+func (mw *Writer) WritePackedUint64s(v []uint64) error {
+	return mw.writePacked(packedUint64s(v))
+}
+
+// ReadPackedUint64s reads a []uint64 written by
+// WritePackedUint64s, reusing the capacity of 'v'.
+func (m *Reader) ReadPackedUint64s(v []uint64) ([]uint64, error) {
+	n, err := m.readPackedHeader(8)
+	if err != nil {
+		return v, err
+	}
+	if v == nil || cap(v) < n {
+		v = make([]uint64, n)
+	} else {
+		v = v[:n]
+	}
+	return v, m.readPacked(packedUint64s(v))
+}
+
+// ReadPackedUint64sBytes reads a []uint64 written by
+// AppendPackedUint64s from 'b', reusing the capacity
+// of 'v', and returns the remaining bytes.
+func ReadPackedUint64sBytes(b []byte, v []uint64) ([]uint64, []byte, error) {
+	src, o, err := readPackedBytes(b, 8)
+	if err != nil {
+		return v, b, err
+	}
+	n := len(src) / 8
+	if v == nil || cap(v) < n {
+		v = make([]uint64, n)
+	} else {
+		v = v[:n]
+	}
+	getPacked(packedUint64s(v), src)
+	return v, o, nil
+}
+
+type packedFloat32s []float32
+
+func (s packedFloat32s) width() int  { return 4 }
+func (s packedFloat32s) length() int { return len(s) }
+
+func (s packedFloat32s) put(dst []byte, i int) {
+	for j := 0; j < len(dst); j, i = j+4, i+1 {
+		binary.LittleEndian.PutUint32(dst[j:], math.Float32bits(s[i]))
+	}
+}
+
+func (s packedFloat32s) get(src []byte, i int) {
+	for j := 0; j < len(src); j, i = j+4, i+1 {
+		s[i] = math.Float32frombits(binary.LittleEndian.Uint32(src[j:]))
+	}
+}
+
+// AppendPackedFloat32s appends a []float32 to 'b'
+// as a bin object holding its elements in
+// little-endian order. It returns a PackedSizeError
+// if the slice is larger than a bin object can hold.
+func AppendPackedFloat32s(b []byte, v []float32) ([]byte, error) {
+	return appendPacked(b, packedFloat32s(v))
+}
+
+// WritePackedFloat32s writes a []float32 as a
+// bin object holding its elements in
+// little-endian order. It returns a PackedSizeError
+// if the slice is larger than a bin object can hold.
+func (mw *Writer) WritePackedFloat32s(v []float32) error {
+	return mw.writePacked(packedFloat32s(v))
+}
+
+// ReadPackedFloat32s reads a []float32 written by
+// WritePackedFloat32s, reusing the capacity of 'v'.
+func (m *Reader) ReadPackedFloat32s(v []float32) ([]float32, error) {
+	n, err := m.readPackedHeader(4)
+	if err != nil {
+		return v, err
+	}
+	if v == nil || cap(v) < n {
+		v = make([]float32, n)
+	} else {
+		v = v[:n]
+	}
+	return v, m.readPacked(packedFloat32s(v))
+}
+
+// ReadPackedFloat32sBytes reads a []float32 written by
+// AppendPackedFloat32s from 'b', reusing the capacity
+// of 'v', and returns the remaining bytes.
+func ReadPackedFloat32sBytes(b []byte, v []float32) ([]float32, []byte, error) {
+	src, o, err := readPackedBytes(b, 4)
+	if err != nil {
+		return v, b, err
+	}
+	n := len(src) / 4
+	if v == nil || cap(v) < n {
+		v = make([]float32, n)
+	} else {
+		v = v[:n]
+	}
+	getPacked(packedFloat32s(v), src)
+	return v, o, nil
+}
+
+type packedFloat64s []float64
+
+func (s packedFloat64s) width() int  { return 8 }
+func (s packedFloat64s) length() int { return len(s) }
+
+func (s packedFloat64s) put(dst []byte, i int) {
+	for j := 0; j < len(dst); j, i = j+8, i+1 {
+		binary.LittleEndian.PutUint64(dst[j:], math.Float64bits(s[i]))
+	}
+}
+
+func (s packedFloat64s) get(src []byte, i int) {
+	for j := 0; j < len(src); j, i = j+8, i+1 {
+		s[i] = math.Float64frombits(binary.LittleEndian.Uint64(src[j:]))
+	}
+}
+
+// AppendPackedFloat64s appends a []float64 to 'b'
+// as a bin object holding its elements in
+// little-endian order. It returns a PackedSizeError
+// if the slice is larger than a bin object can hold.
+func AppendPackedFloat64s(b []byte, v []float64) ([]byte, error) {
+	return appendPacked(b, packedFloat64s(v))
+}
+
+// WritePackedFloat64s writes a []float64 as a
+// bin object holding its elements in
+// little-endian order. It returns a PackedSizeError
+// if the slice is larger than a bin object can hold.
+func (mw *Writer) WritePackedFloat64s(v []float64) error {
+	return mw.writePacked(packedFloat64s(v))
+}
+
+// ReadPackedFloat64s reads a []float64 written by
+// WritePackedFloat64s, reusing the capacity of 'v'.
+func (m *Reader) ReadPackedFloat64s(v []float64) ([]float64, error) {
+	n, err := m.readPackedHeader(8)
+	if err != nil {
+		return v, err
+	}
+	if v == nil || cap(v) < n {
+		v = make([]float64, n)
+	} else {
+		v = v[:n]
+	}
+	return v, m.readPacked(packedFloat64s(v))
+}
+
+// ReadPackedFloat64sBytes reads a []float64 written by
+// AppendPackedFloat64s from 'b', reusing the capacity
+// of 'v', and returns the remaining bytes.
+func ReadPackedFloat64sBytes(b []byte, v []float64) ([]float64, []byte, error) {
+	src, o, err := readPackedBytes(b, 8)
+	if err != nil {
+		return v, b, err
+	}
+	n := len(src) / 8
+	if v == nil || cap(v) < n {
+		v = make([]float64, n)
+	} else {
+		v = v[:n]
+	}
+	getPacked(packedFloat64s(v), src)
+	return v, o, nil
+}
@@ -0,0 +1,155 @@
+package msgp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestPackedFloat64s(t *testing.T) {
+	for _, n := range []int{0, 1, 31, 100, 10000} {
+		v := make([]float64, n)
+		for i := range v {
+			v[i] = float64(i) * -1.5
+		}
+		want := make([]byte, n*8)
+		for i := range v {
+			binary.LittleEndian.PutUint64(want[i*8:], math.Float64bits(v[i]))
+		}
+
+		b, err := AppendPackedFloat64s(nil, v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, o, err := ReadBytesBytes(b, nil)
+		if err != nil || len(o) != 0 {
+			t.Fatalf("n=%d: %v, %d bytes left", n, err, len(o))
+		}
+		if !bytes.Equal(data, want) {
+			t.Errorf("n=%d: elements aren't in little-endian order", n)
+		}
+		if len(b) > BytesPrefixSize+n*8 {
+			t.Errorf("n=%d: %d bytes is more than the packed size", n, len(b))
+		}
+
+		got, o, err := ReadPackedFloat64sBytes(b, nil)
+		if err != nil || len(o) != 0 {
+			t.Fatalf("n=%d: %v, %d bytes left", n, err, len(o))
+		}
+		if got == nil || !reflect.DeepEqual(got, v) {
+			t.Errorf("n=%d: ReadPackedFloat64sBytes got %v", n, got)
+		}
+
+		var buf bytes.Buffer
+		w := NewWriterSize(&buf, 64)
+		if err = w.WritePackedFloat64s(v); err != nil {
+			t.Fatal(err)
+		}
+		w.Flush()
+		if !bytes.Equal(buf.Bytes(), b) {
+			t.Errorf("n=%d: WritePackedFloat64s and AppendPackedFloat64s differ", n)
+		}
+		got, err = NewReaderSize(&buf, 64).ReadPackedFloat64s(make([]float64, 3, 20))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, v) {
+			t.Errorf("n=%d: ReadPackedFloat64s got %v", n, got)
+		}
+	}
+}
+
+func TestPackedInts(t *testing.T) {
+	i16 := []int16{0, -1, math.MinInt16, math.MaxInt16}
+	u32 := []uint32{0, 1, math.MaxUint32}
+	i64 := []int64{math.MinInt64, -2, math.MaxInt64}
+	f32 := []float32{-0.5, float32(math.Inf(1)), math.SmallestNonzeroFloat32}
+
+	b, _ := AppendPackedInt16s(nil, i16)
+	b, _ = AppendPackedUint32s(b, u32)
+	b, _ = AppendPackedInt64s(b, i64)
+	b, _ = AppendPackedFloat32s(b, f32)
+
+	gi16, b, err := ReadPackedInt16sBytes(b, nil)
+	if err != nil || !reflect.DeepEqual(gi16, i16) {
+		t.Errorf("int16: got %v, %v", gi16, err)
+	}
+	gu32, b, err := ReadPackedUint32sBytes(b, nil)
+	if err != nil || !reflect.DeepEqual(gu32, u32) {
+		t.Errorf("uint32: got %v, %v", gu32, err)
+	}
+	gi64, b, err := ReadPackedInt64sBytes(b, nil)
+	if err != nil || !reflect.DeepEqual(gi64, i64) {
+		t.Errorf("int64: got %v, %v", gi64, err)
+	}
+	gf32, b, err := ReadPackedFloat32sBytes(b, nil)
+	if err != nil || !reflect.DeepEqual(gf32, f32) {
+		t.Errorf("float32: got %v, %v", gf32, err)
+	}
+	if len(b) != 0 {
+		t.Errorf("%d bytes left", len(b))
+	}
+}
+
+// the element-wise conversion must match
+// the bulk copy used on little-endian hosts
+func TestPackedPutGet(t *testing.T) {
+	v := packedInt32s{1, -2, 1 << 30, math.MinInt32}
+	dst := make([]byte, 16)
+	v.put(dst, 0)
+	for i := range v {
+		if got := int32(binary.LittleEndian.Uint32(dst[i*4:])); got != v[i] {
+			t.Errorf("element %d: put %d; want %d", i, got, v[i])
+		}
+	}
+	out := make(packedInt32s, 4)
+	out.get(dst[8:], 2)
+	if out[2] != v[2] || out[3] != v[3] || out[0] != 0 {
+		t.Errorf("get: %v", out)
+	}
+}
+
+func TestPackedError(t *testing.T) {
+	b := AppendBytes(nil, []byte{1, 2, 3})
+	b = AppendInt(b, 7)
+	if _, o, err := ReadPackedUint16sBytes(b, nil); err != (PackedError{Width: 2, Len: 3}) || len(o) != len(b) {
+		t.Errorf("got %v with %d bytes left", err, len(o))
+	}
+
+	// the Reader skips the object
+	r := NewReader(bytes.NewReader(b))
+	if _, err := r.ReadPackedUint16s(nil); err != (PackedError{Width: 2, Len: 3}) {
+		t.Errorf("got %v", err)
+	}
+	if n, err := r.ReadInt(); n != 7 || err != nil {
+		t.Errorf("after the error, read %d, %v", n, err)
+	}
+}
+
+// hugePacked is a packer too large for a bin object,
+// which fails if its elements are ever copied
+type hugePacked struct{}
+
+func (hugePacked) width() int            { return 8 }
+func (hugePacked) length() int           { return 1 << 29 }
+func (hugePacked) put(dst []byte, i int) { panic("put") }
+func (hugePacked) get(src []byte, i int) { panic("get") }
+
+func TestPackedSizeError(t *testing.T) {
+	want := PackedSizeError{Size: 1 << 32}
+	b := []byte{1}
+	if o, err := appendPacked(b, hugePacked{}); err != want || len(o) != 1 {
+		t.Errorf("got %v with %d bytes", err, len(o))
+	}
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	if err := w.writePacked(hugePacked{}); err != want {
+		t.Errorf("got %v", err)
+	}
+	w.Flush()
+	if buf.Len() != 0 {
+		t.Errorf("%d bytes were written", buf.Len())
+	}
+}
@@ -13,3 +13,9 @@ func UnsafeString(b []byte) string {
 func UnsafeBytes(s string) []byte {
 	return []byte(s)
 }
+
+// packed slices are always
+// converted one element at a time
+func packedBytes(p packer) []byte {
+	return nil
+}
@@ -79,6 +79,7 @@ func (t Type) String() string {
 }
 
 func freeR(m *Reader) {
+	m.intern = nil
 	readerPool.Put(m)
 }
 
@@ -117,6 +118,7 @@ func NewReader(r io.Reader) *Reader {
 	} else {
 		p.R.Reset(r)
 	}
+	p.body = nil
 	return p
 }
 
@@ -139,6 +141,8 @@ type Reader struct {
 	// within R.
 	R       *fwd.Reader
 	scratch []byte
+	intern  *Interner
+	body    *bodyStream // set once BinReader or StrReader is called
 }
 
 // Read implements `io.Reader`
@@ -202,7 +206,10 @@ func (m *Reader) ReadFull(p []byte) (int, error) {
 }
 
 // Reset resets the underlying reader.
-func (m *Reader) Reset(r io.Reader) { m.R.Reset(r) }
+func (m *Reader) Reset(r io.Reader) {
+	m.R.Reset(r)
+	m.body = nil
+}
 
 // Buffered returns the number of bytes currently in the read buffer.
 func (m *Reader) Buffered() int { return m.R.Buffered() }
@@ -230,7 +237,7 @@ func (m *Reader) NextType() (Type, error) {
 			return Complex64Type, nil
 		case Complex128Extension:
 			return Complex128Type, nil
-		case TimeExtension:
+		case TimeExtension, TimestampExtension:
 			return TimeType, nil
 		}
 	}
@@ -1137,6 +1144,14 @@ fill:
 		s, err = "", nil
 		return
 	}
+	if m.intern != nil && read <= int64(m.R.BufferSize()) {
+		p, err = m.R.Next(int(read))
+		if err != nil {
+			return
+		}
+		s = m.intern.Intern(p)
+		return
+	}
 	// reading into the memory
 	// that will become the string
 	// itself has vastly superior
@@ -1233,24 +1248,29 @@ func (m *Reader) ReadMapStrIntf(mp map[string]interface{}) (err error) {
 }
 
 // ReadTime reads a time.Time object from the reader.
+// Both the MessagePack timestamp extension and the
+// encoding written by WriteTime are accepted.
 // The returned time's location will be set to time.Local.
 func (m *Reader) ReadTime() (t time.Time, err error) {
 	var p []byte
-	p, err = m.R.Peek(15)
+	p, err = m.R.Peek(1)
 	if err != nil {
 		return
 	}
-	if p[0] != mext8 || p[1] != 12 {
+	n := timeLen(p[0])
+	if n == 0 {
 		err = badPrefix(TimeType, p[0])
 		return
 	}
-	if int8(p[2]) != TimeExtension {
-		err = errExt(int8(p[2]), TimeExtension)
+	p, err = m.R.Peek(n)
+	if err != nil {
+		return
+	}
+	t, err = getTime(p)
+	if err != nil {
 		return
 	}
-	sec, nsec := getUnix(p[3:])
-	t = time.Unix(sec, int64(nsec)).Local()
-	_, err = m.R.Skip(15)
+	_, err = m.R.Skip(n)
 	return
 }
 
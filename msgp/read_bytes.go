@@ -19,15 +19,18 @@ func NextType(b []byte) Type {
 	}
 	spec := sizes[b[0]]
 	t := spec.typ
-	if t == ExtensionType && len(b) > int(spec.size) {
-		var tp int8
+	if t == ExtensionType {
+		// the extension type is the last byte of
+		// the header, or the second byte of a fixext
+		i := int(spec.size) - 1
 		if spec.extra == constsize {
-			tp = int8(b[1])
-		} else {
-			tp = int8(b[spec.size-1])
+			i = 1
+		}
+		if len(b) <= i {
+			return t
 		}
-		switch tp {
-		case TimeExtension:
+		switch int8(b[i]) {
+		case TimeExtension, TimestampExtension:
 			return TimeType
 		case Complex128Extension:
 			return Complex128Type
@@ -963,27 +966,32 @@ func ReadComplex64Bytes(b []byte) (c complex64, o []byte, err error) {
 
 // ReadTimeBytes reads a time.Time
 // extension object from 'b' and returns the
-// remaining bytes.
+// remaining bytes. Both the MessagePack timestamp
+// extension and the encoding written by AppendTime
+// are accepted.
 // Possible errors:
 // - ErrShortBytes (not enough bytes in 'b')
-// - TypeError{} (object not a complex64)
+// - TypeError{} (object not a time.Time)
 // - ExtensionTypeError{} (object an extension of the correct size, but not a time.Time)
 func ReadTimeBytes(b []byte) (t time.Time, o []byte, err error) {
-	if len(b) < 15 {
+	if len(b) < 1 {
 		err = ErrShortBytes
 		return
 	}
-	if b[0] != mext8 || b[1] != 12 {
+	n := timeLen(b[0])
+	if n == 0 {
 		err = badPrefix(TimeType, b[0])
 		return
 	}
-	if int8(b[2]) != TimeExtension {
-		err = errExt(int8(b[2]), TimeExtension)
+	if len(b) < n {
+		err = ErrShortBytes
+		return
+	}
+	t, err = getTime(b)
+	if err != nil {
 		return
 	}
-	sec, nsec := getUnix(b[3:])
-	t = time.Unix(sec, int64(nsec)).Local()
-	o = b[15:]
+	o = b[n:]
 	return
 }
 
@@ -0,0 +1,129 @@
+package rpc
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/bytedance/msgp/msgp"
+)
+
+// writeBody writes 'body' with its
+// EncodeMsg method if it has one, or
+// otherwise with WriteIntf
+func writeBody(w *msgp.Writer, body interface{}) error {
+	if e, ok := body.(msgp.Encodable); ok {
+		return e.EncodeMsg(w)
+	}
+	return w.WriteIntf(body)
+}
+
+// readBody reads into 'body' with its
+// DecodeMsg method if it has one, or
+// otherwise with ReadIntf and reflection.
+// A nil body is skipped.
+func readBody(r *msgp.Reader, body interface{}) error {
+	if body == nil {
+		return r.Skip()
+	}
+	if d, ok := body.(msgp.Decodable); ok {
+		return d.DecodeMsg(r)
+	}
+	dst := reflect.ValueOf(body)
+	if dst.Kind() != reflect.Ptr || dst.IsNil() {
+		return fmt.Errorf("msgp/rpc: can't decode into non-pointer %T", body)
+	}
+	v, err := r.ReadIntf()
+	if err != nil {
+		return err
+	}
+	return setValue(dst.Elem(), v)
+}
+
+// setValue sets 'dst' to the value 'v'
+// returned by ReadIntf, converting numbers
+// and building slices, arrays, maps and
+// pointers of the type of 'dst'
+func setValue(dst reflect.Value, v interface{}) error {
+	if v == nil {
+		dst.Set(reflect.Zero(dst.Type()))
+		return nil
+	}
+	src := reflect.ValueOf(v)
+	if src.Type().AssignableTo(dst.Type()) {
+		dst.Set(src)
+		return nil
+	}
+	switch dst.Kind() {
+	case reflect.Ptr:
+		p := reflect.New(dst.Type().Elem())
+		if err := setValue(p.Elem(), v); err != nil {
+			return err
+		}
+		dst.Set(p)
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		switch src.Kind() {
+		case reflect.Int64:
+			if k := dst.Kind(); k >= reflect.Int && k <= reflect.Int64 && dst.OverflowInt(src.Int()) {
+				return msgp.IntOverflow{Value: src.Int(), FailedBitsize: dst.Type().Bits()}
+			}
+			if k := dst.Kind(); k >= reflect.Uint && k <= reflect.Uint64 && src.Int() < 0 {
+				return msgp.UintBelowZero{Value: src.Int()}
+			}
+		case reflect.Uint64:
+			if k := dst.Kind(); k >= reflect.Uint && k <= reflect.Uint64 && dst.OverflowUint(src.Uint()) {
+				return msgp.UintOverflow{Value: src.Uint(), FailedBitsize: dst.Type().Bits()}
+			}
+		}
+		switch src.Kind() {
+		case reflect.Int64, reflect.Uint64, reflect.Float32, reflect.Float64:
+			dst.Set(src.Convert(dst.Type()))
+			return nil
+		}
+	case reflect.String:
+		if src.Kind() == reflect.String {
+			dst.SetString(src.String())
+			return nil
+		}
+	case reflect.Slice:
+		if b, ok := v.([]byte); ok && dst.Type().Elem().Kind() == reflect.Uint8 {
+			dst.Set(reflect.ValueOf(b).Convert(dst.Type()))
+			return nil
+		}
+		if s, ok := v.([]interface{}); ok {
+			out := reflect.MakeSlice(dst.Type(), len(s), len(s))
+			for i := range s {
+				if err := setValue(out.Index(i), s[i]); err != nil {
+					return err
+				}
+			}
+			dst.Set(out)
+			return nil
+		}
+	case reflect.Array:
+		if s, ok := v.([]interface{}); ok && len(s) == dst.Len() {
+			for i := range s {
+				if err := setValue(dst.Index(i), s[i]); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	case reflect.Map:
+		if m, ok := v.(map[string]interface{}); ok && dst.Type().Key().Kind() == reflect.String {
+			out := reflect.MakeMapWithSize(dst.Type(), len(m))
+			for k, mv := range m {
+				val := reflect.New(dst.Type().Elem()).Elem()
+				if err := setValue(val, mv); err != nil {
+					return err
+				}
+				out.SetMapIndex(reflect.ValueOf(k).Convert(dst.Type().Key()), val)
+			}
+			dst.Set(out)
+			return nil
+		}
+	}
+	return fmt.Errorf("msgp/rpc: can't decode %T into %s", v, dst.Type())
+}
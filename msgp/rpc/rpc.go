@@ -0,0 +1,209 @@
+// Package rpc implements the net/rpc ServerCodec and
+// ClientCodec interfaces over MessagePack.
+//
+// Request headers are written as the array
+// [ServiceMethod, Seq], and response headers as the array
+// [ServiceMethod, Seq, Error], each followed by the body.
+// Bodies that implement msgp.Encodable and msgp.Decodable
+// use those methods; others are written with
+// (*msgp.Writer).WriteIntf and read with
+// (*msgp.Reader).ReadIntf and reflection.
+package rpc
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"net/rpc"
+
+	"github.com/bytedance/msgp/msgp"
+)
+
+// encoder encodes each message in full before it
+// is written to the connection, so that a body that
+// can't be encoded doesn't leave part of its message
+// to be sent with the next one.
+type encoder struct {
+	conn io.Writer
+	buf  bytes.Buffer
+	w    *msgp.Writer // writes to buf
+}
+
+func newEncoder(conn io.Writer) *encoder {
+	e := &encoder{conn: conn}
+	e.w = msgp.NewWriter(&e.buf)
+	return e
+}
+
+// send writes the message encoded so far to
+// the connection, or discards it if 'err' is
+// an error from encoding it.
+func (e *encoder) send(err error) error {
+	if err == nil {
+		err = e.w.Flush()
+	}
+	if err == nil {
+		_, err = e.conn.Write(e.buf.Bytes())
+	}
+	e.buf.Reset()
+	e.w.Reset(&e.buf)
+	return err
+}
+
+type serverCodec struct {
+	rwc io.ReadWriteCloser
+	r   *msgp.Reader
+	enc *encoder
+}
+
+// NewServerCodec returns a new rpc.ServerCodec
+// using MessagePack on 'conn'.
+func NewServerCodec(conn io.ReadWriteCloser) rpc.ServerCodec {
+	return &serverCodec{
+		rwc: conn,
+		r:   msgp.NewReader(conn),
+		enc: newEncoder(conn),
+	}
+}
+
+func (c *serverCodec) ReadRequestHeader(r *rpc.Request) error {
+	sz, err := c.r.ReadArrayHeader()
+	if err != nil {
+		return err
+	}
+	if sz != 2 {
+		return msgp.ArrayError{Wanted: 2, Got: sz}
+	}
+	if r.ServiceMethod, err = c.r.ReadString(); err != nil {
+		return err
+	}
+	r.Seq, err = c.r.ReadUint64()
+	return err
+}
+
+func (c *serverCodec) ReadRequestBody(body interface{}) error {
+	return readBody(c.r, body)
+}
+
+// WriteResponse writes the response 'r'. If
+// its body can't be encoded, an error response
+// is sent instead, and the error is returned.
+func (c *serverCodec) WriteResponse(r *rpc.Response, body interface{}) error {
+	if r.Error != "" {
+		// net/rpc sends an empty struct
+		// as the body of errors
+		body = nil
+	}
+	err := writeResponse(c.enc.w, r, body)
+	if err == nil {
+		return c.enc.send(nil)
+	}
+	c.enc.send(err)
+	eresp := *r
+	eresp.Error = "msgp/rpc: encoding the response: " + err.Error()
+	if serr := c.enc.send(writeResponse(c.enc.w, &eresp, nil)); serr != nil {
+		return serr
+	}
+	return err
+}
+
+func writeResponse(w *msgp.Writer, r *rpc.Response, body interface{}) error {
+	err := w.WriteArrayHeader(3)
+	if err != nil {
+		return err
+	}
+	if err = w.WriteString(r.ServiceMethod); err != nil {
+		return err
+	}
+	if err = w.WriteUint64(r.Seq); err != nil {
+		return err
+	}
+	if err = w.WriteString(r.Error); err != nil {
+		return err
+	}
+	return writeBody(w, body)
+}
+
+func (c *serverCodec) Close() error { return c.rwc.Close() }
+
+type clientCodec struct {
+	rwc io.ReadWriteCloser
+	r   *msgp.Reader
+	enc *encoder
+}
+
+// NewClientCodec returns a new rpc.ClientCodec
+// using MessagePack on 'conn'.
+func NewClientCodec(conn io.ReadWriteCloser) rpc.ClientCodec {
+	return &clientCodec{
+		rwc: conn,
+		r:   msgp.NewReader(conn),
+		enc: newEncoder(conn),
+	}
+}
+
+// WriteRequest writes the request 'r'. Nothing
+// is sent if its body can't be encoded.
+func (c *clientCodec) WriteRequest(r *rpc.Request, body interface{}) error {
+	return c.enc.send(writeRequest(c.enc.w, r, body))
+}
+
+func writeRequest(w *msgp.Writer, r *rpc.Request, body interface{}) error {
+	err := w.WriteArrayHeader(2)
+	if err != nil {
+		return err
+	}
+	if err = w.WriteString(r.ServiceMethod); err != nil {
+		return err
+	}
+	if err = w.WriteUint64(r.Seq); err != nil {
+		return err
+	}
+	return writeBody(w, body)
+}
+
+func (c *clientCodec) ReadResponseHeader(r *rpc.Response) error {
+	sz, err := c.r.ReadArrayHeader()
+	if err != nil {
+		return err
+	}
+	if sz != 3 {
+		return msgp.ArrayError{Wanted: 3, Got: sz}
+	}
+	if r.ServiceMethod, err = c.r.ReadString(); err != nil {
+		return err
+	}
+	if r.Seq, err = c.r.ReadUint64(); err != nil {
+		return err
+	}
+	r.Error, err = c.r.ReadString()
+	return err
+}
+
+func (c *clientCodec) ReadResponseBody(body interface{}) error {
+	return readBody(c.r, body)
+}
+
+func (c *clientCodec) Close() error { return c.rwc.Close() }
+
+// NewClient returns a new rpc.Client
+// using MessagePack on 'conn'.
+func NewClient(conn io.ReadWriteCloser) *rpc.Client {
+	return rpc.NewClientWithCodec(NewClientCodec(conn))
+}
+
+// Dial connects to a MessagePack RPC
+// server at the given network address.
+func Dial(network, address string) (*rpc.Client, error) {
+	conn, err := net.Dial(network, address)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(conn), nil
+}
+
+// ServeConn runs the rpc.DefaultServer on 'conn'
+// using MessagePack, until the client hangs up.
+func ServeConn(conn io.ReadWriteCloser) {
+	rpc.ServeCodec(NewServerCodec(conn))
+}
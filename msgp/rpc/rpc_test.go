@@ -0,0 +1,158 @@
+package rpc
+
+import (
+	"errors"
+	"net"
+	"net/rpc"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bytedance/msgp/msgp"
+)
+
+// Args implements msgp.Encodable and msgp.Decodable
+type Args struct {
+	A, B int64
+}
+
+func (a *Args) EncodeMsg(w *msgp.Writer) error {
+	if err := w.WriteArrayHeader(2); err != nil {
+		return err
+	}
+	if err := w.WriteInt64(a.A); err != nil {
+		return err
+	}
+	return w.WriteInt64(a.B)
+}
+
+func (a *Args) DecodeMsg(r *msgp.Reader) (err error) {
+	sz, err := r.ReadArrayHeader()
+	if err != nil {
+		return err
+	}
+	if sz != 2 {
+		return msgp.ArrayError{Wanted: 2, Got: sz}
+	}
+	if a.A, err = r.ReadInt64(); err != nil {
+		return err
+	}
+	a.B, err = r.ReadInt64()
+	return err
+}
+
+type Arith struct{}
+
+func (Arith) Add(args *Args, reply *Args) error {
+	reply.A = args.A + args.B
+	return nil
+}
+
+func (Arith) Double(x int, reply *int) error {
+	*reply = 2 * x
+	return nil
+}
+
+func (Arith) Fields(s string, reply *map[string][]uint16) error {
+	*reply = make(map[string][]uint16)
+	for _, f := range strings.Fields(s) {
+		(*reply)[f] = append((*reply)[f], uint16(len(f)))
+	}
+	return nil
+}
+
+func (Arith) Fail(x int, reply *int) error {
+	return errors.New("failed")
+}
+
+// Unencodable replies with a
+// value that can't be encoded
+func (Arith) Unencodable(x int, reply *struct{ X int }) error {
+	reply.X = x
+	return nil
+}
+
+func testClient(t *testing.T) *rpc.Client {
+	srv := rpc.NewServer()
+	if err := srv.Register(Arith{}); err != nil {
+		t.Fatal(err)
+	}
+	cli, conn := net.Pipe()
+	go srv.ServeCodec(NewServerCodec(conn))
+	return NewClient(cli)
+}
+
+func TestRPC(t *testing.T) {
+	client := testClient(t)
+	defer client.Close()
+
+	var sum Args
+	if err := client.Call("Arith.Add", &Args{A: 3, B: 4}, &sum); err != nil || sum.A != 7 {
+		t.Errorf("Add: got %d, %v", sum.A, err)
+	}
+
+	var d int
+	if err := client.Call("Arith.Double", 21, &d); err != nil || d != 42 {
+		t.Errorf("Double: got %d, %v", d, err)
+	}
+
+	var fields map[string][]uint16
+	want := map[string][]uint16{"a": {1, 1}, "bc": {2}}
+	if err := client.Call("Arith.Fields", "a bc a", &fields); err != nil || !reflect.DeepEqual(fields, want) {
+		t.Errorf("Fields: got %v, %v", fields, err)
+	}
+
+	// errors don't break the connection
+	err := client.Call("Arith.Fail", 1, &d)
+	if se, ok := err.(rpc.ServerError); !ok || se != "failed" {
+		t.Errorf("Fail: got %v", err)
+	}
+	if err = client.Call("Arith.Missing", 1, &d); err == nil {
+		t.Error("expected an error for an unknown method")
+	}
+	if err = client.Call("Arith.Double", 2, &d); err != nil || d != 4 {
+		t.Errorf("Double after errors: got %d, %v", d, err)
+	}
+}
+
+func TestRPCUnencodable(t *testing.T) {
+	client := testClient(t)
+	defer client.Close()
+
+	// a request body that can't be encoded
+	var d int
+	if err := client.Call("Arith.Double", struct{ X int }{1}, &d); err == nil {
+		t.Error("sent a body that can't be encoded")
+	}
+	if err := client.Call("Arith.Double", 21, &d); err != nil || d != 42 {
+		t.Errorf("Double after an unencodable request: got %d, %v", d, err)
+	}
+
+	// a response body that can't be encoded
+	var s struct{ X int }
+	if _, ok := client.Call("Arith.Unencodable", 1, &s).(rpc.ServerError); !ok {
+		t.Error("expected a server error for an unencodable response")
+	}
+	if err := client.Call("Arith.Double", 2, &d); err != nil || d != 4 {
+		t.Errorf("Double after an unencodable response: got %d, %v", d, err)
+	}
+}
+
+func TestSetValue(t *testing.T) {
+	var i8 int8
+	if err := setValue(reflect.ValueOf(&i8).Elem(), int64(300)); err == nil {
+		t.Error("expected an overflow error")
+	}
+	var u uint
+	if err := setValue(reflect.ValueOf(&u).Elem(), int64(-1)); err == nil {
+		t.Error("expected an error for a negative uint")
+	}
+	var p *[2]float32
+	if err := setValue(reflect.ValueOf(&p).Elem(), []interface{}{int64(1), 2.5}); err != nil || *p != [2]float32{1, 2.5} {
+		t.Errorf("got %v, %v", p, err)
+	}
+	var s string
+	if err := setValue(reflect.ValueOf(&s).Elem(), int64(65)); err == nil {
+		t.Error("expected an error decoding an int into a string")
+	}
+}
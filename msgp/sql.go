@@ -0,0 +1,77 @@
+package msgp
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// MarshalUnmarshaler is the interface fulfilled
+// by pointers to generated types when both
+// Marshal and Unmarshal methods are generated.
+type MarshalUnmarshaler interface {
+	Marshaler
+	Unmarshaler
+}
+
+// SQL stores Msg in a database as its MessagePack
+// encoding, which fits in a BLOB (or bytea) column.
+// It implements sql.Scanner and driver.Valuer for
+// types that don't, e.g.
+//
+//	err := row.Scan(msgp.SQL{Msg: &v})
+//	_, err = db.Exec(query, msgp.SQL{Msg: &v})
+//
+// The generator writes Scan and Value methods
+// that use SQL when it is run with -sql. (With
+// Go 1.18 and later, SQLOf holds a value rather
+// than a pointer.)
+type SQL struct {
+	Msg MarshalUnmarshaler
+}
+
+// Value implements driver.Valuer. A nil
+// Msg is stored as NULL.
+func (s SQL) Value() (driver.Value, error) {
+	if s.Msg == nil {
+		return nil, nil
+	}
+	if v := reflect.ValueOf(s.Msg); v.Kind() == reflect.Ptr && v.IsNil() {
+		return nil, nil
+	}
+	b, err := s.Msg.MarshalMsg(nil)
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Scan implements sql.Scanner. NULL sets
+// Msg to its zero value. Since drivers may
+// reuse the bytes of 'src', they are copied
+// before they are unmarshaled. A nil Msg
+// returns an error.
+func (s SQL) Scan(src interface{}) error {
+	if s.Msg == nil {
+		return errors.New("msgp: can't scan into a nil SQL.Msg")
+	}
+	if v := reflect.ValueOf(s.Msg); v.Kind() == reflect.Ptr && v.IsNil() {
+		return fmt.Errorf("msgp: can't scan into a nil %T", s.Msg)
+	}
+	var b []byte
+	switch src := src.(type) {
+	case nil:
+		v := reflect.ValueOf(s.Msg).Elem()
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	case []byte:
+		b = append([]byte(nil), src...)
+	case string:
+		b = []byte(src)
+	default:
+		return fmt.Errorf("msgp: can't scan %T into %T", src, s.Msg)
+	}
+	_, err := s.Msg.UnmarshalMsg(b)
+	return err
+}
@@ -0,0 +1,38 @@
+//go:build go1.18
+// +build go1.18
+
+package msgp
+
+import (
+	"database/sql/driver"
+)
+
+// SQLOf holds a value of the type T, which is
+// stored in a database as its MessagePack encoding
+// like it is by SQL. Since it holds the value
+// rather than a pointer, it can be a field of a
+// struct, or scanned into directly, e.g.
+//
+//	var acct msgp.SQLOf[Account, *Account]
+//	err := row.Scan(&acct)
+//	_, err = db.Exec(query, acct)
+//
+// (It can't be named SQL, which is taken by the
+// form that works before Go 1.18.)
+type SQLOf[T any, P interface {
+	*T
+	MarshalUnmarshaler
+}] struct {
+	V T
+}
+
+// Value implements driver.Valuer.
+func (s SQLOf[T, P]) Value() (driver.Value, error) {
+	return SQL{Msg: P(&s.V)}.Value()
+}
+
+// Scan implements sql.Scanner. NULL
+// sets V to its zero value.
+func (s *SQLOf[T, P]) Scan(src interface{}) error {
+	return SQL{Msg: P(&s.V)}.Scan(src)
+}
@@ -0,0 +1,31 @@
+//go:build go1.18
+// +build go1.18
+
+package msgp
+
+import (
+	"bytes"
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+)
+
+var (
+	_ sql.Scanner   = (*SQLOf[Raw, *Raw])(nil)
+	_ driver.Valuer = SQLOf[Raw, *Raw]{}
+)
+
+func TestSQLOf(t *testing.T) {
+	in := SQLOf[Raw, *Raw]{V: Raw(AppendString(nil, "stored"))}
+	v, err := in.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out SQLOf[Raw, *Raw]
+	if err = out.Scan(v); err != nil || !bytes.Equal(out.V, in.V) {
+		t.Errorf("got %x, %v", []byte(out.V), err)
+	}
+	if err = out.Scan(nil); err != nil || out.V != nil {
+		t.Errorf("got %x, %v after NULL", []byte(out.V), err)
+	}
+}
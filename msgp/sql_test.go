@@ -0,0 +1,45 @@
+package msgp
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSQL(t *testing.T) {
+	in := Raw(AppendString(nil, "stored"))
+	v, err := SQL{Msg: &in}.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, ok := v.([]byte); !ok || !bytes.Equal(b, in) {
+		t.Fatalf("Value returned %v", v)
+	}
+
+	var out Raw
+	if err = (SQL{Msg: &out}).Scan(v); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, in) {
+		t.Errorf("got %x; want %x", []byte(out), []byte(in))
+	}
+	if err = (SQL{Msg: &out}).Scan(nil); err != nil || out != nil {
+		t.Errorf("got %x, %v after NULL", []byte(out), err)
+	}
+	if err = (SQL{Msg: &out}).Scan(3.5); err == nil {
+		t.Error("scanned a float64")
+	}
+
+	// nil is NULL
+	var p *Raw
+	for _, s := range []SQL{{}, {Msg: p}} {
+		if v, err = s.Value(); v != nil || err != nil {
+			t.Errorf("got %v, %v for %#v", v, err, s)
+		}
+		// but can't be scanned into
+		for _, src := range []interface{}{nil, []byte(in)} {
+			if err = s.Scan(src); err == nil {
+				t.Errorf("scanned %v into %#v", src, s)
+			}
+		}
+	}
+}
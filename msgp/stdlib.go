@@ -0,0 +1,485 @@
+package msgp
+
+import (
+	"encoding/json"
+	mathbig "math/big"
+	"net"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// This file provides encoders and decoders for
+// standard library types that the code generator
+// supports natively. Their wire forms are:
+//
+//	time.Duration   int; nanoseconds
+//	*big.Int        bin; big-endian two's complement, minimal length (0 is empty)
+//	*big.Float      bin; the output of (*big.Float).GobEncode
+//	net.IP          bin; 4 bytes for IPv4 addresses, 16 bytes otherwise
+//	*url.URL        str; (*url.URL).String()
+//	json.Number     int, uint or float64 if that is read back as the same text, str otherwise
+//	*time.Location  str; the location name, which must be known to time.LoadLocation
+//
+// Nil pointers are written as nil, and nil
+// is read back as a nil pointer. URLs and
+// locations are only supported as pointers;
+// fields of type url.URL or time.Location
+// need a shim.
+
+// ReadDuration reads a time.Duration from the reader.
+func (m *Reader) ReadDuration() (time.Duration, error) {
+	i, err := m.ReadInt64()
+	return time.Duration(i), err
+}
+
+// ReadDurationBytes reads a time.Duration from 'b'
+// and returns the remaining bytes.
+func ReadDurationBytes(b []byte) (time.Duration, []byte, error) {
+	i, o, err := ReadInt64Bytes(b)
+	return time.Duration(i), o, err
+}
+
+// WriteDuration writes a time.Duration to the writer.
+func (mw *Writer) WriteDuration(d time.Duration) error { return mw.WriteInt64(int64(d)) }
+
+// AppendDuration appends a time.Duration to the slice.
+func AppendDuration(b []byte, d time.Duration) []byte { return AppendInt64(b, int64(d)) }
+
+// bigIntBytes returns the minimal big-endian
+// two's complement representation of 'x'
+func bigIntBytes(x *mathbig.Int) []byte {
+	switch x.Sign() {
+	case 0:
+		return []byte{}
+	case 1:
+		mag := x.Bytes()
+		if mag[0]&0x80 == 0 {
+			return mag
+		}
+		return append([]byte{0}, mag...)
+	default:
+		// -x-1 in n bytes, with every bit flipped
+		m := new(mathbig.Int).Neg(x)
+		m.Sub(m, mathbig.NewInt(1))
+		mag := m.Bytes()
+		out := make([]byte, len(mag)+1)
+		copy(out[1:], mag)
+		for i := range out {
+			out[i] = ^out[i]
+		}
+		if len(out) > 1 && out[0] == 0xff && out[1]&0x80 != 0 {
+			out = out[1:]
+		}
+		return out
+	}
+}
+
+// setBigIntBytes sets 'x' to the value of the
+// big-endian two's complement integer in 'b'
+func setBigIntBytes(x *mathbig.Int, b []byte) *mathbig.Int {
+	if len(b) == 0 || b[0]&0x80 == 0 {
+		return x.SetBytes(b)
+	}
+	flipped := make([]byte, len(b))
+	for i := range b {
+		flipped[i] = ^b[i]
+	}
+	x.SetBytes(flipped)
+	x.Add(x, mathbig.NewInt(1))
+	return x.Neg(x)
+}
+
+// ReadBigInt reads a *big.Int from the reader.
+func (m *Reader) ReadBigInt() (*mathbig.Int, error) {
+	if m.IsNil() {
+		return nil, m.ReadNil()
+	}
+	b, err := m.ReadBytes(nil)
+	if err != nil {
+		return nil, err
+	}
+	return setBigIntBytes(new(mathbig.Int), b), nil
+}
+
+// ReadBigIntBytes reads a *big.Int from 'b'
+// and returns the remaining bytes.
+func ReadBigIntBytes(b []byte) (*mathbig.Int, []byte, error) {
+	if IsNil(b) {
+		o, err := ReadNilBytes(b)
+		return nil, o, err
+	}
+	v, o, err := ReadBytesZC(b)
+	if err != nil {
+		return nil, b, err
+	}
+	return setBigIntBytes(new(mathbig.Int), v), o, nil
+}
+
+// WriteBigInt writes a *big.Int to the writer.
+func (mw *Writer) WriteBigInt(x *mathbig.Int) error {
+	if x == nil {
+		return mw.WriteNil()
+	}
+	return mw.WriteBytes(bigIntBytes(x))
+}
+
+// AppendBigInt appends a *big.Int to the slice.
+func AppendBigInt(b []byte, x *mathbig.Int) []byte {
+	if x == nil {
+		return AppendNil(b)
+	}
+	return AppendBytes(b, bigIntBytes(x))
+}
+
+// BigIntSize returns the maximum encoded size of 'x'.
+func BigIntSize(x *mathbig.Int) int {
+	if x == nil {
+		return NilSize
+	}
+	return BytesPrefixSize + x.BitLen()/8 + 1
+}
+
+// ReadBigFloat reads a *big.Float from the reader.
+func (m *Reader) ReadBigFloat() (*mathbig.Float, error) {
+	if m.IsNil() {
+		return nil, m.ReadNil()
+	}
+	b, err := m.ReadBytes(nil)
+	if err != nil {
+		return nil, err
+	}
+	x := new(mathbig.Float)
+	return x, x.GobDecode(b)
+}
+
+// ReadBigFloatBytes reads a *big.Float from 'b'
+// and returns the remaining bytes.
+func ReadBigFloatBytes(b []byte) (*mathbig.Float, []byte, error) {
+	if IsNil(b) {
+		o, err := ReadNilBytes(b)
+		return nil, o, err
+	}
+	v, o, err := ReadBytesZC(b)
+	if err != nil {
+		return nil, b, err
+	}
+	x := new(mathbig.Float)
+	if err = x.GobDecode(v); err != nil {
+		return nil, b, err
+	}
+	return x, o, nil
+}
+
+// WriteBigFloat writes a *big.Float to the writer.
+func (mw *Writer) WriteBigFloat(x *mathbig.Float) error {
+	if x == nil {
+		return mw.WriteNil()
+	}
+	v, err := x.GobEncode()
+	if err != nil {
+		return err
+	}
+	return mw.WriteBytes(v)
+}
+
+// AppendBigFloat appends a *big.Float to the slice.
+func AppendBigFloat(b []byte, x *mathbig.Float) []byte {
+	if x == nil {
+		return AppendNil(b)
+	}
+	v, _ := x.GobEncode() // never fails for non-nil x
+	return AppendBytes(b, v)
+}
+
+// BigFloatSize returns the maximum encoded size of 'x'.
+func BigFloatSize(x *mathbig.Float) int {
+	if x == nil {
+		return NilSize
+	}
+	// 10 header bytes, then at most
+	// prec bits of mantissa in whole words
+	return BytesPrefixSize + 10 + int(x.Prec())/8 + 8
+}
+
+// ReadNetIP reads a net.IP from the reader.
+func (m *Reader) ReadNetIP() (net.IP, error) {
+	b, err := m.ReadBytes(nil)
+	if err != nil || len(b) == 0 {
+		return nil, err
+	}
+	return net.IP(b), nil
+}
+
+// ReadNetIPBytes reads a net.IP from 'b'
+// and returns the remaining bytes.
+func ReadNetIPBytes(b []byte) (net.IP, []byte, error) {
+	v, o, err := ReadBytesZC(b)
+	if err != nil || len(v) == 0 {
+		return nil, o, err
+	}
+	return append(net.IP(nil), v...), o, nil
+}
+
+// WriteNetIP writes a net.IP to the writer.
+func (mw *Writer) WriteNetIP(ip net.IP) error {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	return mw.WriteBytes(ip)
+}
+
+// AppendNetIP appends a net.IP to the slice.
+func AppendNetIP(b []byte, ip net.IP) []byte {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	return AppendBytes(b, ip)
+}
+
+// NetIPSize returns the maximum encoded size of 'ip'.
+func NetIPSize(ip net.IP) int { return BytesPrefixSize + len(ip) }
+
+// ReadURL reads a *url.URL from the reader.
+func (m *Reader) ReadURL() (*url.URL, error) {
+	if m.IsNil() {
+		return nil, m.ReadNil()
+	}
+	s, err := m.ReadString()
+	if err != nil {
+		return nil, err
+	}
+	return url.Parse(s)
+}
+
+// ReadURLBytes reads a *url.URL from 'b'
+// and returns the remaining bytes.
+func ReadURLBytes(b []byte) (*url.URL, []byte, error) {
+	if IsNil(b) {
+		o, err := ReadNilBytes(b)
+		return nil, o, err
+	}
+	s, o, err := ReadStringBytes(b)
+	if err != nil {
+		return nil, b, err
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, b, err
+	}
+	return u, o, nil
+}
+
+// WriteURL writes a *url.URL to the writer.
+func (mw *Writer) WriteURL(u *url.URL) error {
+	if u == nil {
+		return mw.WriteNil()
+	}
+	return mw.WriteString(u.String())
+}
+
+// AppendURL appends a *url.URL to the slice.
+func AppendURL(b []byte, u *url.URL) []byte {
+	if u == nil {
+		return AppendNil(b)
+	}
+	return AppendString(b, u.String())
+}
+
+// URLSize returns the maximum encoded size of 'u'.
+func URLSize(u *url.URL) int {
+	if u == nil {
+		return NilSize
+	}
+	return StringPrefixSize + len(u.String())
+}
+
+// ReadJSONNumber reads a json.Number from the reader.
+// Any MessagePack number or string is accepted.
+func (m *Reader) ReadJSONNumber() (json.Number, error) {
+	t, err := m.NextType()
+	if err != nil {
+		return "", err
+	}
+	switch t {
+	case IntType:
+		i, err := m.ReadInt64()
+		return json.Number(strconv.FormatInt(i, 10)), err
+	case UintType:
+		u, err := m.ReadUint64()
+		return json.Number(strconv.FormatUint(u, 10)), err
+	case Float32Type:
+		f, err := m.ReadFloat32()
+		return json.Number(strconv.FormatFloat(float64(f), 'g', -1, 32)), err
+	case Float64Type:
+		f, err := m.ReadFloat64()
+		return json.Number(strconv.FormatFloat(f, 'g', -1, 64)), err
+	case StrType:
+		s, err := m.ReadString()
+		return json.Number(s), err
+	default:
+		return "", TypeError{Method: IntType, Encoded: t}
+	}
+}
+
+// ReadJSONNumberBytes reads a json.Number from 'b'
+// and returns the remaining bytes.
+// Any MessagePack number or string is accepted.
+func ReadJSONNumberBytes(b []byte) (json.Number, []byte, error) {
+	switch t := NextType(b); t {
+	case IntType:
+		i, o, err := ReadInt64Bytes(b)
+		return json.Number(strconv.FormatInt(i, 10)), o, err
+	case UintType:
+		u, o, err := ReadUint64Bytes(b)
+		return json.Number(strconv.FormatUint(u, 10)), o, err
+	case Float32Type:
+		f, o, err := ReadFloat32Bytes(b)
+		return json.Number(strconv.FormatFloat(float64(f), 'g', -1, 32)), o, err
+	case Float64Type:
+		f, o, err := ReadFloat64Bytes(b)
+		return json.Number(strconv.FormatFloat(f, 'g', -1, 64)), o, err
+	case StrType:
+		s, o, err := ReadStringBytes(b)
+		return json.Number(s), o, err
+	case InvalidType:
+		if len(b) == 0 {
+			return "", b, ErrShortBytes
+		}
+		return "", b, InvalidPrefixError(b[0])
+	default:
+		return "", b, TypeError{Method: IntType, Encoded: t}
+	}
+}
+
+// the ways a json.Number is written
+const (
+	jsonNumberStr = iota
+	jsonNumberInt
+	jsonNumberUint
+	jsonNumberFloat
+)
+
+// parseJSONNumber returns how 'n' is written. It is
+// only written as a number if reading that number
+// back gives the same text, so that no digits (or
+// trailing zeros, etc.) are lost.
+func parseJSONNumber(n json.Number) (kind int, i int64, u uint64, f float64) {
+	s := string(n)
+	var err error
+	if i, err = strconv.ParseInt(s, 10, 64); err == nil && strconv.FormatInt(i, 10) == s {
+		return jsonNumberInt, i, 0, 0
+	}
+	if u, err = strconv.ParseUint(s, 10, 64); err == nil && strconv.FormatUint(u, 10) == s {
+		return jsonNumberUint, 0, u, 0
+	}
+	if f, err = strconv.ParseFloat(s, 64); err == nil && strconv.FormatFloat(f, 'g', -1, 64) == s {
+		return jsonNumberFloat, 0, 0, f
+	}
+	return jsonNumberStr, 0, 0, 0
+}
+
+// WriteJSONNumber writes a json.Number to the writer.
+func (mw *Writer) WriteJSONNumber(n json.Number) error {
+	switch kind, i, u, f := parseJSONNumber(n); kind {
+	case jsonNumberInt:
+		return mw.WriteInt64(i)
+	case jsonNumberUint:
+		return mw.WriteUint64(u)
+	case jsonNumberFloat:
+		return mw.WriteFloat64(f)
+	default:
+		return mw.WriteString(string(n))
+	}
+}
+
+// AppendJSONNumber appends a json.Number to the slice.
+func AppendJSONNumber(b []byte, n json.Number) []byte {
+	switch kind, i, u, f := parseJSONNumber(n); kind {
+	case jsonNumberInt:
+		return AppendInt64(b, i)
+	case jsonNumberUint:
+		return AppendUint64(b, u)
+	case jsonNumberFloat:
+		return AppendFloat64(b, f)
+	default:
+		return AppendString(b, string(n))
+	}
+}
+
+// JSONNumberSize returns the maximum encoded size of 'n'.
+func JSONNumberSize(n json.Number) int {
+	switch kind, _, _, _ := parseJSONNumber(n); kind {
+	case jsonNumberInt:
+		return Int64Size
+	case jsonNumberUint:
+		return Uint64Size
+	case jsonNumberFloat:
+		return Float64Size
+	default:
+		return StringPrefixSize + len(n)
+	}
+}
+
+// loadLocation is time.LoadLocation, except that
+// "Local" is always time.Local
+func loadLocation(name string) (*time.Location, error) {
+	if name == "Local" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(name)
+}
+
+// ReadLocation reads a *time.Location from the reader.
+func (m *Reader) ReadLocation() (*time.Location, error) {
+	if m.IsNil() {
+		return nil, m.ReadNil()
+	}
+	s, err := m.ReadString()
+	if err != nil {
+		return nil, err
+	}
+	return loadLocation(s)
+}
+
+// ReadLocationBytes reads a *time.Location from 'b'
+// and returns the remaining bytes.
+func ReadLocationBytes(b []byte) (*time.Location, []byte, error) {
+	if IsNil(b) {
+		o, err := ReadNilBytes(b)
+		return nil, o, err
+	}
+	s, o, err := ReadStringBytes(b)
+	if err != nil {
+		return nil, b, err
+	}
+	loc, err := loadLocation(s)
+	if err != nil {
+		return nil, b, err
+	}
+	return loc, o, nil
+}
+
+// WriteLocation writes a *time.Location to the writer.
+func (mw *Writer) WriteLocation(loc *time.Location) error {
+	if loc == nil {
+		return mw.WriteNil()
+	}
+	return mw.WriteString(loc.String())
+}
+
+// AppendLocation appends a *time.Location to the slice.
+func AppendLocation(b []byte, loc *time.Location) []byte {
+	if loc == nil {
+		return AppendNil(b)
+	}
+	return AppendString(b, loc.String())
+}
+
+// LocationSize returns the maximum encoded size of 'loc'.
+func LocationSize(loc *time.Location) int {
+	if loc == nil {
+		return NilSize
+	}
+	return StringPrefixSize + len(loc.String())
+}
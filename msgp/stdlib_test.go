@@ -0,0 +1,145 @@
+package msgp
+
+import (
+	"bytes"
+	"encoding/json"
+	mathbig "math/big"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestBigIntBytes(t *testing.T) {
+	tests := []struct {
+		v   int64
+		enc []byte
+	}{
+		{0, []byte{}},
+		{1, []byte{0x01}},
+		{127, []byte{0x7f}},
+		{128, []byte{0x00, 0x80}},
+		{256, []byte{0x01, 0x00}},
+		{-1, []byte{0xff}},
+		{-128, []byte{0x80}},
+		{-129, []byte{0xff, 0x7f}},
+		{-256, []byte{0xff, 0x00}},
+		{-32768, []byte{0x80, 0x00}},
+	}
+	for _, tt := range tests {
+		x := mathbig.NewInt(tt.v)
+		enc := bigIntBytes(x)
+		if !bytes.Equal(enc, tt.enc) {
+			t.Errorf("%d: got % x; want % x", tt.v, enc, tt.enc)
+		}
+		if got := setBigIntBytes(new(mathbig.Int), enc); got.Cmp(x) != 0 {
+			t.Errorf("%d: decoded %v", tt.v, got)
+		}
+	}
+}
+
+func TestBigInt(t *testing.T) {
+	x, _ := new(mathbig.Int).SetString("-98765432109876543210987654321", 10)
+	for _, v := range []*mathbig.Int{x, new(mathbig.Int).Neg(x), nil} {
+		b := AppendBigInt(nil, v)
+		if len(b) > BigIntSize(v) {
+			t.Errorf("%v: BigIntSize %d is less than %d", v, BigIntSize(v), len(b))
+		}
+		got, left, err := ReadBigIntBytes(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(left) != 0 || (v == nil) != (got == nil) || (v != nil && got.Cmp(v) != 0) {
+			t.Errorf("ReadBigIntBytes: got %v; want %v", got, v)
+		}
+
+		var buf bytes.Buffer
+		wr := NewWriter(&buf)
+		wr.WriteBigInt(v)
+		wr.Flush()
+		if !bytes.Equal(buf.Bytes(), b) {
+			t.Errorf("WriteBigInt wrote % x; want % x", buf.Bytes(), b)
+		}
+		got, err = NewReader(&buf).ReadBigInt()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (v == nil) != (got == nil) || (v != nil && got.Cmp(v) != 0) {
+			t.Errorf("ReadBigInt: got %v; want %v", got, v)
+		}
+	}
+}
+
+func TestJSONNumber(t *testing.T) {
+	tests := []struct {
+		in, out json.Number
+		typ     Type
+	}{
+		{"12", "12", IntType},
+		{"-12", "-12", IntType},
+		{"18446744073709551615", "18446744073709551615", UintType},
+		{"1.25", "1.25", Float64Type},
+		{"1e+21", "1e+21", Float64Type},
+		{"1e3", "1e3", StrType},
+		{"1.50", "1.50", StrType},
+		{"007", "007", StrType},
+		{"12345678901234567890123", "12345678901234567890123", StrType},
+		{"3.14159265358979323846264", "3.14159265358979323846264", StrType},
+		{"1e400", "1e400", StrType},
+		{"", "", StrType},
+	}
+	for _, tt := range tests {
+		b := AppendJSONNumber(nil, tt.in)
+		if NextType(b) != tt.typ {
+			t.Errorf("%q: encoded as %s; want %s", tt.in, NextType(b), tt.typ)
+		}
+		if len(b) > JSONNumberSize(tt.in) {
+			t.Errorf("%q: JSONNumberSize %d is less than %d", tt.in, JSONNumberSize(tt.in), len(b))
+		}
+		got, _, err := ReadJSONNumberBytes(b)
+		if err != nil || got != tt.out {
+			t.Errorf("%q: ReadJSONNumberBytes returned %q, %v", tt.in, got, err)
+		}
+		got, err = NewReader(bytes.NewReader(b)).ReadJSONNumber()
+		if err != nil || got != tt.out {
+			t.Errorf("%q: ReadJSONNumber returned %q, %v", tt.in, got, err)
+		}
+	}
+	got, _, err := ReadJSONNumberBytes(AppendFloat32(nil, 0.5))
+	if err != nil || got != "0.5" {
+		t.Errorf("float32: got %q, %v", got, err)
+	}
+	if _, _, err = ReadJSONNumberBytes(AppendBool(nil, true)); err == nil {
+		t.Error("expected an error for a bool")
+	}
+}
+
+func TestNetIP(t *testing.T) {
+	for _, s := range []string{"10.1.2.3", "::ffff:10.1.2.3", "2001:db8::1"} {
+		ip := net.ParseIP(s)
+		b := AppendNetIP(nil, ip)
+		got, _, err := ReadNetIPBytes(b)
+		if err != nil || !got.Equal(ip) {
+			t.Errorf("%s: got %v, %v", s, got, err)
+		}
+		if want := len(ip.To4()); want != 0 && len(got) != want {
+			t.Errorf("%s: decoded %d bytes", s, len(got))
+		}
+	}
+	got, _, err := ReadNetIPBytes(AppendNetIP(nil, nil))
+	if err != nil || got != nil {
+		t.Errorf("nil: got %v, %v", got, err)
+	}
+}
+
+func TestLocation(t *testing.T) {
+	for _, loc := range []*time.Location{time.UTC, time.Local, nil} {
+		b := AppendLocation(nil, loc)
+		got, _, err := ReadLocationBytes(b)
+		if err != nil || got != loc {
+			t.Errorf("%v: got %v, %v", loc, got, err)
+		}
+	}
+	if _, _, err := ReadLocationBytes(AppendString(nil, "Not/AZone")); err == nil {
+		t.Error("expected an error for an unknown location")
+	}
+}
@@ -0,0 +1,185 @@
+package msgp
+
+import (
+	"io"
+
+	"github.com/philhofer/fwd"
+)
+
+// BinReader reads the header of a 'bin' object and
+// returns an io.Reader of its contents and their
+// size, so that large objects can be read without
+// holding them in memory. Reading the contents of
+// the object directly doesn't copy them through the
+// buffer of the Reader.
+//
+// The io.Reader is only valid until the next call to
+// a method of the Reader, which skips any contents
+// that haven't been read, so it can be abandoned
+// at any point.
+func (m *Reader) BinReader() (io.Reader, uint32, error) {
+	sz, err := m.ReadBytesHeader()
+	if err != nil {
+		return nil, 0, err
+	}
+	return m.stream(sz), sz, nil
+}
+
+// StrReader is like BinReader,
+// but for 'str' objects.
+func (m *Reader) StrReader() (io.Reader, uint32, error) {
+	sz, err := m.ReadStringHeader()
+	if err != nil {
+		return nil, 0, err
+	}
+	return m.stream(sz), sz, nil
+}
+
+// bodyStream holds the bytes of the stream after the
+// header of a streamed object. While an object is
+// streamed, m.R is replaced by a fwd.Reader (wrap) that
+// reads through a bodyStream from the previous m.R, and
+// then skips what hasn't been read of the object before
+// its first read. Once the object is read to its end
+// with nothing buffered after it, m.R is restored.
+type bodyStream struct {
+	m     *Reader
+	src   *fwd.Reader // the reader that m.R replaced
+	wrap  *fwd.Reader // m.R while the object is streamed
+	pre   []byte      // bytes that were buffered by m.R, which come before src
+	buf   []byte      // holds pre
+	spare []byte      // the next buf
+	left  int64       // what is left of the object
+	gen   uint64      // counts the objects, so that old readers stop
+}
+
+// stream returns a reader of
+// the next 'sz' bytes
+func (m *Reader) stream(sz uint32) io.Reader {
+	s := m.body
+	if s == nil {
+		s = &bodyStream{m: m}
+		m.body = s
+	}
+	s.unwrap()
+	if m.R != s.wrap {
+		// src is read directly, so nothing
+		// comes before it
+		s.src = m.R
+		if s.wrap == nil {
+			s.wrap = fwd.NewReaderSize(tailReader{s}, m.R.BufferSize())
+		} else {
+			s.wrap.Reset(tailReader{s})
+		}
+		m.R = s.wrap
+	} else {
+		// what m.R has buffered comes before the rest
+		b, _ := m.R.Next(m.R.Buffered())
+		s.spare = append(append(s.spare[:0], b...), s.pre...)
+		s.buf, s.spare = s.spare, s.buf
+		s.pre = s.buf
+		m.R.Reset(tailReader{s})
+	}
+	s.left = int64(sz)
+	s.gen++
+	return &bodyReader{s: s, gen: s.gen}
+}
+
+// unwrap restores m.R if the object has been
+// read and nothing after it is buffered
+func (s *bodyStream) unwrap() {
+	m := s.m
+	if m.R == s.wrap && s.left == 0 && len(s.pre) == 0 && m.R.Buffered() == 0 {
+		m.R = s.src
+	}
+}
+
+func (s *bodyStream) read(p []byte) (int, error) {
+	if len(s.pre) > 0 {
+		n := copy(p, s.pre)
+		s.pre = s.pre[n:]
+		return n, nil
+	}
+	return s.src.Read(p)
+}
+
+// skip discards what is left of the object
+func (s *bodyStream) skip() error {
+	n := len(s.pre)
+	if int64(n) > s.left {
+		n = int(s.left)
+	}
+	s.pre = s.pre[n:]
+	s.left -= int64(n)
+	for s.left > 0 {
+		n = 1 << 30
+		if int64(n) > s.left {
+			n = int(s.left)
+		}
+		// (the count returned by Skip isn't
+		// reliable when it seeks, so it is
+		// only used to check for errors)
+		if _, err := s.src.Skip(n); err != nil {
+			return noEOF(err)
+		}
+		s.left -= int64(n)
+	}
+	return nil
+}
+
+// tailReader reads what
+// follows the object
+type tailReader struct {
+	s *bodyStream
+}
+
+func (t tailReader) Read(p []byte) (int, error) {
+	if t.s.left > 0 {
+		if err := t.s.skip(); err != nil {
+			return 0, err
+		}
+	}
+	return t.s.read(p)
+}
+
+// bodyReader is the io.Reader
+// returned by BinReader
+type bodyReader struct {
+	s   *bodyStream
+	gen uint64
+}
+
+func (b *bodyReader) Read(p []byte) (int, error) {
+	s := b.s
+	if b.gen != s.gen || s.left == 0 {
+		return 0, io.EOF
+	}
+	if int64(len(p)) > s.left {
+		p = p[:s.left]
+	}
+	n, err := s.read(p)
+	s.left -= int64(n)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if s.left == 0 {
+		s.unwrap()
+	}
+	return n, err
+}
+
+// WriteBinFrom writes a 'bin' object of 'sz' bytes
+// read from 'r', so that large objects can be written
+// without holding them in memory. If 'r' has fewer
+// than 'sz' bytes, it returns io.ErrUnexpectedEOF,
+// and the object is incomplete.
+func (mw *Writer) WriteBinFrom(r io.Reader, sz uint32) error {
+	if err := mw.WriteBytesHeader(sz); err != nil {
+		return err
+	}
+	n, err := io.CopyN(mw, r, int64(sz))
+	if err == io.EOF && n < int64(sz) {
+		err = io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,192 @@
+package msgp
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestBinReader(t *testing.T) {
+	blob := make([]byte, 1<<20)
+	rand.Read(blob)
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	if err := w.WriteBinFrom(bytes.NewReader(blob), uint32(len(blob))); err != nil {
+		t.Fatal(err)
+	}
+	w.WriteString(strings.Repeat("str", 1000))
+	w.WriteInt(42)
+	w.Flush()
+
+	// read the objects in full, and then
+	// abandon them after 'n' bytes
+	for _, n := range []int{-1, 0, 1, 100, 5000} {
+		r := NewReaderSize(bytes.NewReader(buf.Bytes()), 64)
+		br, sz, err := r.BinReader()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sz != uint32(len(blob)) {
+			t.Fatalf("got size %d", sz)
+		}
+		if n < 0 {
+			got, err := ioutil.ReadAll(br)
+			if err != nil || !bytes.Equal(got, blob) {
+				t.Fatalf("read %d bytes, %v", len(got), err)
+			}
+		} else {
+			got := make([]byte, n)
+			if _, err = io.ReadFull(br, got); err != nil || !bytes.Equal(got, blob[:n]) {
+				t.Fatalf("read %d bytes: %v", n, err)
+			}
+		}
+
+		sr, sz, err := r.StrReader()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sz != 3000 {
+			t.Fatalf("got size %d", sz)
+		}
+		if n > 0 {
+			got := make([]byte, n%3000)
+			if _, err = io.ReadFull(sr, got); err != nil || string(got) != strings.Repeat("str", 1000)[:n%3000] {
+				t.Fatalf("read %q: %v", got, err)
+			}
+		}
+		if i, err := r.ReadInt(); err != nil || i != 42 {
+			t.Fatalf("got %d, %v", i, err)
+		}
+
+		// the readers are done once the Reader moves on
+		for _, rd := range []io.Reader{br, sr} {
+			if n, err := rd.Read(make([]byte, 10)); n != 0 || err != io.EOF {
+				t.Errorf("old reader returned %d, %v", n, err)
+			}
+		}
+	}
+}
+
+func TestBinReaderMany(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	for i := 0; i < 500; i++ {
+		w.WriteBytes(bytes.Repeat([]byte{byte(i)}, i))
+		w.WriteInt(i)
+	}
+	w.Flush()
+
+	r := NewReaderSize(iotest.HalfReader(bytes.NewReader(buf.Bytes())), 128)
+	for i := 0; i < 500; i++ {
+		if i%3 == 0 {
+			// some objects are read without streaming
+			b, err := r.ReadBytes(nil)
+			if err != nil || !bytes.Equal(b, bytes.Repeat([]byte{byte(i)}, i)) {
+				t.Fatalf("object %d: got %v, %v", i, b, err)
+			}
+		} else {
+			br, sz, err := r.BinReader()
+			if err != nil || sz != uint32(i) {
+				t.Fatalf("object %d: got size %d, %v", i, sz, err)
+			}
+			got := make([]byte, i/2)
+			if _, err = io.ReadFull(br, got); err != nil || !bytes.Equal(got, bytes.Repeat([]byte{byte(i)}, i/2)) {
+				t.Fatalf("object %d: read %v, %v", i, got, err)
+			}
+		}
+		if n, err := r.ReadInt(); err != nil || n != i {
+			t.Fatalf("object %d: got %d, %v", i, n, err)
+		}
+	}
+	if _, err := r.NextType(); err != io.EOF {
+		t.Errorf("got %v at the end", err)
+	}
+
+	// a Reset Reader starts over
+	r.Reset(bytes.NewReader(buf.Bytes()))
+	if b, err := r.ReadBytes(nil); err != nil || len(b) != 0 {
+		t.Errorf("got %v, %v after Reset", b, err)
+	}
+}
+
+func TestBinReaderUnwrap(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	for i := 0; i < 100; i++ {
+		w.WriteBytes(bytes.Repeat([]byte{byte(i)}, 1000))
+		w.WriteInt(i)
+	}
+	w.Flush()
+
+	r := NewReaderSize(bytes.NewReader(buf.Bytes()), 128)
+	src := r.R
+	next := func(i int, n int) {
+		br, _, err := r.BinReader()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := make([]byte, n)
+		if _, err = io.ReadFull(br, got); err != nil || !bytes.Equal(got, bytes.Repeat([]byte{byte(i)}, n)) {
+			t.Fatalf("object %d: read %v, %v", i, got, err)
+		}
+		if v, err := r.ReadInt(); err != nil || v != i {
+			t.Fatalf("object %d: got %d, %v", i, v, err)
+		}
+	}
+
+	// an object read to its end
+	// restores the Reader's reader
+	next(0, 1000)
+	if r.R != src {
+		t.Error("the reader is still wrapped")
+	}
+
+	// an abandoned object leaves it
+	// wrapped, without allocating
+	// for what it has buffered
+	next(1, 10)
+	i := 2
+	allocs := testing.AllocsPerRun(50, func() {
+		br, _, err := r.BinReader()
+		if err != nil {
+			t.Fatal(err)
+		}
+		br.Read(make([]byte, 1))
+		if v, err := r.ReadInt(); err != nil || v != i {
+			t.Fatalf("object %d: got %d, %v", i, v, err)
+		}
+		i++
+	})
+	if allocs > 2 {
+		t.Errorf("%v allocations per object", allocs)
+	}
+}
+
+func TestBinReaderErrors(t *testing.T) {
+	r := NewReader(bytes.NewReader(AppendInt(nil, 1)))
+	if _, _, err := r.BinReader(); err == nil {
+		t.Error("read an int as bin")
+	}
+
+	// a truncated object
+	b := AppendBytes(nil, make([]byte, 100))
+	r = NewReader(bytes.NewReader(b[:50]))
+	br, _, err := r.BinReader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ioutil.ReadAll(br); err != io.ErrUnexpectedEOF {
+		t.Errorf("got %v for a truncated object", err)
+	}
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	if err = w.WriteBinFrom(strings.NewReader("short"), 10); err != io.ErrUnexpectedEOF {
+		t.Errorf("got %v from a short reader", err)
+	}
+}
@@ -0,0 +1,178 @@
+package msgp
+
+import (
+	"math"
+	"time"
+)
+
+// TimeFormat selects how WriteTimeAs and
+// AppendTimeAs encode a time.Time.
+type TimeFormat uint8
+
+const (
+	TimeExt       TimeFormat = iota // the time extension, as written by WriteTime
+	TimeUnix                        // int seconds since the Unix epoch
+	TimeUnixMilli                   // int milliseconds since the Unix epoch
+	TimeUnixNano                    // int nanoseconds since the Unix epoch; see below
+	TimeRFC3339                     // str in the time.RFC3339Nano format; see below
+)
+
+// Nanoseconds since the Unix epoch only fit in an
+// int64 for times between the years 1678 and 2262;
+// like (time.Time).UnixNano, TimeUnixNano writes
+// an undefined value for times outside them.
+//
+// The RFC 3339 format only has room for the years
+// 0 to 9999, so TimeRFC3339 writes times outside
+// them as the first or last time that fits.
+
+// TimeAsSize returns the maximum encoded
+// size of a time.Time written with format 'f'.
+func TimeAsSize(f TimeFormat) int {
+	switch f {
+	case TimeUnix, TimeUnixMilli, TimeUnixNano:
+		return Int64Size
+	case TimeRFC3339:
+		// (the longest time that fits, since
+		// the year has at most 4 digits)
+		return StringPrefixSize + len(time.RFC3339Nano)
+	default:
+		return TimeSize
+	}
+}
+
+// rfc3339 formats 't' in the time.RFC3339Nano
+// format, clamping its year to 0 to 9999
+func rfc3339(t time.Time) string {
+	if y := t.Year(); y < 0 {
+		t = time.Date(0, 1, 1, 0, 0, 0, 0, t.Location())
+	} else if y > 9999 {
+		t = time.Date(9999, 12, 31, 23, 59, 59, 999999999, t.Location())
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+// unixTime returns 't' as an integer
+// number of units since the Unix epoch
+func unixTime(t time.Time, f TimeFormat) int64 {
+	switch f {
+	case TimeUnixMilli:
+		return t.Unix()*1e3 + int64(t.Nanosecond())/1e6
+	case TimeUnixNano:
+		return t.UnixNano()
+	default:
+		return t.Unix()
+	}
+}
+
+// fromUnix returns the time 'n' units after the Unix
+// epoch, where the unit is set by the format 'f'.
+// Formats that aren't integers use seconds.
+func fromUnix(n int64, f TimeFormat) time.Time {
+	switch f {
+	case TimeUnixMilli:
+		return time.Unix(n/1e3, n%1e3*1e6)
+	case TimeUnixNano:
+		return time.Unix(0, n)
+	default:
+		return time.Unix(n, 0)
+	}
+}
+
+// fromUnixFloat is fromUnix for fractional units
+func fromUnixFloat(v float64, f TimeFormat) time.Time {
+	switch f {
+	case TimeUnixMilli:
+		v /= 1e3
+	case TimeUnixNano:
+		v /= 1e9
+	}
+	sec, frac := math.Modf(v)
+	return time.Unix(int64(sec), int64(frac*1e9))
+}
+
+// WriteTimeAs writes a time.Time in the format 'f'.
+func (mw *Writer) WriteTimeAs(t time.Time, f TimeFormat) error {
+	switch f {
+	case TimeUnix, TimeUnixMilli, TimeUnixNano:
+		return mw.WriteInt64(unixTime(t, f))
+	case TimeRFC3339:
+		return mw.WriteString(rfc3339(t))
+	default:
+		return mw.WriteTime(t)
+	}
+}
+
+// AppendTimeAs appends a time.Time in the format 'f'.
+func AppendTimeAs(b []byte, t time.Time, f TimeFormat) []byte {
+	switch f {
+	case TimeUnix, TimeUnixMilli, TimeUnixNano:
+		return AppendInt64(b, unixTime(t, f))
+	case TimeRFC3339:
+		return AppendString(b, rfc3339(t))
+	default:
+		return AppendTime(b, t)
+	}
+}
+
+// ReadTimeAs reads a time.Time written in any of the
+// time formats, or as a float. Integers and floats
+// are read in the units of 'f', which are seconds for
+// TimeExt and TimeRFC3339.
+func (m *Reader) ReadTimeAs(f TimeFormat) (t time.Time, err error) {
+	var typ Type
+	typ, err = m.NextType()
+	if err != nil {
+		return
+	}
+	switch typ {
+	case IntType, UintType:
+		var n int64
+		n, err = m.ReadInt64()
+		return fromUnix(n, f), err
+	case Float32Type, Float64Type:
+		var v float64
+		v, err = m.ReadFloat64()
+		return fromUnixFloat(v, f), err
+	case StrType:
+		var s string
+		s, err = m.ReadString()
+		if err != nil {
+			return
+		}
+		return time.Parse(time.RFC3339Nano, s)
+	default:
+		return m.ReadTime()
+	}
+}
+
+// ReadTimeAsBytes reads a time.Time written in any
+// of the time formats, or as a float, from 'b' and
+// returns the remaining bytes. Integers and floats
+// are read in the units of 'f', which are seconds for
+// TimeExt and TimeRFC3339.
+func ReadTimeAsBytes(b []byte, f TimeFormat) (t time.Time, o []byte, err error) {
+	switch NextType(b) {
+	case IntType, UintType:
+		var n int64
+		n, o, err = ReadInt64Bytes(b)
+		return fromUnix(n, f), o, err
+	case Float32Type, Float64Type:
+		var v float64
+		v, o, err = ReadFloat64Bytes(b)
+		return fromUnixFloat(v, f), o, err
+	case StrType:
+		var s []byte
+		s, o, err = ReadStringZC(b)
+		if err != nil {
+			return
+		}
+		t, err = time.Parse(time.RFC3339Nano, string(s))
+		if err != nil {
+			return t, b, err
+		}
+		return t, o, nil
+	default:
+		return ReadTimeBytes(b)
+	}
+}
@@ -0,0 +1,100 @@
+package msgp
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestTimeAs(t *testing.T) {
+	times := []time.Time{
+		time.Date(2021, 6, 7, 8, 9, 10, 123456789, time.UTC),
+		time.Date(1950, 1, 1, 0, 0, 0, 999999999, time.UTC),
+		time.Unix(0, 0),
+	}
+	formats := []struct {
+		f     TimeFormat
+		trunc time.Duration
+	}{
+		{TimeExt, 0},
+		{TimeUnix, time.Second},
+		{TimeUnixMilli, time.Millisecond},
+		{TimeUnixNano, 0},
+		{TimeRFC3339, 0},
+	}
+	for _, tm := range times {
+		for _, tf := range formats {
+			want := tm
+			if tf.trunc != 0 {
+				// round toward negative infinity, like
+				// integer division of the Unix time
+				want = time.Unix(0, 0).Add(tm.Sub(time.Unix(0, 0)) / tf.trunc * tf.trunc)
+				if want.After(tm) {
+					want = want.Add(-tf.trunc)
+				}
+			}
+			b := AppendTimeAs(nil, tm, tf.f)
+			if len(b) > TimeAsSize(tf.f) {
+				t.Errorf("format %d: %d bytes is more than TimeAsSize", tf.f, len(b))
+			}
+			got, o, err := ReadTimeAsBytes(b, tf.f)
+			if err != nil || len(o) != 0 {
+				t.Fatalf("format %d: %v, %d bytes left", tf.f, err, len(o))
+			}
+			if !got.Equal(want) {
+				t.Errorf("format %d: ReadTimeAsBytes got %v; want %v", tf.f, got, want)
+			}
+
+			var buf bytes.Buffer
+			w := NewWriter(&buf)
+			if err = w.WriteTimeAs(tm, tf.f); err != nil {
+				t.Fatal(err)
+			}
+			w.Flush()
+			if !bytes.Equal(buf.Bytes(), b) {
+				t.Errorf("format %d: WriteTimeAs and AppendTimeAs differ", tf.f)
+			}
+			got, err = NewReader(&buf).ReadTimeAs(tf.f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.Equal(want) {
+				t.Errorf("format %d: ReadTimeAs got %v; want %v", tf.f, got, want)
+			}
+		}
+	}
+}
+
+func TestTimeAsRFC3339Clamp(t *testing.T) {
+	east := time.FixedZone("east", 14*3600)
+	for _, tt := range []struct {
+		in, want time.Time
+	}{
+		{time.Date(12345, 1, 1, 0, 0, 0, 0, east), time.Date(9999, 12, 31, 23, 59, 59, 999999999, east)},
+		{time.Date(-1, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(0, 1, 1, 0, 0, 0, 0, time.UTC)},
+	} {
+		b := AppendTimeAs(nil, tt.in, TimeRFC3339)
+		if len(b) > TimeAsSize(TimeRFC3339) {
+			t.Errorf("%v: %d bytes is more than TimeAsSize", tt.in, len(b))
+		}
+		got, _, err := ReadTimeAsBytes(b, TimeRFC3339)
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("%v: got %v, %v; want %v", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestReadTimeAsErrors(t *testing.T) {
+	for _, b := range [][]byte{
+		AppendString(nil, "yesterday"),
+		AppendBool(nil, true),
+		AppendUint64(nil, 1<<63),
+	} {
+		if _, _, err := ReadTimeAsBytes(b, TimeUnix); err == nil {
+			t.Errorf("ReadTimeAsBytes(% x): expected an error", b)
+		}
+		if _, err := NewReader(bytes.NewReader(b)).ReadTimeAs(TimeUnix); err == nil {
+			t.Errorf("ReadTimeAs(% x): expected an error", b)
+		}
+	}
+}
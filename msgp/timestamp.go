@@ -0,0 +1,109 @@
+package msgp
+
+import (
+	"math"
+	"time"
+)
+
+// The MessagePack timestamp extension (type -1)
+// has three forms:
+//
+//	timestamp 32: fixext4; uint32 seconds
+//	timestamp 64: fixext8; 30-bit nanoseconds, then 34-bit seconds
+//	timestamp 96: ext8 with length 12; uint32 nanoseconds, then int64 seconds
+//
+// The legacy encoding used by WriteTime and AppendTime
+// by default is an ext8 with length 12 and type TimeExtension
+// (5), holding int64 seconds followed by int32 nanoseconds.
+
+// timeLen returns the encoded length of a
+// time beginning with 'lead', or 0 if 'lead'
+// cannot begin an encoded time
+func timeLen(lead byte) int {
+	switch lead {
+	case mfixext4:
+		return 6
+	case mfixext8:
+		return 10
+	case mext8:
+		return 15
+	default:
+		return 0
+	}
+}
+
+// getTime decodes a time encoded in either the
+// timestamp extension or the legacy format. 'b' must
+// be at least timeLen(b[0]) bytes long.
+func getTime(b []byte) (t time.Time, err error) {
+	var sec int64
+	var nsec uint32
+	switch b[0] {
+	case mfixext4:
+		if int8(b[1]) != TimestampExtension {
+			return t, errExt(int8(b[1]), TimestampExtension)
+		}
+		sec = int64(big.Uint32(b[2:]))
+	case mfixext8:
+		if int8(b[1]) != TimestampExtension {
+			return t, errExt(int8(b[1]), TimestampExtension)
+		}
+		v := big.Uint64(b[2:])
+		nsec = uint32(v >> 34)
+		sec = int64(v & (1<<34 - 1))
+	case mext8:
+		if b[1] != 12 {
+			return t, badPrefix(TimeType, b[0])
+		}
+		switch int8(b[2]) {
+		case TimeExtension:
+			var n int32
+			sec, n = getUnix(b[3:])
+			nsec = uint32(n)
+		case TimestampExtension:
+			nsec = big.Uint32(b[3:])
+			sec = int64(big.Uint64(b[7:]))
+		default:
+			return t, errExt(int8(b[2]), TimeExtension)
+		}
+	default:
+		return t, badPrefix(TimeType, b[0])
+	}
+	return time.Unix(sec, int64(nsec)).Local(), nil
+}
+
+// timestampLen returns the encoded length of
+// 't' in the smallest timestamp extension form
+func timestampLen(t time.Time) int {
+	sec := t.Unix()
+	if uint64(sec)>>34 != 0 {
+		return 15
+	}
+	if t.Nanosecond() == 0 && sec <= math.MaxUint32 {
+		return 6
+	}
+	return 10
+}
+
+// putTimestamp writes 't' into 'b' in the timestamp
+// extension form that is timestampLen(t) bytes long
+func putTimestamp(b []byte, t time.Time) {
+	sec := t.Unix()
+	nsec := uint32(t.Nanosecond())
+	switch len(b) {
+	case 6:
+		b[0] = mfixext4
+		b[1] = byte(TimestampExtension & 0xff)
+		big.PutUint32(b[2:], uint32(sec))
+	case 10:
+		b[0] = mfixext8
+		b[1] = byte(TimestampExtension & 0xff)
+		big.PutUint64(b[2:], uint64(nsec)<<34|uint64(sec))
+	default:
+		b[0] = mext8
+		b[1] = 12
+		b[2] = byte(TimestampExtension & 0xff)
+		big.PutUint32(b[3:], nsec)
+		big.PutUint64(b[7:], uint64(sec))
+	}
+}
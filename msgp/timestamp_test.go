@@ -0,0 +1,121 @@
+package msgp
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+var timestampTests = []struct {
+	t   time.Time
+	enc []byte
+}{
+	{time.Unix(0, 0), []byte{mfixext4, 0xff, 0, 0, 0, 0}},
+	{time.Unix(1<<32-1, 0), []byte{mfixext4, 0xff, 0xff, 0xff, 0xff, 0xff}},
+	{time.Unix(1, 1), []byte{mfixext8, 0xff, 0, 0, 0, 0x04, 0, 0, 0, 0x01}},
+	{time.Unix(1<<32, 0), []byte{mfixext8, 0xff, 0, 0, 0, 0x01, 0, 0, 0, 0}},
+	{time.Unix(-1, 0), []byte{mext8, 12, 0xff, 0, 0, 0, 0, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+	{time.Unix(1<<34, 5), []byte{mext8, 12, 0xff, 0, 0, 0, 5, 0, 0, 0, 0x04, 0, 0, 0, 0}},
+}
+
+func TestTimestamp(t *testing.T) {
+	for _, tt := range timestampTests {
+		enc := AppendTimestamp(nil, tt.t)
+		if !bytes.Equal(enc, tt.enc) {
+			t.Errorf("%v: got % x; want % x", tt.t, enc, tt.enc)
+		}
+		if NextType(enc) != TimeType {
+			t.Errorf("%v: NextType is %s", tt.t, NextType(enc))
+		}
+
+		var buf bytes.Buffer
+		wr := NewWriter(&buf)
+		wr.UseTimestamps(true)
+		wr.WriteTime(tt.t)
+		wr.Flush()
+		if !bytes.Equal(buf.Bytes(), tt.enc) {
+			t.Errorf("%v: WriteTime wrote % x; want % x", tt.t, buf.Bytes(), tt.enc)
+		}
+
+		got, left, err := ReadTimeBytes(enc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(left) != 0 || !got.Equal(tt.t) {
+			t.Errorf("%v: ReadTimeBytes returned %v with %d bytes left", tt.t, got, len(left))
+		}
+		if _, _, err = ReadTimeBytes(enc[:len(enc)-1]); err != ErrShortBytes {
+			t.Errorf("%v: expected ErrShortBytes; got %v", tt.t, err)
+		}
+
+		rd := NewReader(&buf)
+		if typ, err := rd.NextType(); err != nil || typ != TimeType {
+			t.Errorf("%v: NextType returned %s, %v", tt.t, typ, err)
+		}
+		got, err = rd.ReadTime()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !got.Equal(tt.t) {
+			t.Errorf("%v: ReadTime returned %v", tt.t, got)
+		}
+	}
+}
+
+func TestTimestampLegacy(t *testing.T) {
+	now := time.Now()
+	var buf bytes.Buffer
+	wr := NewWriter(&buf)
+	wr.WriteTime(now)
+	wr.UseTimestamps(true)
+	wr.WriteTime(now)
+	wr.UseTimestamps(false)
+	wr.WriteTime(now)
+	wr.Flush()
+
+	b := buf.Bytes()
+	if b[0] != mext8 || int8(b[2]) != TimeExtension {
+		t.Fatalf("default WriteTime wrote % x", b[:3])
+	}
+	rd := NewReader(&buf)
+	for i := 0; i < 3; i++ {
+		got, err := rd.ReadTime()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !got.Equal(now) {
+			t.Errorf("time %d: got %v; want %v", i, got, now)
+		}
+	}
+}
+
+func TestTimestampJSON(t *testing.T) {
+	tm := time.Unix(1500000000, 123456789)
+	want, _ := tm.Local().MarshalJSON()
+
+	var js bytes.Buffer
+	if _, err := UnmarshalAsJSON(&js, AppendTimestamp(nil, tm)); err != nil {
+		t.Fatal(err)
+	}
+	if js.String() != string(want) {
+		t.Errorf("UnmarshalAsJSON: got %s; want %s", js.String(), want)
+	}
+
+	js.Reset()
+	if _, err := CopyToJSON(&js, bytes.NewReader(AppendTimestamp(nil, tm))); err != nil {
+		t.Fatal(err)
+	}
+	if js.String() != string(want) {
+		t.Errorf("CopyToJSON: got %s; want %s", js.String(), want)
+	}
+}
+
+func TestTimestampBadType(t *testing.T) {
+	b := []byte{mfixext4, 3, 0, 0, 0, 0}
+	if _, _, err := ReadTimeBytes(b); err == nil {
+		t.Error("expected an error for extension type 3")
+	}
+	if _, err := NewReader(bytes.NewReader(b)).ReadTime(); err == nil {
+		t.Error("expected an error for extension type 3")
+	}
+}
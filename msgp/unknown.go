@@ -0,0 +1,54 @@
+package msgp
+
+// The functions in this file support struct fields
+// tagged `msg:",unknown"`. Such a field is a Raw that
+// holds the key/value pairs of a map-encoded struct
+// that the decoder did not recognize, back to back and
+// without a map header, so that they can be re-emitted
+// when the struct is encoded again.
+
+// ReadUnknownField appends the key 'field' and the
+// next object in the reader to 'raw' and returns the
+// extended Raw. 'field' may point into the reader's
+// buffer; it is copied before the value is read.
+func (m *Reader) ReadUnknownField(raw Raw, field []byte) (Raw, error) {
+	d := AppendStringFromBytes([]byte(raw), field)
+	err := appendNext(m, &d)
+	return Raw(d), err
+}
+
+// ReadUnknownFieldBytes appends the key 'field' and the
+// next object in 'b' to 'raw' and returns the extended
+// Raw and the remaining bytes in 'b'.
+// Possible errors:
+// - ErrShortBytes (not enough bytes in 'b')
+// - InvalidPrefixError (bad encoding)
+func ReadUnknownFieldBytes(raw Raw, field []byte, b []byte) (Raw, []byte, error) {
+	o, err := Skip(b)
+	if err != nil {
+		return raw, b, err
+	}
+	d := AppendStringFromBytes([]byte(raw), field)
+	d = append(d, b[:len(b)-len(o)]...)
+	return Raw(d), o, nil
+}
+
+// UnknownFieldCount returns the number of key/value
+// pairs held in 'raw'.
+func UnknownFieldCount(raw Raw) (uint32, error) {
+	var n uint32
+	b := []byte(raw)
+	var err error
+	for len(b) > 0 {
+		b, err = Skip(b)
+		if err != nil {
+			return 0, err
+		}
+		b, err = Skip(b)
+		if err != nil {
+			return 0, err
+		}
+		n++
+	}
+	return n, nil
+}
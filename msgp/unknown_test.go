@@ -0,0 +1,75 @@
+package msgp
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestReadUnknownField(t *testing.T) {
+	var buf bytes.Buffer
+	en := NewWriter(&buf)
+	en.WriteMapHeader(3)
+	en.WriteString("a")
+	en.WriteInt(1)
+	en.WriteString("b")
+	en.WriteArrayHeader(2)
+	en.WriteString("x")
+	en.WriteFloat64(3.5)
+	en.WriteString("c")
+	en.WriteNil()
+	en.Flush()
+	msg := buf.Bytes()
+
+	// read with the []byte API
+	var rawb Raw
+	sz, b, err := ReadMapHeaderBytes(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := uint32(0); i < sz; i++ {
+		var field []byte
+		field, b, err = ReadMapKeyZC(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rawb, b, err = ReadUnknownFieldBytes(rawb, field, b)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(b) != 0 {
+		t.Errorf("%d bytes left over", len(b))
+	}
+
+	// read with the streaming API
+	var raws Raw
+	dc := NewReader(bytes.NewReader(msg))
+	sz, err = dc.ReadMapHeader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := uint32(0); i < sz; i++ {
+		field, err := dc.ReadMapKeyPtr()
+		if err != nil {
+			t.Fatal(err)
+		}
+		raws, err = dc.ReadUnknownField(raws, field)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if !bytes.Equal(rawb, raws) {
+		t.Fatalf("streaming and []byte results differ:\n%x\n%x", rawb, raws)
+	}
+	n, err := UnknownFieldCount(rawb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != sz {
+		t.Errorf("UnknownFieldCount() = %d; want %d", n, sz)
+	}
+	if !bytes.Equal(append(AppendMapHeader(nil, n), rawb...), msg) {
+		t.Errorf("re-encoded fields don't match the input")
+	}
+}
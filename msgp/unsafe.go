@@ -39,3 +39,47 @@ func UnsafeBytes(s string) []byte {
 		Data: (*(*reflect.StringHeader)(unsafe.Pointer(&s))).Data,
 	}))
 }
+
+// littleEndian is set if numbers are stored
+// in little-endian order on this host
+var littleEndian = func() bool {
+	x := uint16(1)
+	return *(*byte)(unsafe.Pointer(&x)) == 1
+}()
+
+// packedBytes returns the memory of the packed
+// slice 'p' as a []byte, or nil if it can't be
+// copied in bulk on this host.
+func packedBytes(p packer) []byte {
+	n := p.length() * p.width()
+	if !littleEndian || n == 0 {
+		return nil
+	}
+	var ptr unsafe.Pointer
+	switch s := p.(type) {
+	case packedInt16s:
+		ptr = unsafe.Pointer(&s[0])
+	case packedInt32s:
+		ptr = unsafe.Pointer(&s[0])
+	case packedInt64s:
+		ptr = unsafe.Pointer(&s[0])
+	case packedUint16s:
+		ptr = unsafe.Pointer(&s[0])
+	case packedUint32s:
+		ptr = unsafe.Pointer(&s[0])
+	case packedUint64s:
+		ptr = unsafe.Pointer(&s[0])
+	case packedFloat32s:
+		ptr = unsafe.Pointer(&s[0])
+	case packedFloat64s:
+		ptr = unsafe.Pointer(&s[0])
+	default:
+		return nil
+	}
+	var b []byte
+	sh := (*reflect.SliceHeader)(unsafe.Pointer(&b))
+	sh.Data = uintptr(ptr)
+	sh.Len = n
+	sh.Cap = n
+	return b
+}
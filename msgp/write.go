@@ -42,6 +42,9 @@ func popWriter(w io.Writer) *Writer {
 func pushWriter(wr *Writer) {
 	wr.w = nil
 	wr.wloc = 0
+	wr.timestamps = false
+	wr.open = wr.open[:0]
+	wr.held = nil
 	writerPool.Put(wr)
 }
 
@@ -105,9 +108,12 @@ type Encodable interface {
 // to flush all of the buffered data
 // to the underlying writer.
 type Writer struct {
-	w    io.Writer
-	buf  []byte
-	wloc int
+	w          io.Writer
+	buf        []byte
+	wloc       int
+	timestamps bool
+	open       []int  // the offsets of the headers of open containers
+	held       []byte // what is held back while containers are open
 }
 
 // NewWriter returns a new *Writer.
@@ -148,6 +154,12 @@ func (mw *Writer) flush() error {
 	if mw.wloc == 0 {
 		return nil
 	}
+	if len(mw.open) > 0 {
+		// headers can't be patched once written
+		mw.held = append(mw.held, mw.buf[:mw.wloc]...)
+		mw.wloc = 0
+		return nil
+	}
 	n, err := mw.w.Write(mw.buf[:mw.wloc])
 	if err != nil {
 		if n > 0 {
@@ -160,7 +172,10 @@ func (mw *Writer) flush() error {
 }
 
 // Flush flushes all of the buffered
-// data to the underlying writer.
+// data to the underlying writer. While a
+// container started by BeginArray or
+// BeginMap is open, the data from its
+// header on is held back until End.
 func (mw *Writer) Flush() error { return mw.flush() }
 
 // Buffered returns the number bytes in the write buffer
@@ -270,6 +285,10 @@ func (mw *Writer) Write(p []byte) (int, error) {
 			return 0, err
 		}
 		if l > len(mw.buf) {
+			if len(mw.open) > 0 {
+				mw.held = append(mw.held, p...)
+				return l, nil
+			}
 			return mw.w.Write(p)
 		}
 	}
@@ -285,6 +304,10 @@ func (mw *Writer) writeString(s string) error {
 			return err
 		}
 		if l > len(mw.buf) {
+			if len(mw.open) > 0 {
+				mw.held = append(mw.held, s...)
+				return nil
+			}
 			_, err := io.WriteString(mw.w, s)
 			return err
 		}
@@ -298,6 +321,8 @@ func (mw *Writer) Reset(w io.Writer) {
 	mw.buf = mw.buf[:cap(mw.buf)]
 	mw.w = w
 	mw.wloc = 0
+	mw.open = mw.open[:0]
+	mw.held = mw.held[:0]
 }
 
 // WriteMapHeader writes a map header of the given
@@ -591,7 +616,13 @@ func (mw *Writer) WriteMapStrIntf(mp map[string]interface{}) (err error) {
 // binary encoding, because its implementation relies
 // heavily on the internal representation used by the
 // time package.)
+//
+// If UseTimestamps(true) has been called, WriteTime
+// writes the MessagePack timestamp extension instead.
 func (mw *Writer) WriteTime(t time.Time) error {
+	if mw.timestamps {
+		return mw.WriteTimestamp(t)
+	}
 	t = t.UTC()
 	o, err := mw.require(15)
 	if err != nil {
@@ -604,6 +635,28 @@ func (mw *Writer) WriteTime(t time.Time) error {
 	return nil
 }
 
+// WriteTimestamp writes a time.Time object to the wire
+// as a MessagePack timestamp extension (type -1), using
+// the smallest of its 32, 64 and 96-bit forms that
+// represents 't' exactly. Unlike the format written by
+// WriteTime, it can be read by other MessagePack
+// implementations.
+func (mw *Writer) WriteTimestamp(t time.Time) error {
+	n := timestampLen(t)
+	o, err := mw.require(n)
+	if err != nil {
+		return err
+	}
+	putTimestamp(mw.buf[o:o+n], t)
+	return nil
+}
+
+// UseTimestamps selects the encoding written by WriteTime.
+// If 'on' is true, WriteTime writes the MessagePack timestamp
+// extension, like WriteTimestamp. Otherwise it writes the
+// legacy TimeExtension format, which is the default.
+func (mw *Writer) UseTimestamps(on bool) { mw.timestamps = on }
+
 // WriteIntf writes the concrete type of 'v'.
 // WriteIntf will error if 'v' is not one of the following:
 //  - A bool, float, string, []byte, int, uint, or complex
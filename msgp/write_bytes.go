@@ -285,6 +285,15 @@ func AppendTime(b []byte, t time.Time) []byte {
 	return o
 }
 
+// AppendTimestamp appends a time.Time to the slice as a
+// MessagePack timestamp extension (type -1). See WriteTimestamp.
+func AppendTimestamp(b []byte, t time.Time) []byte {
+	sz := timestampLen(t)
+	o, n := ensure(b, sz)
+	putTimestamp(o[n:n+sz], t)
+	return o
+}
+
 // AppendMapStrStr appends a map[string]string to the slice
 // as a MessagePack map with 'str'-type keys and values
 func AppendMapStrStr(b []byte, m map[string]string) []byte {
@@ -3,9 +3,12 @@ package parse
 import (
 	"fmt"
 	"go/ast"
+	"go/types"
+	"strconv"
 	"strings"
 
 	"github.com/bytedance/msgp/gen"
+	"github.com/bytedance/msgp/msgp"
 )
 
 const linePrefix = "//msgp:"
@@ -21,9 +24,19 @@ type passDirective func(gen.Method, []string, *gen.Printer) error
 // to add a directive, define a func([]string, *FileSet) error
 // and then add it to this list.
 var directives = map[string]directive{
-	"shim":   applyShim,
-	"ignore": ignore,
-	"tuple":  astuple,
+	"shim":      applyShim,
+	"ignore":    ignore,
+	"tuple":     astuple,
+	"intkeys":   asintkeys,
+	"pool":      aspooled,
+	"intern":    asinterned,
+	"timestamp": astimestamp,
+	"typecheck": typecheck,
+	"external":  external,
+	"extension": asextension,
+	"enum":      asenum,
+	"allownil":  asallownil,
+	"columnar":  ascolumnar,
 }
 
 var passDirectives = map[string]passDirective{
@@ -120,6 +133,9 @@ func astuple(text []string, f *FileSet) error {
 		if el, ok := f.Identities[name]; ok {
 			if st, ok := el.(*gen.Struct); ok {
 				st.AsTuple = true
+				if st.Unknown != nil {
+					warnf("%s: unknown fields are not preserved for tuples\n", name)
+				}
 				infoln(name)
 			} else {
 				warnf("%s: only structs can be tuples\n", name)
@@ -128,3 +144,381 @@ func astuple(text []string, f *FileSet) error {
 	}
 	return nil
 }
+
+//msgp:intkeys {TypeA} {TypeB}...
+func asintkeys(text []string, f *FileSet) error {
+	if len(text) < 2 {
+		return nil
+	}
+	for _, item := range text[1:] {
+		name := strings.TrimSpace(item)
+		el, ok := f.Identities[name]
+		if !ok {
+			continue
+		}
+		st, ok := el.(*gen.Struct)
+		if !ok {
+			warnf("%s: only structs can have integer keys\n", name)
+			continue
+		}
+		if st.AsTuple {
+			warnf("%s: tuples can't have integer keys\n", name)
+			continue
+		}
+		pushstate(name)
+		fields := st.Fields[:0]
+		seen := make(map[int64]string, len(st.Fields))
+		for _, sf := range st.Fields {
+			n, err := strconv.ParseInt(sf.FieldTag, 10, 64)
+			if err != nil {
+				warnf("field %s: tag %q is not an integer key; field ignored\n", sf.FieldName, sf.FieldTag)
+				continue
+			}
+			if prev, ok := seen[n]; ok {
+				warnf("field %s: key %d already used by %s; field ignored\n", sf.FieldName, n, prev)
+				continue
+			}
+			seen[n] = sf.FieldName
+			fields = append(fields, sf)
+		}
+		st.Fields = fields
+		if st.Unknown != nil {
+			warnln("unknown fields are not preserved for integer keys")
+			st.Unknown = nil
+		}
+		st.AsIntKeys = true
+		infoln("integer keys")
+		popstate()
+	}
+	return nil
+}
+
+// Writes a slice of structs as a map of each
+// field to an array of its values.
+//
+//msgp:columnar {SliceA} {SliceB}...
+func ascolumnar(text []string, f *FileSet) error {
+	if len(text) < 2 {
+		return nil
+	}
+	for _, item := range text[1:] {
+		name := strings.TrimSpace(item)
+		el, ok := f.Identities[name]
+		if !ok {
+			continue
+		}
+		sl, ok := el.(*gen.Slice)
+		if !ok {
+			warnf("%s: only slices of structs can be columnar\n", name)
+			continue
+		}
+		// the struct is copied into the slice,
+		// since its fields are written separately
+		st, ok := sl.Els.(*gen.Struct)
+		if be, isIdent := sl.Els.(*gen.BaseElem); isIdent && be.Value == gen.IDENT {
+			st, ok = f.Identities[be.TypeName()].(*gen.Struct)
+			if ok {
+				st = st.Copy().(*gen.Struct)
+			}
+		}
+		if !ok {
+			warnf("%s: only slices of structs can be columnar\n", name)
+			continue
+		}
+		if st.Unknown != nil {
+			warnf("%s: unknown fields are not preserved for columnar slices\n", name)
+		}
+		sl.Els = st
+		sl.Columnar = true
+		infoln(name)
+	}
+	return nil
+}
+
+//msgp:pool {TypeA} {TypeB}...
+func aspooled(text []string, f *FileSet) error {
+	if len(text) < 2 {
+		return nil
+	}
+	pooled := make(map[string]bool, len(text)-1)
+	for _, item := range text[1:] {
+		name := strings.TrimSpace(item)
+		el, ok := f.Identities[name]
+		if !ok {
+			continue
+		}
+		if st, ok := el.(*gen.Struct); ok {
+			st.Pooled = true
+			pooled[name] = true
+			infoln(name)
+			// so are its slices and maps
+			for i := range st.Fields {
+				alloc := "msgpPool" + name + st.Fields[i].FieldName
+				switch e := st.Fields[i].FieldElem.(type) {
+				case *gen.Slice:
+					if !e.Packed {
+						e.Alloc = alloc
+					}
+				case *gen.Map:
+					e.Alloc = alloc
+				}
+			}
+		} else {
+			warnf("%s: only structs can be pooled\n", name)
+		}
+	}
+	// references to pooled types are allocated
+	// and released through the pool
+	for _, el := range f.Identities {
+		walkElems(el, func(e gen.Elem) {
+			if be, ok := e.(*gen.BaseElem); ok && be.Value == gen.IDENT && pooled[be.TypeName()] {
+				be.Pooled = true
+			}
+		})
+	}
+	return nil
+}
+
+// The types get an UnmarshalMsgWithInterner method
+// that reads strings and map keys through an Interner.
+//
+//msgp:intern {TypeA} {TypeB}...
+func asinterned(text []string, f *FileSet) error {
+	for _, item := range text[1:] {
+		name := strings.TrimSpace(item)
+		el, ok := f.Identities[name]
+		if !ok {
+			continue
+		}
+		if st, ok := el.(*gen.Struct); ok {
+			st.Interned = true
+			infoln(name)
+		} else {
+			warnf("%s: only structs can be interned\n", name)
+		}
+	}
+	return nil
+}
+
+// With no arguments, applies to every type.
+//
+//msgp:timestamp {TypeA} {TypeB}...
+func astimestamp(text []string, f *FileSet) error {
+	names := make(map[string]bool, len(text))
+	for _, item := range text[1:] {
+		names[strings.TrimSpace(item)] = true
+	}
+	for name, el := range f.Identities {
+		if len(names) > 0 && !names[name] {
+			continue
+		}
+		walkElems(el, func(e gen.Elem) {
+			if be, ok := e.(*gen.BaseElem); ok && be.Value == gen.Time {
+				be.Timestamp = true
+			}
+		})
+		if len(names) > 0 {
+			infoln(name)
+		}
+	}
+	if len(names) == 0 {
+		infoln("all types")
+	}
+	return nil
+}
+
+// Encodes nil slices, maps and []byte as nil
+// and decodes nil back into nil. With no
+// arguments, applies to every type.
+//
+//msgp:allownil {TypeA} {TypeB}...
+func asallownil(text []string, f *FileSet) error {
+	names := make(map[string]bool, len(text))
+	for _, item := range text[1:] {
+		names[strings.TrimSpace(item)] = true
+	}
+	for name, el := range f.Identities {
+		if len(names) > 0 && !names[name] {
+			continue
+		}
+		walkElems(el, func(e gen.Elem) { setAllowNil(e, false) })
+		if len(names) > 0 {
+			infoln(name)
+		}
+	}
+	if len(names) == 0 {
+		infoln("all types")
+	}
+	return nil
+}
+
+//msgp:extension {Type} {Number}
+func asextension(text []string, f *FileSet) error {
+	if len(text) != 3 {
+		return fmt.Errorf("extension directive should have 2 arguments; found %d", len(text)-1)
+	}
+	name := strings.TrimSpace(text[1])
+	num := strings.TrimSpace(text[2])
+	n, err := strconv.ParseInt(num, 10, 8)
+	if err != nil {
+		return fmt.Errorf("%s: extension type %q is not an int8", name, num)
+	}
+	switch {
+	case n < 0:
+		return fmt.Errorf("%s: negative extension types are reserved", name)
+	case n == msgp.Complex64Extension, n == msgp.Complex128Extension, n == msgp.TimeExtension:
+		return fmt.Errorf("%s: extension type %d is used by msgp", name, n)
+	}
+	switch el := f.Identities[name].(type) {
+	case *gen.Struct:
+		el.ExtType = num
+	case *gen.BaseElem:
+		el.ExtType = num
+	case nil:
+		return fmt.Errorf("%s: type not found", name)
+	default:
+		return fmt.Errorf("%s: only structs and shimmed types can be extensions", name)
+	}
+	infof("%s: extension type %s\n", name, num)
+	return nil
+}
+
+// Enums are written by name unless as:int is given,
+// and are read from either their name or their value.
+//
+//msgp:enum {TypeA} {TypeB}... [as:string|as:int]
+func asenum(text []string, f *FileSet) error {
+	asInt := false
+	var names []string
+	for _, item := range text[1:] {
+		item = strings.TrimSpace(item)
+		switch item {
+		case "as:int":
+			asInt = true
+		case "as:string":
+			asInt = false
+		default:
+			names = append(names, item)
+		}
+	}
+	for _, name := range names {
+		be, ok := f.Identities[name].(*gen.BaseElem)
+		if !ok || !be.Convert || be.ShimToBase != "" || !isInteger(be.Value) {
+			warnf("%s: only named integer types can be enums\n", name)
+			continue
+		}
+		consts := f.Consts[name]
+		if len(consts) == 0 {
+			warnf("%s: no constants of this type\n", name)
+			continue
+		}
+		be.Enum = consts
+		be.EnumAsInt = asInt
+		be.EnumString = !f.Funcs[name+".String"]
+		infof("%s: %d constants\n", name, len(consts))
+	}
+	return nil
+}
+
+// isInteger returns whether 'p'
+// is a signed or unsigned integer
+func isInteger(p gen.Primitive) bool {
+	switch p {
+	case gen.Int, gen.Int8, gen.Int16, gen.Int32, gen.Int64,
+		gen.Uint, gen.Uint8, gen.Uint16, gen.Uint32, gen.Uint64, gen.Byte:
+		return true
+	default:
+		return false
+	}
+}
+
+// Imports the packages used by the file, and encodes
+// their types that don't implement the msgp interfaces
+// through their encoding.BinaryMarshaler or
+// encoding.TextMarshaler methods.
+//
+//msgp:typecheck
+func typecheck(text []string, f *FileSet) error {
+	imp := sourceImporter(f.fset)
+	pkgs := make(map[string]*types.Package, len(f.Imports))
+	for _, spec := range f.Imports {
+		path, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		pkg, err := imp.Import(path)
+		if err != nil {
+			warnf("couldn't import %s: %s\n", path, err)
+			continue
+		}
+		name := pkg.Name()
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		pkgs[name] = pkg
+	}
+	for _, el := range f.Identities {
+		walkElems(el, func(e gen.Elem) {
+			be, ok := e.(*gen.BaseElem)
+			if !ok || be.Value != gen.IDENT || be.Marshaler != gen.MsgMarshaler || be.Resolved() {
+				return
+			}
+			name := be.TypeName()
+			dot := strings.IndexByte(name, '.')
+			if dot < 0 {
+				return
+			}
+			pkg, ok := pkgs[name[:dot]]
+			if !ok {
+				return
+			}
+			obj, ok := pkg.Scope().Lookup(name[dot+1:]).(*types.TypeName)
+			if !ok {
+				return
+			}
+			if m := marshalerOf(obj.Type()); m != gen.MsgMarshaler {
+				be.Marshaler = m
+				infof("%s: using its %s marshaler\n", name, strings.ToLower(m.String()))
+			}
+		})
+	}
+	return nil
+}
+
+// marshalerOf returns the marshaler to use
+// for values of type 't', preferring the msgp
+// interfaces if 't' implements them
+func marshalerOf(t types.Type) gen.Marshaler {
+	ms := types.NewMethodSet(types.NewPointer(t))
+	has := func(name string) bool { return ms.Lookup(nil, name) != nil }
+	switch {
+	case has("DecodeMsg") || has("UnmarshalMsg"):
+		return gen.MsgMarshaler
+	case has("MarshalBinary") && has("UnmarshalBinary"):
+		return gen.BinaryMarshaler
+	case has("MarshalText") && has("UnmarshalText"):
+		return gen.TextMarshaler
+	default:
+		return gen.MsgMarshaler
+	}
+}
+
+// walkElems calls fn for 'e' and
+// each of its children
+func walkElems(e gen.Elem, fn func(gen.Elem)) {
+	fn(e)
+	switch e := e.(type) {
+	case *gen.Struct:
+		for i := range e.Fields {
+			walkElems(e.Fields[i].FieldElem, fn)
+		}
+	case *gen.Array:
+		walkElems(e.Els, fn)
+	case *gen.Slice:
+		walkElems(e.Els, fn)
+	case *gen.Map:
+		walkElems(e.Value, fn)
+	case *gen.Ptr:
+		walkElems(e.Value, fn)
+	}
+}
@@ -0,0 +1,164 @@
+package parse
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strconv"
+	"strings"
+
+	"github.com/bytedance/msgp/gen"
+)
+
+// Loads the definitions of types from other packages
+// and generates free functions for them (e.g. EncodeYType,
+// DecodeYType, AppendYType, UnmarshalYType and SizeYType
+// for y.Type), which are used by fields of those types.
+//
+//msgp:external {import/path.TypeA} {import/path.TypeB}...
+func external(text []string, f *FileSet) error {
+	imp := sourceImporter(f.fset)
+	pkgs := make(map[string]*types.Package)
+	names := make(map[string]bool, len(text)-1)
+	for _, item := range text[1:] {
+		item = strings.TrimSpace(item)
+		dot := strings.LastIndexByte(item, '.')
+		if dot <= strings.LastIndexByte(item, '/') {
+			warnf("%s: expected {import/path}.{Type}\n", item)
+			continue
+		}
+		path, typ := item[:dot], item[dot+1:]
+		pkg, err := imp.Import(path)
+		if err != nil {
+			warnf("couldn't import %s: %s\n", path, err)
+			continue
+		}
+		obj, ok := pkg.Scope().Lookup(typ).(*types.TypeName)
+		if !ok || !obj.Exported() {
+			warnf("%s: no exported type %s in %s\n", item, typ, path)
+			continue
+		}
+		name := f.qualify(pkg, pkgs) + "." + typ
+		if other := f.externalNamed(gen.ExternalName(name)); other != "" && other != name {
+			// e.g. foo.BarBaz and fooBar.Baz
+			warnf("%s: its functions would have the same names as those of %s\n", name, other)
+			continue
+		}
+		pushstate(name)
+		el := f.parseExternal(obj.Type().Underlying(), pkgs)
+		popstate()
+		if el == nil {
+			warnf("%s: unsupported type\n", name)
+			continue
+		}
+		el.Alias(name)
+		f.Identities[name] = el
+		names[name] = true
+		infoln(name)
+	}
+	// references to external types
+	// call the generated functions
+	for _, el := range f.Identities {
+		walkElems(el, func(e gen.Elem) {
+			if be, ok := e.(*gen.BaseElem); ok && be.Value == gen.IDENT && names[be.TypeName()] {
+				be.External = true
+			}
+		})
+	}
+	return nil
+}
+
+// externalNamed returns the external type whose
+// generated functions have the suffix 'suffix',
+// or the empty string if there is none
+func (f *FileSet) externalNamed(suffix string) string {
+	for name := range f.Identities {
+		if gen.ExternalName(name) == suffix {
+			return name
+		}
+	}
+	return ""
+}
+
+// parseExternal translates the underlying type 't'
+// of a type from another package into a gen.Elem.
+// Unexported struct fields are left out, since they
+// can't be accessed from this package.
+func (f *FileSet) parseExternal(t types.Type, pkgs map[string]*types.Package) gen.Elem {
+	if st, ok := t.(*types.Struct); ok {
+		var fields []*types.Var
+		var tags []string
+		for i := 0; i < st.NumFields(); i++ {
+			if st.Field(i).Exported() {
+				fields = append(fields, st.Field(i))
+				tags = append(tags, st.Tag(i))
+			}
+		}
+		t = types.NewStruct(fields, tags)
+	}
+	src := types.TypeString(t, func(p *types.Package) string {
+		return f.qualify(p, pkgs)
+	})
+	expr, err := parser.ParseExpr(src)
+	if err != nil {
+		return nil
+	}
+	el := f.parseExpr(expr)
+	if el == nil {
+		return nil
+	}
+	walkElems(el, func(e gen.Elem) {
+		be, ok := e.(*gen.BaseElem)
+		if !ok || be.Value != gen.IDENT || be.Resolved() {
+			return
+		}
+		name := be.TypeName()
+		dot := strings.IndexByte(name, '.')
+		if dot < 0 {
+			return
+		}
+		pkg, ok := pkgs[name[:dot]]
+		if !ok {
+			return
+		}
+		obj, ok := pkg.Scope().Lookup(name[dot+1:]).(*types.TypeName)
+		if !ok {
+			return
+		}
+		// named types of primitives are converted,
+		// and other types without the msgp methods
+		// use their marshalers if they have them
+		if prim, ok := f.parseExternal(obj.Type().Underlying(), pkgs).(*gen.BaseElem); ok && prim.Value != gen.IDENT {
+			*be = *prim
+			be.Alias(name)
+		} else if m := marshalerOf(obj.Type()); m != gen.MsgMarshaler {
+			be.Marshaler = m
+		}
+	})
+	return el
+}
+
+// qualify returns the name by which the package
+// 'p' is referred to in the file, adding an import
+// for it if there isn't one already
+func (f *FileSet) qualify(p *types.Package, pkgs map[string]*types.Package) string {
+	name := p.Name()
+	found := false
+	for _, spec := range f.Imports {
+		if path, err := strconv.Unquote(spec.Path.Value); err == nil && path == p.Path() {
+			if spec.Name != nil {
+				name = spec.Name.Name
+			}
+			found = true
+			break
+		}
+	}
+	if !found {
+		f.Imports = append(f.Imports, &ast.ImportSpec{
+			Path: &ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(p.Path())},
+		})
+	}
+	pkgs[name] = p
+	return name
+}
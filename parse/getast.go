@@ -8,6 +8,7 @@ import (
 	"os"
 	"reflect"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/bytedance/msgp/gen"
@@ -23,6 +24,9 @@ type FileSet struct {
 	Identities  map[string]gen.Elem // processed from specs
 	Directives  []string            // raw preprocessor directives
 	Imports     []*ast.ImportSpec   // imports
+	Consts      map[string][]string // typed constants, by type name
+	Funcs       map[string]bool     // declared functions and methods ("Type.Method")
+	fset        *token.FileSet      // positions of the parsed files
 }
 
 // File parses a file at the relative path
@@ -38,9 +42,12 @@ func File(name string, unexported bool) (*FileSet, error) {
 		Specs:       make(map[string]ast.Expr),
 		StructSpecs: make(map[string]ast.Expr),
 		Identities:  make(map[string]gen.Elem),
+		Consts:      make(map[string][]string),
+		Funcs:       make(map[string]bool),
 	}
 
 	fset := token.NewFileSet()
+	fs.fset = fset
 	finfo, err := os.Stat(name)
 	if err != nil {
 		return nil, err
@@ -210,6 +217,8 @@ func strToMethod(s string) gen.Method {
 		return gen.Marshal
 	case "unmarshal":
 		return gen.Unmarshal
+	case "sql":
+		return gen.SQL
 	default:
 		return 0
 	}
@@ -250,6 +259,7 @@ loop:
 
 func (f *FileSet) PrintTo(p *gen.Printer) error {
 	f.applyDirs(p)
+	f.skipSQLConflicts(p)
 	names := make([]string, 0, len(f.Identities))
 	for name := range f.Identities {
 		names = append(names, name)
@@ -268,6 +278,60 @@ func (f *FileSet) PrintTo(p *gen.Printer) error {
 	return nil
 }
 
+// skipSQLConflicts skips the Scan and Value methods
+// for types that have a field or method of either
+// name, which would make the generated code fail
+// to compile
+func (f *FileSet) skipSQLConflicts(p *gen.Printer) {
+	p.ApplyDirective(gen.SQL, func(e gen.Elem) gen.Elem {
+		if what := f.sqlConflict(e.TypeName(), map[string]bool{}); what != "" {
+			warnf("has a %s; not generating Scan and Value\n", what)
+			return nil
+		}
+		return e
+	})
+}
+
+// sqlConflict returns the field or method of the type
+// 'name' that is named Value or Scan, or "" if it has none.
+// 'seen' holds the types already checked.
+func (f *FileSet) sqlConflict(name string, seen map[string]bool) string {
+	if seen[name] {
+		return ""
+	}
+	seen[name] = true
+	for _, m := range []string{"Value", "Scan"} {
+		if f.Funcs[name+"."+m] {
+			return "method " + m
+		}
+	}
+	st, ok := f.StructSpecs[name].(*ast.StructType)
+	if !ok {
+		return ""
+	}
+	for _, fl := range st.Fields.List {
+		if len(fl.Names) == 0 {
+			// embedded fields promote
+			// their own methods
+			emb := strings.TrimPrefix(stringify(fl.Type), "*")
+			if what := f.sqlConflict(emb, seen); what != "" {
+				return what + " (through " + emb + ")"
+			}
+			emb = emb[strings.LastIndex(emb, ".")+1:]
+			if emb == "Value" || emb == "Scan" {
+				return "field " + emb
+			}
+			continue
+		}
+		for _, n := range fl.Names {
+			if n.Name == "Value" || n.Name == "Scan" {
+				return "field " + n.Name
+			}
+		}
+	}
+	return ""
+}
+
 // getTypeSpecs extracts all of the *ast.TypeSpecs in the file
 // into fs.Identities, but does not set the actual element
 func (fs *FileSet) getTypeSpecs(f *ast.File) {
@@ -278,9 +342,25 @@ func (fs *FileSet) getTypeSpecs(f *ast.File) {
 	// check all declarations...
 	for i := range f.Decls {
 
+		// note functions and methods...
+		if fn, ok := f.Decls[i].(*ast.FuncDecl); ok {
+			name := fn.Name.Name
+			if fn.Recv != nil && len(fn.Recv.List) == 1 {
+				name = strings.TrimPrefix(stringify(fn.Recv.List[0].Type), "*") + "." + name
+			}
+			fs.Funcs[name] = true
+			continue
+		}
+
 		// for GenDecls...
 		if g, ok := f.Decls[i].(*ast.GenDecl); ok {
 
+			// collect typed constants...
+			if g.Tok == token.CONST {
+				fs.getConsts(g)
+				continue
+			}
+
 			// and check the specs...
 			for _, s := range g.Specs {
 
@@ -305,6 +385,37 @@ func (fs *FileSet) getTypeSpecs(f *ast.File) {
 	}
 }
 
+// getConsts collects the names of the typed constants
+// in a const block, including those that repeat the
+// type of the previous specification, as in
+//
+//	const (
+//		Red Color = iota
+//		Green
+//	)
+func (fs *FileSet) getConsts(g *ast.GenDecl) {
+	typ := ""
+	for _, s := range g.Specs {
+		vs, ok := s.(*ast.ValueSpec)
+		if !ok {
+			continue
+		}
+		if vs.Type != nil {
+			typ = stringify(vs.Type)
+		} else if len(vs.Values) > 0 {
+			typ = ""
+		}
+		if typ == "" {
+			continue
+		}
+		for _, nm := range vs.Names {
+			if nm.Name != "_" {
+				fs.Consts[typ] = append(fs.Consts[typ], nm.Name)
+			}
+		}
+	}
+}
+
 func fieldName(f *ast.Field) string {
 	switch len(f.Names) {
 	case 0:
@@ -316,38 +427,129 @@ func fieldName(f *ast.Field) string {
 	}
 }
 
-func (fs *FileSet) parseFieldList(fl *ast.FieldList) []gen.StructField {
+func (fs *FileSet) parseStruct(st *ast.StructType) *gen.Struct {
+	fl := st.Fields
 	if fl == nil || fl.NumFields() == 0 {
-		return nil
+		return &gen.Struct{}
 	}
-	out := make([]gen.StructField, 0, fl.NumFields())
+	out := &gen.Struct{Fields: make([]gen.StructField, 0, fl.NumFields())}
 	for _, field := range fl.List {
 		pushstate(fieldName(field))
+		if hasOption(msgTags(field), "unknown") {
+			if u := fs.getUnknownField(field); u != nil {
+				if out.Unknown != nil {
+					warnf("duplicate unknown field; %s already holds unknown fields\n", out.Unknown.FieldName)
+				} else {
+					u.Index = len(out.Fields)
+					out.Unknown = u
+				}
+			} else {
+				warnln("ignored.")
+			}
+			popstate()
+			continue
+		}
 		fds := fs.getField(field)
 		if len(fds) > 0 {
-			out = append(out, fds...)
+			out.Fields = append(out.Fields, fds...)
 		} else {
 			warnln("ignored.")
 		}
 		popstate()
 	}
+	if out.Unknown != nil {
+		// keys the struct doesn't recognize are passed to
+		// its embedded structs, so there's nothing left
+		// to put in the unknown field
+		for i := range out.Fields {
+			if out.Fields[i].Expandable {
+				pushstate(out.Unknown.FieldName)
+				warnf("unknown fields can't be combined with embedded struct %s; ignored.\n", out.Fields[i].FieldName)
+				popstate()
+				out.Unknown = nil
+				break
+			}
+		}
+	}
 	return out
 }
 
+// msgTags returns the comma-separated parts
+// of the field's `msg` (or `msgpack`) tag.
+func msgTags(f *ast.Field) []string {
+	if f.Tag == nil {
+		return nil
+	}
+	tag, err := strconv.Unquote(f.Tag.Value)
+	if err != nil {
+		tag = f.Tag.Value
+	}
+	body := reflect.StructTag(tag).Get("msg")
+	if body == "" {
+		body = reflect.StructTag(tag).Get("msgpack")
+	}
+	return strings.Split(body, ",")
+}
+
+// hasOption reports whether any tag
+// option (after the field tag) is 'opt'
+func hasOption(tags []string, opt string) bool {
+	for i := 1; i < len(tags); i++ {
+		if strings.TrimSpace(tags[i]) == opt {
+			return true
+		}
+	}
+	return false
+}
+
+// translate a field tagged `msg:",unknown"`
+// into a *gen.UnknownField
+func (fs *FileSet) getUnknownField(f *ast.Field) *gen.UnknownField {
+	if len(f.Names) != 1 {
+		warnln("unknown fields must be declared one per line with a name")
+		return nil
+	}
+	if stringify(f.Type) != "msgp.Raw" {
+		warnf("unknown fields must be of type msgp.Raw; found %s\n", stringify(f.Type))
+		return nil
+	}
+	return &gen.UnknownField{
+		FieldName: f.Names[0].Name,
+		RawTag:    f.Tag.Value,
+	}
+}
+
 // translate *ast.Field into []gen.StructField
 func (fs *FileSet) getField(f *ast.Field) []gen.StructField {
 	sf := make([]gen.StructField, 1)
-	var extension bool
+	var extension, zerocopy, allownil, packed bool
+	var timeAs string
+	marshaler := gen.MsgMarshaler
 	// parse tag; otherwise field name is field tag
 	if f.Tag != nil {
-		body := reflect.StructTag(strings.Trim(f.Tag.Value, "`")).Get("msg")
-		if body == "" {
-			body = reflect.StructTag(strings.Trim(f.Tag.Value, "`")).Get("msgpack")
-		}
-		tags := strings.Split(body, ",")
-		if len(tags) == 2 && tags[1] == "extension" {
+		tags := msgTags(f)
+		if hasOption(tags, "extension") {
 			extension = true
 		}
+		if hasOption(tags, "zerocopy") {
+			zerocopy = true
+		}
+		if hasOption(tags, "allownil") {
+			allownil = true
+		}
+		if hasOption(tags, "packed") {
+			packed = true
+		}
+		for _, opt := range []string{"unix", "unixms", "unixns", "rfc3339"} {
+			if hasOption(tags, opt) {
+				timeAs = opt
+			}
+		}
+		if hasOption(tags, "binary") {
+			marshaler = gen.BinaryMarshaler
+		} else if hasOption(tags, "text") {
+			marshaler = gen.TextMarshaler
+		}
 		// ignore "-" fields
 		if tags[0] == "-" {
 			return nil
@@ -360,6 +562,21 @@ func (fs *FileSet) getField(f *ast.Field) []gen.StructField {
 	if ex == nil {
 		return nil
 	}
+	if zerocopy && !setZeroCopy(ex) {
+		warnln("zerocopy only applies to string and []byte values; ignoring the option")
+	}
+	if allownil && !setAllowNil(ex, true) {
+		warnln("allownil only applies to slice, map and []byte values; ignoring the option")
+	}
+	if packed && !setPacked(ex) {
+		warnln("packed only applies to slices of fixed-size integers and floats; ignoring the option")
+	}
+	if timeAs != "" && !setTimeAs(ex, timeAs) {
+		warnf("%s only applies to time.Time values; ignoring the option\n", timeAs)
+	}
+	if marshaler != gen.MsgMarshaler && !setMarshaler(ex, marshaler) {
+		warnf("can't encode %s with its %s marshaler; ignoring the option\n", ex.TypeName(), strings.ToLower(marshaler.String()))
+	}
 
 	// parse field name
 	switch len(f.Names) {
@@ -408,6 +625,151 @@ func (fs *FileSet) getField(f *ast.Field) []gen.StructField {
 	return sf
 }
 
+// setZeroCopy marks the string and []byte
+// elements in 'e' (including those held in
+// pointers, slices, arrays and maps) to be read
+// without copying in UnmarshalMsg. It returns
+// false if there are no such elements.
+func setZeroCopy(e gen.Elem) bool {
+	switch e := e.(type) {
+	case *gen.BaseElem:
+		if e.Value == gen.String || e.Value == gen.Bytes {
+			e.ZeroCopy = true
+			return true
+		}
+		return false
+	case *gen.Ptr:
+		return setZeroCopy(e.Value)
+	case *gen.Slice:
+		return setZeroCopy(e.Els)
+	case *gen.Array:
+		return setZeroCopy(e.Els)
+	case *gen.Map:
+		return setZeroCopy(e.Value)
+	default:
+		return false
+	}
+}
+
+// setAllowNil marks the slice, map and []byte
+// element 'e' to keep nil values as nil. If
+// 'deep' is set, it also marks those held in
+// pointers, slices, arrays and maps. It returns
+// false if there are no such elements.
+func setAllowNil(e gen.Elem, deep bool) bool {
+	switch e := e.(type) {
+	case *gen.BaseElem:
+		if e.Value == gen.Bytes {
+			e.AllowNil = true
+			return true
+		}
+		return false
+	case *gen.Slice:
+		e.AllowNil = true
+		if deep {
+			setAllowNil(e.Els, deep)
+		}
+		return true
+	case *gen.Map:
+		e.AllowNil = true
+		if deep {
+			setAllowNil(e.Value, deep)
+		}
+		return true
+	case *gen.Ptr:
+		return deep && setAllowNil(e.Value, deep)
+	case *gen.Array:
+		return deep && setAllowNil(e.Els, deep)
+	default:
+		return false
+	}
+}
+
+// setPacked marks the slices of fixed-size
+// numbers in 'e' (including those held in
+// pointers, slices, arrays and maps) to be
+// written as a single bin object. It returns
+// false if there are no such slices.
+func setPacked(e gen.Elem) bool {
+	switch e := e.(type) {
+	case *gen.Slice:
+		if be, ok := e.Els.(*gen.BaseElem); ok && gen.PackedWidth(be.Value) > 0 &&
+			!be.Convert && be.Enum == nil && be.TypeName() == be.BaseType() {
+			e.Packed = true
+			return true
+		}
+		return setPacked(e.Els)
+	case *gen.Ptr:
+		return setPacked(e.Value)
+	case *gen.Array:
+		return setPacked(e.Els)
+	case *gen.Map:
+		return setPacked(e.Value)
+	default:
+		return false
+	}
+}
+
+// setTimeAs sets the encoding option 'opt'
+// on the time.Time elements in 'e' (including
+// those held in pointers, slices, arrays and maps).
+// It returns false if there are no such elements.
+func setTimeAs(e gen.Elem, opt string) bool {
+	switch e := e.(type) {
+	case *gen.BaseElem:
+		if e.Value == gen.Time {
+			e.TimeAs = opt
+			return true
+		}
+		return false
+	case *gen.Ptr:
+		return setTimeAs(e.Value, opt)
+	case *gen.Slice:
+		return setTimeAs(e.Els, opt)
+	case *gen.Array:
+		return setTimeAs(e.Els, opt)
+	case *gen.Map:
+		return setTimeAs(e.Value, opt)
+	default:
+		return false
+	}
+}
+
+// setMarshaler makes the named type in 'e' (or
+// the one held in a pointer, slice, array or map)
+// encode itself through the marshaler 'm'. It
+// returns false if there is no such type.
+func setMarshaler(e gen.Elem, m gen.Marshaler) bool {
+	switch e := e.(type) {
+	case *gen.BaseElem:
+		if e.ShimToBase != "" || e.Value == gen.Intf || e.Value == gen.Bytes {
+			return false
+		}
+		if e.Value != gen.IDENT {
+			// e.g. time.Time; treat it as an
+			// identity with the same name
+			name := e.TypeName()
+			if !strings.Contains(name, ".") || strings.HasPrefix(name, "*") {
+				return false
+			}
+			*e = gen.BaseElem{Value: gen.IDENT}
+			e.Alias(name)
+		}
+		e.Marshaler = m
+		return true
+	case *gen.Ptr:
+		return setMarshaler(e.Value, m)
+	case *gen.Slice:
+		return setMarshaler(e.Els, m)
+	case *gen.Array:
+		return setMarshaler(e.Els, m)
+	case *gen.Map:
+		return setMarshaler(e.Value, m)
+	default:
+		return false
+	}
+}
+
 // extract embedded field name
 //
 // so, for a struct like
@@ -530,13 +892,20 @@ func (fs *FileSet) parseExpr(e ast.Expr) gen.Elem {
 		return &gen.Slice{Els: els}
 
 	case *ast.StarExpr:
+		// pointers to some library types,
+		// like *big.Int, are primitives
+		if sel, ok := e.X.(*ast.SelectorExpr); ok {
+			if b := gen.Ident("*" + stringify(sel)); b.Value != gen.IDENT {
+				return b
+			}
+		}
 		if v := fs.parseExpr(e.X); v != nil {
 			return &gen.Ptr{Value: v}
 		}
 		return nil
 
 	case *ast.StructType:
-		return &gen.Struct{Fields: fs.parseFieldList(e.Fields)}
+		return fs.parseStruct(e)
 
 	case *ast.SelectorExpr:
 		return gen.Ident(stringify(e))
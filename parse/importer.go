@@ -0,0 +1,15 @@
+// +build go1.12
+
+package parse
+
+import (
+	"go/importer"
+	"go/token"
+	"go/types"
+)
+
+// sourceImporter returns an importer that type-checks
+// packages from source, with positions in 'fset'
+func sourceImporter(fset *token.FileSet) types.Importer {
+	return importer.ForCompiler(fset, "source", nil)
+}
@@ -0,0 +1,16 @@
+// +build !go1.12
+
+package parse
+
+import (
+	"go/importer"
+	"go/token"
+	"go/types"
+)
+
+// sourceImporter returns an importer that type-checks
+// packages from source (importer.ForCompiler, which
+// takes 'fset', was added in Go 1.12)
+func sourceImporter(fset *token.FileSet) types.Importer {
+	return importer.For("source", nil)
+}
@@ -49,11 +49,21 @@ func (f *FileSet) findShim(id string, be *gen.BaseElem) {
 		}
 		popstate()
 	}
-	// we'll need this at the top level as well
+	// we'll need this at the top level as well,
+	// keeping the extension type if it was set
+	// before the shim
+	if old, ok := f.Identities[id].(*gen.BaseElem); ok && be.ExtType == "" {
+		be.ExtType = old.ExtType
+	}
 	f.Identities[id] = be
 }
 
 func (f *FileSet) nextShim(ref *gen.Elem, id string, be *gen.BaseElem) {
+	// fields tagged as extensions keep
+	// using the msgp.Extension methods
+	if be, ok := (*ref).(*gen.BaseElem); ok && be.Value == gen.Ext {
+		return
+	}
 	if (*ref).TypeName() == id {
 		vn := (*ref).Varname()
 		*ref = be.Copy()
@@ -98,6 +108,13 @@ func (f *FileSet) propInline() {
 	}
 }
 
+// enums are encoded by their generated
+// methods rather than inlined
+func isEnum(e gen.Elem) bool {
+	be, ok := e.(*gen.BaseElem)
+	return ok && be.Enum != nil
+}
+
 const fatalloop = `detected infinite recursion in inlining loop!
 Please file a bug at github.com/bytedance/msgp/issues!
 Thanks!
@@ -108,9 +125,11 @@ func (f *FileSet) nextInline(ref *gen.Elem, root string) {
 	case *gen.BaseElem:
 		// ensure that we're not inlining
 		// a type into itself
+		// and that we're not replacing
+		// a type encoded by its marshalers
 		typ := el.TypeName()
-		if el.Value == gen.IDENT && typ != root {
-			if node, ok := f.Identities[typ]; ok && node.Complexity() < maxComplex {
+		if el.Value == gen.IDENT && typ != root && el.Marshaler == gen.MsgMarshaler {
+			if node, ok := f.Identities[typ]; ok && node.Complexity() < maxComplex && !isEnum(node) {
 				infof("inlining %s\n", typ)
 
 				// This should never happen; it will cause